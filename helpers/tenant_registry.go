@@ -0,0 +1,220 @@
+package helpers
+
+import (
+	"container/list"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrTenantNotCreated Error returned to the requests waiting for a tenant value when its creation
+// did not finish
+var ErrTenantNotCreated = errors.New("the tenant value was not created")
+
+// TenantRegistry Concurrency safe cache of values per tenant, like the tenant database factories.
+// When the registry is full the least recently used tenant is evicted and the tenants that were
+// not used for the idle timeout are evicted on the next access. The tenant ids are not case
+// sensitive
+type TenantRegistry[T any] struct {
+	lock        sync.Mutex
+	entries     map[string]*list.Element
+	creating    map[string]*tenantRegistryCreation[T]
+	order       *list.List
+	maxSize     int
+	idleTimeout time.Duration
+	onEvict     func(tenantId string, value T)
+	now         func() time.Time
+}
+
+type tenantRegistryEntry[T any] struct {
+	key      string
+	tenantId string
+	value    T
+	lastUsed time.Time
+}
+
+// tenantRegistryCreation Value of a tenant being created, the requests of the tenant wait for
+// done to be closed
+type tenantRegistryCreation[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// NewTenantRegistry Creates a registry keeping up to maxSize tenants, a maxSize or idleTimeout
+// lower or equal than 0 disables the limit. The onEvict callback can be nil, it is called
+// outside of the registry lock to release the resources of the evicted values
+func NewTenantRegistry[T any](maxSize int, idleTimeout time.Duration, onEvict func(tenantId string, value T)) *TenantRegistry[T] {
+	return &TenantRegistry[T]{
+		entries:     make(map[string]*list.Element),
+		creating:    make(map[string]*tenantRegistryCreation[T]),
+		order:       list.New(),
+		maxSize:     maxSize,
+		idleTimeout: idleTimeout,
+		onEvict:     onEvict,
+		now:         time.Now,
+	}
+}
+
+// GetOrCreate Gets the value of the tenant creating it if the tenant is not in the registry,
+// concurrent requests of a tenant wait for a single creation without blocking the other
+// tenants. The failed creations are not kept so the next request of the tenant tries again
+func (r *TenantRegistry[T]) GetOrCreate(tenantId string, create func() (T, error)) (T, error) {
+	key := strings.ToLower(tenantId)
+
+	r.lock.Lock()
+	evicted := r.evictIdle()
+
+	if element, ok := r.entries[key]; ok {
+		entry := element.Value.(*tenantRegistryEntry[T])
+		entry.lastUsed = r.now()
+		r.order.MoveToFront(element)
+		r.lock.Unlock()
+
+		r.evict(evicted)
+		return entry.value, nil
+	}
+
+	creation, isCreating := r.creating[key]
+	if !isCreating {
+		creation = &tenantRegistryCreation[T]{done: make(chan struct{}), err: ErrTenantNotCreated}
+		r.creating[key] = creation
+	}
+	r.lock.Unlock()
+
+	r.evict(evicted)
+	if isCreating {
+		<-creation.done
+		return creation.value, creation.err
+	}
+
+	defer r.finishCreation(key, tenantId, creation)
+	creation.value, creation.err = create()
+
+	return creation.value, creation.err
+}
+
+// finishCreation Adds the created value to the registry and releases the requests waiting for
+// it, the waiting requests get ErrTenantNotCreated if the creation panicked
+func (r *TenantRegistry[T]) finishCreation(key string, tenantId string, creation *tenantRegistryCreation[T]) {
+	r.lock.Lock()
+	delete(r.creating, key)
+
+	evicted := make([]*tenantRegistryEntry[T], 0)
+	if creation.err == nil {
+		entry := &tenantRegistryEntry[T]{
+			key:      key,
+			tenantId: tenantId,
+			value:    creation.value,
+			lastUsed: r.now(),
+		}
+		r.entries[key] = r.order.PushFront(entry)
+
+		if r.maxSize > 0 {
+			for r.order.Len() > r.maxSize {
+				evicted = append(evicted, r.remove(r.order.Back()))
+			}
+		}
+	}
+	r.lock.Unlock()
+
+	close(creation.done)
+	r.evict(evicted)
+}
+
+// Get Gets the value of the tenant if it is in the registry
+func (r *TenantRegistry[T]) Get(tenantId string) (T, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	element, ok := r.entries[strings.ToLower(tenantId)]
+	if !ok {
+		var empty T
+		return empty, false
+	}
+
+	entry := element.Value.(*tenantRegistryEntry[T])
+	entry.lastUsed = r.now()
+	r.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// Remove Removes the tenant from the registry, the evict callback is called with its value
+func (r *TenantRegistry[T]) Remove(tenantId string) {
+	r.lock.Lock()
+	element, ok := r.entries[strings.ToLower(tenantId)]
+	if !ok {
+		r.lock.Unlock()
+		return
+	}
+	entry := r.remove(element)
+	r.lock.Unlock()
+
+	r.evict([]*tenantRegistryEntry[T]{entry})
+}
+
+// EvictIdle Evicts the tenants that were not used for the idle timeout, this can be called
+// periodically as the idle tenants are otherwise only evicted when the registry is used
+func (r *TenantRegistry[T]) EvictIdle() {
+	r.lock.Lock()
+	evicted := r.evictIdle()
+	r.lock.Unlock()
+
+	r.evict(evicted)
+}
+
+// Clear Removes all the tenants from the registry
+func (r *TenantRegistry[T]) Clear() {
+	r.lock.Lock()
+	evicted := make([]*tenantRegistryEntry[T], 0)
+	for r.order.Len() > 0 {
+		evicted = append(evicted, r.remove(r.order.Back()))
+	}
+	r.lock.Unlock()
+
+	r.evict(evicted)
+}
+
+// Len Gets the number of tenants in the registry
+func (r *TenantRegistry[T]) Len() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.order.Len()
+}
+
+// evictIdle Removes the idle tenants, the least recently used are at the back of the list
+func (r *TenantRegistry[T]) evictIdle() []*tenantRegistryEntry[T] {
+	evicted := make([]*tenantRegistryEntry[T], 0)
+	if r.idleTimeout <= 0 {
+		return evicted
+	}
+
+	limit := r.now().Add(-r.idleTimeout)
+	for element := r.order.Back(); element != nil; element = r.order.Back() {
+		if element.Value.(*tenantRegistryEntry[T]).lastUsed.After(limit) {
+			break
+		}
+		evicted = append(evicted, r.remove(element))
+	}
+
+	return evicted
+}
+
+func (r *TenantRegistry[T]) remove(element *list.Element) *tenantRegistryEntry[T] {
+	entry := element.Value.(*tenantRegistryEntry[T])
+	r.order.Remove(element)
+	delete(r.entries, entry.key)
+	return entry
+}
+
+func (r *TenantRegistry[T]) evict(entries []*tenantRegistryEntry[T]) {
+	if r.onEvict == nil {
+		return
+	}
+
+	for _, entry := range entries {
+		r.onEvict(entry.tenantId, entry.value)
+	}
+}
@@ -0,0 +1,153 @@
+package helpers
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTenantRegistry_GetOrCreate(t *testing.T) {
+	evicted := make([]string, 0)
+	registry := NewTenantRegistry(2, 0, func(tenantId string, value int) {
+		evicted = append(evicted, tenantId)
+	})
+
+	registry.GetOrCreate("tenant1", func() (int, error) { return 1, nil })
+	registry.GetOrCreate("tenant2", func() (int, error) { return 2, nil })
+	if value, _ := registry.GetOrCreate("TENANT1", func() (int, error) { return 10, nil }); value != 1 {
+		t.Errorf("GetOrCreate() = %v, expected 1", value)
+	}
+	registry.GetOrCreate("tenant3", func() (int, error) { return 3, nil })
+
+	if !reflect.DeepEqual(evicted, []string{"tenant2"}) {
+		t.Errorf("evicted = %v, expected [tenant2]", evicted)
+	}
+	if _, ok := registry.Get("tenant2"); ok {
+		t.Errorf("Get() found the evicted tenant")
+	}
+	if registry.Len() != 2 {
+		t.Errorf("Len() = %v, expected 2", registry.Len())
+	}
+}
+
+func TestTenantRegistry_EvictIdle(t *testing.T) {
+	now := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	evicted := make([]string, 0)
+	registry := NewTenantRegistry(0, time.Minute, func(tenantId string, value int) {
+		evicted = append(evicted, tenantId)
+	})
+	registry.now = func() time.Time { return now }
+
+	registry.GetOrCreate("tenant1", func() (int, error) { return 1, nil })
+	now = now.Add(30 * time.Second)
+	registry.GetOrCreate("tenant2", func() (int, error) { return 2, nil })
+	now = now.Add(45 * time.Second)
+	registry.EvictIdle()
+
+	if !reflect.DeepEqual(evicted, []string{"tenant1"}) {
+		t.Errorf("evicted = %v, expected [tenant1]", evicted)
+	}
+	if _, ok := registry.Get("tenant2"); !ok {
+		t.Errorf("Get() did not find tenant2")
+	}
+}
+
+func TestTenantRegistry_Concurrent(t *testing.T) {
+	registry := NewTenantRegistry[int](10, time.Minute, nil)
+	created := 0
+
+	var wait sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			registry.GetOrCreate("tenant1", func() (int, error) {
+				created++
+				return created, nil
+			})
+		}()
+	}
+	wait.Wait()
+
+	if created != 1 {
+		t.Errorf("created = %v, expected 1", created)
+	}
+}
+
+func TestTenantRegistry_GetOrCreateError(t *testing.T) {
+	createErr := errors.New("connection refused")
+	registry := NewTenantRegistry[int](10, time.Minute, nil)
+
+	if _, err := registry.GetOrCreate("tenant1", func() (int, error) { return 0, createErr }); !errors.Is(err, createErr) {
+		t.Errorf("GetOrCreate() error = %v, wantErr %v", err, createErr)
+	}
+	if registry.Len() != 0 {
+		t.Errorf("Len() = %v, expected 0", registry.Len())
+	}
+
+	value, err := registry.GetOrCreate("tenant1", func() (int, error) { return 1, nil })
+	if err != nil || value != 1 {
+		t.Errorf("GetOrCreate() = %v, %v, expected 1", value, err)
+	}
+}
+
+func TestTenantRegistry_GetOrCreatePanic(t *testing.T) {
+	registry := NewTenantRegistry[int](10, time.Minute, nil)
+	started := make(chan struct{})
+	result := make(chan error, 1)
+
+	go func() {
+		defer func() { _ = recover() }()
+		registry.GetOrCreate("tenant1", func() (int, error) {
+			close(started)
+			time.Sleep(10 * time.Millisecond)
+			panic("create failed")
+		})
+	}()
+
+	<-started
+	go func() {
+		_, err := registry.GetOrCreate("tenant1", func() (int, error) { return 1, nil })
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		if err != nil && !errors.Is(err, ErrTenantNotCreated) {
+			t.Errorf("GetOrCreate() error = %v, wantErr %v", err, ErrTenantNotCreated)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("GetOrCreate() did not return after the creation panicked")
+	}
+}
+
+func TestTenantRegistry_GetOrCreateOtherTenants(t *testing.T) {
+	registry := NewTenantRegistry[int](10, time.Minute, nil)
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	go registry.GetOrCreate("slow", func() (int, error) {
+		close(started)
+		<-release
+		return 1, nil
+	})
+	<-started
+
+	result := make(chan int, 1)
+	go func() {
+		value, _ := registry.GetOrCreate("fast", func() (int, error) { return 2, nil })
+		result <- value
+	}()
+
+	select {
+	case value := <-result:
+		if value != 2 {
+			t.Errorf("GetOrCreate() = %v, expected 2", value)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("GetOrCreate() was blocked by the creation of another tenant")
+	}
+	close(release)
+}
@@ -0,0 +1,257 @@
+package mongodb
+
+import (
+	"time"
+
+	"github.com/cjlapao/common-go/execution_context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// AuditOptions Fields stamped with the time and the user of the changes, the fields left empty
+// are not stamped. The user is the id of the execution context authorization user
+type AuditOptions struct {
+	CreatedAtField string
+	UpdatedAtField string
+	CreatedByField string
+	UpdatedByField string
+}
+
+// auditStamp Time and user of a change, all the fields of a change get the same time
+type auditStamp struct {
+	time time.Time
+	user string
+}
+
+// NewAuditOptions Creates audit options stamping the createdAt, updatedAt, createdBy and
+// updatedBy fields
+//
+// Example:
+//		repository.WithAudit(NewAuditOptions().WithUserFields("", "")).InsertOne(order)
+func NewAuditOptions() *AuditOptions {
+	return &AuditOptions{
+		CreatedAtField: "createdAt",
+		UpdatedAtField: "updatedAt",
+		CreatedByField: "createdBy",
+		UpdatedByField: "updatedBy",
+	}
+}
+
+// WithTimeFields Sets the fields stamped with the time of the creation and of the last update
+func (o *AuditOptions) WithTimeFields(createdAtField string, updatedAtField string) *AuditOptions {
+	o.CreatedAtField = createdAtField
+	o.UpdatedAtField = updatedAtField
+	return o
+}
+
+// WithUserFields Sets the fields stamped with the user that created and last updated the document
+func (o *AuditOptions) WithUserFields(createdByField string, updatedByField string) *AuditOptions {
+	o.CreatedByField = createdByField
+	o.UpdatedByField = updatedByField
+	return o
+}
+
+// newAuditStamp Creates the stamp of a change made now by the user of the execution context
+func newAuditStamp() auditStamp {
+	return auditStamp{
+		time: time.Now().UTC(),
+		user: getContextUserId(),
+	}
+}
+
+// getContextUserId Gets the id of the authorization user of the execution context, returns empty
+// if there is no authorization
+func getContextUserId() string {
+	ctx := execution_context.Get()
+	if ctx == nil || ctx.Authorization == nil || ctx.Authorization.User == nil {
+		return ""
+	}
+
+	return ctx.Authorization.User.ID
+}
+
+// getCreatedFields Created fields and the values of the stamp, the empty fields are skipped
+func (o *AuditOptions) getCreatedFields(stamp auditStamp) []primitive.E {
+	return getAuditFields(o.CreatedAtField, o.CreatedByField, stamp)
+}
+
+// getUpdatedFields Updated fields and the values of the stamp, the empty fields are skipped
+func (o *AuditOptions) getUpdatedFields(stamp auditStamp) []primitive.E {
+	return getAuditFields(o.UpdatedAtField, o.UpdatedByField, stamp)
+}
+
+func getAuditFields(timeField string, userField string, stamp auditStamp) []primitive.E {
+	fields := make([]primitive.E, 0)
+	if timeField != "" {
+		fields = append(fields, primitive.E{Key: timeField, Value: stamp.time})
+	}
+	if userField != "" && stamp.user != "" {
+		fields = append(fields, primitive.E{Key: userField, Value: stamp.user})
+	}
+
+	return fields
+}
+
+// stampDocument Stamps a document that is inserted with the created and updated fields, the
+// document is converted into a bson document
+func (o *AuditOptions) stampDocument(document interface{}, stamp auditStamp) (interface{}, error) {
+	fields := append(o.getCreatedFields(stamp), o.getUpdatedFields(stamp)...)
+	return setDocumentFields(document, fields)
+}
+
+// stampReplacement Stamps a document that replaces another with the updated fields, the created
+// fields are kept as they are in the replacement
+func (o *AuditOptions) stampReplacement(document interface{}, stamp auditStamp) (interface{}, error) {
+	return setDocumentFields(document, o.getUpdatedFields(stamp))
+}
+
+// stampUpdate Stamps a copy of an update with the updated fields in $set and the created fields
+// in $setOnInsert so they are only written by upserts. The pipeline updates get a stage setting
+// the created fields only when they are missing. Fields already changed by other operators of
+// the update are not stamped
+func (o *AuditOptions) stampUpdate(update interface{}, stamp auditStamp) interface{} {
+	switch value := update.(type) {
+	case mongo.Pipeline:
+		fields := bson.D{}
+		for _, field := range o.getUpdatedFields(stamp) {
+			fields = append(fields, primitive.E{Key: field.Key, Value: bson.M{"$literal": field.Value}})
+		}
+		for _, field := range o.getCreatedFields(stamp) {
+			fields = append(fields, primitive.E{Key: field.Key, Value: bson.M{"$ifNull": bson.A{"$" + field.Key, bson.M{"$literal": field.Value}}}})
+		}
+		if len(fields) == 0 {
+			return value
+		}
+
+		stages := append(mongo.Pipeline{}, value...)
+		return append(stages, bson.D{{Key: "$set", Value: fields}})
+
+	case primitive.M:
+		value = copyUpdate(value)
+		set := getUpdateFields(value["$set"])
+		setOnInsert := getUpdateFields(value["$setOnInsert"])
+		for _, field := range o.getUpdatedFields(stamp) {
+			if !hasOperatorField(value, field.Key, "$set") {
+				set = setUpdateField(set, field)
+			}
+		}
+		for _, field := range o.getCreatedFields(stamp) {
+			if !hasOperatorField(value, field.Key, "$set", "$setOnInsert") {
+				set = removeUpdateField(set, field.Key)
+				setOnInsert = setUpdateField(setOnInsert, field)
+			}
+		}
+
+		if len(set) > 0 {
+			value["$set"] = set
+		}
+		if len(setOnInsert) > 0 {
+			value["$setOnInsert"] = setOnInsert
+		}
+		return value
+	}
+
+	return update
+}
+
+// setDocumentFields Converts a document into a bson document and sets the fields, the existing
+// fields are replaced in place and the missing ones are added at the end
+func setDocumentFields(document interface{}, fields []primitive.E) (interface{}, error) {
+	if len(fields) == 0 {
+		return document, nil
+	}
+
+	marshalled, err := bson.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	result := bson.D{}
+	if err := bson.Unmarshal(marshalled, &result); err != nil {
+		return nil, err
+	}
+
+	elements := []primitive.E(result)
+	for _, field := range fields {
+		elements = setUpdateField(elements, field)
+	}
+
+	return bson.D(elements), nil
+}
+
+// copyUpdate Copies the operators of an update so it can be changed without changing the update
+// of the caller
+func copyUpdate(update primitive.M) primitive.M {
+	result := primitive.M{}
+	for operator, fields := range update {
+		result[operator] = fields
+	}
+
+	return result
+}
+
+// getUpdateFields Gets a copy of the fields of an update operator as a list of elements
+func getUpdateFields(fields interface{}) []primitive.E {
+	switch value := fields.(type) {
+	case []primitive.E:
+		return append([]primitive.E{}, value...)
+	case primitive.D:
+		return append([]primitive.E{}, value...)
+	case primitive.M:
+		result := make([]primitive.E, 0)
+		for key, fieldValue := range value {
+			result = append(result, primitive.E{Key: key, Value: fieldValue})
+		}
+		return result
+	}
+
+	return make([]primitive.E, 0)
+}
+
+// hasOperatorField Checks if a field is changed by any of the operators of an update other than
+// the ignored ones
+func hasOperatorField(update primitive.M, field string, ignoredOperators ...string) bool {
+	for operator, fields := range update {
+		ignored := false
+		for _, ignoredOperator := range ignoredOperators {
+			if operator == ignoredOperator {
+				ignored = true
+				break
+			}
+		}
+		if ignored {
+			continue
+		}
+
+		for _, element := range getUpdateFields(fields) {
+			if element.Key == field {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func setUpdateField(fields []primitive.E, field primitive.E) []primitive.E {
+	for index, element := range fields {
+		if element.Key == field.Key {
+			fields[index].Value = field.Value
+			return fields
+		}
+	}
+
+	return append(fields, field)
+}
+
+func removeUpdateField(fields []primitive.E, key string) []primitive.E {
+	result := make([]primitive.E, 0)
+	for _, element := range fields {
+		if element.Key != key {
+			result = append(result, element)
+		}
+	}
+
+	return result
+}
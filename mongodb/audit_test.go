@@ -0,0 +1,157 @@
+package mongodb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestAuditOptions_StampUpdate(t *testing.T) {
+	stamp := auditStamp{time: time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC), user: "user1"}
+
+	tests := []struct {
+		name    string
+		options *AuditOptions
+		update  interface{}
+		expect  interface{}
+	}{
+		{
+			name:    "update operators",
+			options: NewAuditOptions(),
+			update: bson.M{
+				"$set": []primitive.E{{Key: "name", Value: "john"}, {Key: "createdAt", Value: time.Time{}}},
+			},
+			expect: bson.M{
+				"$set": []primitive.E{
+					{Key: "name", Value: "john"},
+					{Key: "updatedAt", Value: stamp.time},
+					{Key: "updatedBy", Value: "user1"},
+				},
+				"$setOnInsert": []primitive.E{
+					{Key: "createdAt", Value: stamp.time},
+					{Key: "createdBy", Value: "user1"},
+				},
+			},
+		},
+		{
+			name:    "fields changed by other operators",
+			options: NewAuditOptions().WithUserFields("", ""),
+			update: bson.M{
+				"$currentDate": []primitive.E{{Key: "updatedAt", Value: true}},
+			},
+			expect: bson.M{
+				"$currentDate": []primitive.E{{Key: "updatedAt", Value: true}},
+				"$setOnInsert": []primitive.E{{Key: "createdAt", Value: stamp.time}},
+			},
+		},
+		{
+			name:    "pipeline update",
+			options: NewAuditOptions().WithTimeFields("created", "updated").WithUserFields("", ""),
+			update: mongo.Pipeline{
+				{{Key: "$unset", Value: []string{"draft"}}},
+			},
+			expect: mongo.Pipeline{
+				{{Key: "$unset", Value: []string{"draft"}}},
+				{{Key: "$set", Value: bson.D{
+					{Key: "updated", Value: bson.M{"$literal": stamp.time}},
+					{Key: "created", Value: bson.M{"$ifNull": bson.A{"$created", bson.M{"$literal": stamp.time}}}},
+				}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.options.stampUpdate(tt.update, stamp)
+			if !reflect.DeepEqual(result, tt.expect) {
+				t.Errorf("stampUpdate() = %v, expected %v", result, tt.expect)
+			}
+		})
+	}
+}
+
+func TestAuditOptions_StampDocument(t *testing.T) {
+	stamp := auditStamp{time: time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC), user: ""}
+	document := struct {
+		Name      string    `bson:"name"`
+		UpdatedAt time.Time `bson:"updatedAt"`
+	}{
+		Name: "john",
+	}
+
+	result, err := NewAuditOptions().stampDocument(document, stamp)
+	if err != nil {
+		t.Fatalf("stampDocument() error = %v", err)
+	}
+
+	dateTime := primitive.NewDateTimeFromTime(stamp.time)
+	marshalled, _ := bson.Marshal(result)
+	decoded := bson.D{}
+	bson.Unmarshal(marshalled, &decoded)
+	expected := bson.D{
+		{Key: "name", Value: "john"},
+		{Key: "updatedAt", Value: dateTime},
+		{Key: "createdAt", Value: dateTime},
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("stampDocument() = %v, expected %v", decoded, expected)
+	}
+}
+
+func TestMongoDefaultRepository_PrepareWriteModel(t *testing.T) {
+	stamp := auditStamp{time: time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC), user: "user"}
+	repository := &MongoDefaultRepository{audit: NewAuditOptions()}
+
+	tests := []struct {
+		name  string
+		model mongo.WriteModel
+	}{
+		{
+			name:  "update document",
+			model: mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": 1}).SetUpdate(bson.M{"$set": bson.D{{Key: "name", Value: "john"}}}),
+		},
+		{
+			name:  "update pipeline",
+			model: mongo.NewUpdateManyModel().SetFilter(bson.M{}).SetUpdate(mongo.Pipeline{{{Key: "$set", Value: bson.M{"name": "john"}}}}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := copyWriteModelUpdate(tt.model)
+
+			first, err := repository.prepareWriteModel(tt.model, stamp)
+			if err != nil {
+				t.Fatalf("prepareWriteModel() error = %v", err)
+			}
+			second, err := repository.prepareWriteModel(tt.model, stamp)
+			if err != nil {
+				t.Fatalf("prepareWriteModel() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(copyWriteModelUpdate(tt.model), original) {
+				t.Errorf("prepareWriteModel() changed the model update to %v, expected %v", copyWriteModelUpdate(tt.model), original)
+			}
+			if !reflect.DeepEqual(first, second) {
+				t.Errorf("prepareWriteModel() second = %v, expected %v", second, first)
+			}
+		})
+	}
+}
+
+func copyWriteModelUpdate(model mongo.WriteModel) interface{} {
+	var update interface{}
+	switch writeModel := model.(type) {
+	case *mongo.UpdateOneModel:
+		update = writeModel.Update
+	case *mongo.UpdateManyModel:
+		update = writeModel.Update
+	}
+
+	marshalled, _ := bson.Marshal(bson.M{"update": update})
+	result := bson.M{}
+	bson.Unmarshal(marshalled, &result)
+	return result
+}
@@ -4,6 +4,17 @@ import "errors"
 
 var ErrNoElements = errors.New("no elements to process")
 
+// ErrUpdateConflict Error returned when an update changes the same path, or a path and one of
+// its sub paths, more than once
+var ErrUpdateConflict = errors.New("conflicting update paths")
+
+// ErrInvalidArrayFilter Error returned when an array filter identifier is not used by the update
+// or an update uses an identifier without array filter
+var ErrInvalidArrayFilter = errors.New("invalid array filter")
+
+// ErrMixedUpdate Error returned when an update uses both pipeline stages and update operators
+var ErrMixedUpdate = errors.New("pipeline updates cannot be mixed with update operators")
+
 type elementBuilderOperation int
 
 const (
@@ -11,8 +22,40 @@ const (
 	SetOnInsertOperation
 	UnsetOperation
 	FilterOperation
+	IncOperation
+	MulOperation
+	MinOperation
+	MaxOperation
+	RenameOperation
+	CurrentDateOperation
+	PushOperation
+	AddToSetOperation
+	PullOperation
+	PullAllOperation
+	PopOperation
 )
 
+// updateOperators Update operators of the builder operations in the order they are built
+var updateOperators = []struct {
+	operation elementBuilderOperation
+	operator  string
+}{
+	{SetOperation, "$set"},
+	{SetOnInsertOperation, "$setOnInsert"},
+	{UnsetOperation, "$unset"},
+	{IncOperation, "$inc"},
+	{MulOperation, "$mul"},
+	{MinOperation, "$min"},
+	{MaxOperation, "$max"},
+	{RenameOperation, "$rename"},
+	{CurrentDateOperation, "$currentDate"},
+	{PushOperation, "$push"},
+	{AddToSetOperation, "$addToSet"},
+	{PullOperation, "$pull"},
+	{PullAllOperation, "$pullAll"},
+	{PopOperation, "$pop"},
+}
+
 type builderElement struct {
 	operation       elementBuilderOperation
 	key             string
@@ -25,3 +68,52 @@ type BuilderOptions int
 const (
 	UpsertBuildOption BuilderOptions = 1
 )
+
+// ReturnDocument Version of the document returned by the find one and modify operations
+type ReturnDocument int
+
+const (
+	ReturnBefore ReturnDocument = iota
+	ReturnAfter
+)
+
+// PopPosition Element removed from an array by the pop operation
+type PopPosition int
+
+const (
+	PopFirst PopPosition = -1
+	PopLast  PopPosition = 1
+)
+
+// PushOptions Modifiers of the push operation, the values are added with $each and the options
+// that are not set are not used
+type PushOptions struct {
+	Slice    *int
+	Sort     interface{}
+	Position *int
+}
+
+// NewPushOptions Creates empty push options
+func NewPushOptions() *PushOptions {
+	return &PushOptions{}
+}
+
+// SetSlice Limits the array to the number of elements after the push, negative numbers keep
+// the last elements
+func (o *PushOptions) SetSlice(slice int) *PushOptions {
+	o.Slice = &slice
+	return o
+}
+
+// SetSort Sorts the array after the push, use 1 or -1 for arrays of values or a document with
+// the fields for arrays of documents
+func (o *PushOptions) SetSort(sort interface{}) *PushOptions {
+	o.Sort = sort
+	return o
+}
+
+// SetPosition Inserts the values at the position instead of the end of the array
+func (o *PushOptions) SetPosition(position int) *PushOptions {
+	o.Position = &position
+	return o
+}
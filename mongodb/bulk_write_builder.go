@@ -0,0 +1,245 @@
+package mongodb
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// defaultBulkWriteChunkSize Number of operations sent to the server in each bulk write
+const defaultBulkWriteChunkSize = 1000
+
+// MongoBulkWriteModel Batch of insert, update, replace and delete operations
+type MongoBulkWriteModel struct {
+	models    []mongo.WriteModel
+	Ordered   bool
+	ChunkSize int
+}
+
+// Length Number of operations in the batch
+func (model MongoBulkWriteModel) Length() int {
+	return len(model.models)
+}
+
+// BulkWriteOperationError Error of an operation of a bulk write, the index is the position of
+// the operation in the builder
+type BulkWriteOperationError struct {
+	Index   int
+	Code    int
+	Message string
+}
+
+// BulkWriteError Error returned by a bulk write when any of its operations failed, the result
+// of the operations that succeeded is returned with it
+type BulkWriteError struct {
+	Errors            []BulkWriteOperationError
+	WriteConcernError error
+}
+
+// Error Describes the failed operations
+func (e *BulkWriteError) Error() string {
+	messages := make([]string, 0)
+	for _, operationError := range e.Errors {
+		messages = append(messages, fmt.Sprintf("operation %v: (%v) %v", operationError.Index, operationError.Code, operationError.Message))
+	}
+	if e.WriteConcernError != nil {
+		messages = append(messages, e.WriteConcernError.Error())
+	}
+
+	return "bulk write error: " + strings.Join(messages, ", ")
+}
+
+type BulkWriteBuilder struct {
+	models    []mongo.WriteModel
+	ordered   bool
+	chunkSize int
+	err       error
+}
+
+// NewBulkWriteBuilder Creates a new builder for a batch of operations, the batch is ordered by
+// default and is sent in chunks of 1000 operations
+//
+// Example:
+//		update, _ := NewUpdateOneModelBuilder().FilterBy("_id", Equal, id).Inc("stock", -1).Build()
+//		bulk, err := NewBulkWriteBuilder().
+//			Unordered().
+//			Insert(order).
+//			Update(update).
+//			DeleteAll("status eq 'cancelled'").
+//			Build()
+//		result, err := repository.BulkWrite(bulk)
+func NewBulkWriteBuilder() *BulkWriteBuilder {
+	return &BulkWriteBuilder{
+		models:    make([]mongo.WriteModel, 0),
+		ordered:   true,
+		chunkSize: defaultBulkWriteChunkSize,
+	}
+}
+
+// Unordered Runs all the operations even if some of them fail, the server can also run them
+// in any order
+func (c *BulkWriteBuilder) Unordered() *BulkWriteBuilder {
+	c.ordered = false
+	return c
+}
+
+// ChunkSize Sets the number of operations sent to the server in each bulk write, the large
+// batches are split to keep the requests small
+func (c *BulkWriteBuilder) ChunkSize(chunkSize int) *BulkWriteBuilder {
+	if chunkSize > 0 {
+		c.chunkSize = chunkSize
+	}
+
+	return c
+}
+
+// Insert Adds an insert operation for each of the documents
+func (c *BulkWriteBuilder) Insert(documents ...interface{}) *BulkWriteBuilder {
+	for _, document := range documents {
+		c.models = append(c.models, mongo.NewInsertOneModel().SetDocument(document))
+	}
+
+	return c
+}
+
+// Update Adds the update one models built with the UpdateOneModelBuilder
+func (c *BulkWriteBuilder) Update(models ...*MongoUpdateOneModel) *BulkWriteBuilder {
+	for _, model := range models {
+		c.models = append(c.models, model.model)
+	}
+
+	return c
+}
+
+// UpdateAll Adds the update many models built with the BuildMany of the UpdateOneModelBuilder
+func (c *BulkWriteBuilder) UpdateAll(models ...*MongoUpdateManyModel) *BulkWriteBuilder {
+	for _, model := range models {
+		c.models = append(c.models, model.model)
+	}
+
+	return c
+}
+
+// Replace Adds a replace operation of the first document matching the filter, the filter can
+// be a bson document or a odata type of query
+func (c *BulkWriteBuilder) Replace(filter interface{}, replacement interface{}, upsert bool) *BulkWriteBuilder {
+	filterToApply, err := parseFilter(filter)
+	if err != nil {
+		c.err = err
+		return c
+	}
+
+	model := mongo.NewReplaceOneModel().
+		SetFilter(filterToApply).
+		SetReplacement(replacement).
+		SetUpsert(upsert)
+	c.models = append(c.models, model)
+
+	return c
+}
+
+// Delete Adds the delete one models built with the DeleteOneBuilder
+func (c *BulkWriteBuilder) Delete(models ...*MongoDeleteOneModel) *BulkWriteBuilder {
+	for _, model := range models {
+		c.models = append(c.models, model.model)
+	}
+
+	return c
+}
+
+// DeleteAll Adds a delete operation of all the documents matching the filter, the filter can be
+// a bson document or a odata type of query
+func (c *BulkWriteBuilder) DeleteAll(filter interface{}) *BulkWriteBuilder {
+	filterToApply, err := parseFilter(filter)
+	if err != nil {
+		c.err = err
+		return c
+	}
+
+	c.models = append(c.models, mongo.NewDeleteManyModel().SetFilter(filterToApply))
+	return c
+}
+
+// Build Builds the batch, returns the first error of the filters of the operations
+func (c *BulkWriteBuilder) Build() (*MongoBulkWriteModel, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	if len(c.models) == 0 {
+		return nil, ErrNoElements
+	}
+
+	return &MongoBulkWriteModel{
+		models:    c.models,
+		Ordered:   c.ordered,
+		ChunkSize: c.chunkSize,
+	}, nil
+}
+
+// getChunks Splits the operations in chunks of the chunk size
+func (model MongoBulkWriteModel) getChunks() [][]mongo.WriteModel {
+	chunkSize := model.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultBulkWriteChunkSize
+	}
+
+	chunks := make([][]mongo.WriteModel, 0)
+	for start := 0; start < len(model.models); start += chunkSize {
+		end := start + chunkSize
+		if end > len(model.models) {
+			end = len(model.models)
+		}
+		chunks = append(chunks, model.models[start:end])
+	}
+
+	return chunks
+}
+
+// getBulkWriteOptions Gets the driver options of the batch
+func (model MongoBulkWriteModel) getBulkWriteOptions() *options.BulkWriteOptions {
+	return options.BulkWrite().SetOrdered(model.Ordered)
+}
+
+// addBulkWriteResult Adds the result of a chunk to the batch result, the upserted ids are
+// indexed by the position of the operation in the batch
+func (result *mongoBulkWriteResult) addBulkWriteResult(entity *mongo.BulkWriteResult, offset int) {
+	if entity == nil {
+		return
+	}
+
+	result.InsertedCount += entity.InsertedCount
+	result.MatchedCount += entity.MatchedCount
+	result.ModifiedCount += entity.ModifiedCount
+	result.DeletedCount += entity.DeletedCount
+	result.UpsertedCount += entity.UpsertedCount
+	for index, id := range entity.UpsertedIDs {
+		result.UpsertedIDs[index+int64(offset)] = id
+	}
+}
+
+// addBulkWriteException Adds the errors of a chunk to the batch error, the indexes are moved
+// to the position of the operation in the batch. Returns false if the error is not a bulk
+// write exception
+func (e *BulkWriteError) addBulkWriteException(err error, offset int) bool {
+	var exception mongo.BulkWriteException
+	if !errors.As(err, &exception) {
+		return false
+	}
+
+	for _, writeError := range exception.WriteErrors {
+		e.Errors = append(e.Errors, BulkWriteOperationError{
+			Index:   writeError.Index + offset,
+			Code:    writeError.Code,
+			Message: writeError.Message,
+		})
+	}
+	if exception.WriteConcernError != nil {
+		e.WriteConcernError = exception.WriteConcernError
+	}
+
+	return true
+}
@@ -0,0 +1,86 @@
+package mongodb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestBulkWriteBuilder_Build(t *testing.T) {
+	update, _ := NewUpdateOneModelBuilder().FilterBy("name", Equal, "john").Set("age", 30).Build()
+
+	model, err := NewBulkWriteBuilder().
+		Unordered().
+		ChunkSize(2).
+		Insert(bson.M{"name": "jane"}, bson.M{"name": "jack"}).
+		Update(update).
+		Replace("name eq 'jill'", bson.M{"name": "jill", "age": 20}, true).
+		DeleteAll("age lt 18").
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if model.Ordered {
+		t.Errorf("Build() ordered = %v, expected false", model.Ordered)
+	}
+	if model.Length() != 5 {
+		t.Errorf("Build() length = %v, expected 5", model.Length())
+	}
+
+	chunks := model.getChunks()
+	chunkLengths := make([]int, 0)
+	for _, chunk := range chunks {
+		chunkLengths = append(chunkLengths, len(chunk))
+	}
+	if !reflect.DeepEqual(chunkLengths, []int{2, 2, 1}) {
+		t.Errorf("getChunks() lengths = %v, expected [2 2 1]", chunkLengths)
+	}
+
+	deleteModel, ok := chunks[2][0].(*mongo.DeleteManyModel)
+	if !ok {
+		t.Fatalf("getChunks() model = %T, expected *mongo.DeleteManyModel", chunks[2][0])
+	}
+	expectedFilter := bson.M{"age": bson.M{"$lt": 18}}
+	if !reflect.DeepEqual(deleteModel.Filter, expectedFilter) {
+		t.Errorf("DeleteAll() filter = %v, expected %v", deleteModel.Filter, expectedFilter)
+	}
+}
+
+func TestBulkWriteBuilder_BuildErrors(t *testing.T) {
+	if _, err := NewBulkWriteBuilder().Build(); !errors.Is(err, ErrNoElements) {
+		t.Errorf("Build() error = %v, wantErr %v", err, ErrNoElements)
+	}
+
+	if _, err := NewBulkWriteBuilder().Insert(bson.M{}).DeleteAll("age lt").Build(); err == nil {
+		t.Errorf("Build() error = nil, expected a filter error")
+	}
+}
+
+func TestBulkWriteError_AddBulkWriteException(t *testing.T) {
+	bulkWriteError := BulkWriteError{}
+	exception := mongo.BulkWriteException{
+		WriteErrors: []mongo.BulkWriteError{
+			{WriteError: mongo.WriteError{Index: 0, Code: 11000, Message: "duplicate key"}},
+			{WriteError: mongo.WriteError{Index: 3, Code: 121, Message: "validation failed"}},
+		},
+	}
+
+	if !bulkWriteError.addBulkWriteException(exception, 1000) {
+		t.Fatalf("addBulkWriteException() = false, expected true")
+	}
+	if bulkWriteError.addBulkWriteException(errors.New("network error"), 0) {
+		t.Errorf("addBulkWriteException() = true, expected false")
+	}
+
+	expected := []BulkWriteOperationError{
+		{Index: 1000, Code: 11000, Message: "duplicate key"},
+		{Index: 1003, Code: 121, Message: "validation failed"},
+	}
+	if !reflect.DeepEqual(bulkWriteError.Errors, expected) {
+		t.Errorf("addBulkWriteException() errors = %v, expected %v", bulkWriteError.Errors, expected)
+	}
+}
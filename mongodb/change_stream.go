@@ -0,0 +1,355 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultResumeTokenCollection Collection used to store the change stream resume tokens
+const DefaultResumeTokenCollection = "_resume_tokens"
+
+// ErrNoWatchTarget Error returned when a change stream has no collection or database to watch
+var ErrNoWatchTarget = errors.New("change stream has no collection or database to watch")
+
+type changeOperationType string
+
+const (
+	InsertChange       changeOperationType = "insert"
+	UpdateChange       changeOperationType = "update"
+	ReplaceChange      changeOperationType = "replace"
+	DeleteChange       changeOperationType = "delete"
+	DropChange         changeOperationType = "drop"
+	RenameChange       changeOperationType = "rename"
+	DropDatabaseChange changeOperationType = "dropDatabase"
+	InvalidateChange   changeOperationType = "invalidate"
+)
+
+// ChangeEvent Change stream event, the full document is decoded into T, it is only present
+// for inserts and replaces or for updates when the stream was created with FullDocument
+type ChangeEvent[T any] struct {
+	ResumeToken       bson.Raw                      `bson:"_id"`
+	OperationType     changeOperationType           `bson:"operationType"`
+	FullDocument      *T                            `bson:"fullDocument,omitempty"`
+	DocumentKey       bson.M                        `bson:"documentKey,omitempty"`
+	Namespace         ChangeEventNamespace          `bson:"ns"`
+	UpdateDescription *ChangeEventUpdateDescription `bson:"updateDescription,omitempty"`
+	ClusterTime       primitive.Timestamp           `bson:"clusterTime"`
+}
+
+type ChangeEventNamespace struct {
+	Database   string `bson:"db"`
+	Collection string `bson:"coll"`
+}
+
+type ChangeEventUpdateDescription struct {
+	UpdatedFields bson.M   `bson:"updatedFields"`
+	RemovedFields []string `bson:"removedFields"`
+}
+
+// ResumeTokenStore Persists the change streams resume tokens so a stream can continue from
+// the last processed event after a restart
+type ResumeTokenStore interface {
+	Load(name string) (bson.Raw, error)
+	Save(name string, token bson.Raw) error
+}
+
+type ChangeStreamBuilder struct {
+	name         string
+	collection   *mongo.Collection
+	database     *mongo.Database
+	pipeline     bson.A
+	scope        interface{}
+	fullDocument bool
+	batchSize    int32
+	maxAwaitTime time.Duration
+	resumeAfter  bson.Raw
+	store        ResumeTokenStore
+}
+
+// Watch Creates a change stream builder for the collection of the repository. The streams of
+// tenant scoped repositories include the full document of the updates and only deliver the
+// events whose full document belongs to the tenant, so the delete events, that only have the
+// document key, are not delivered. The soft deletes are delivered as update events
+func (r *MongoDefaultRepository) Watch() *ChangeStreamBuilder {
+	builder := newChangeStreamBuilder(r.Database.name+"."+r.Collection.name, r.Collection.coll, nil)
+	if r.tenantField != "" {
+		builder.scope = bson.M{"fullDocument." + r.tenantField: r.getTenantFilter()[r.tenantField]}
+		builder.fullDocument = true
+	}
+
+	return builder
+}
+
+// Watch Creates a change stream builder for all the collections in the factory database
+func (f *MongoFactory) Watch() *ChangeStreamBuilder {
+	if f.Database == nil {
+		return newChangeStreamBuilder("", nil, nil)
+	}
+
+	return newChangeStreamBuilder(f.Database.name, nil, f.Database.db)
+}
+
+func newChangeStreamBuilder(name string, collection *mongo.Collection, database *mongo.Database) *ChangeStreamBuilder {
+	return &ChangeStreamBuilder{
+		name:       name,
+		collection: collection,
+		database:   database,
+		pipeline:   bson.A{},
+	}
+}
+
+// Name Sets the name of the stream, this is the key used to store the resume tokens and
+// defaults to the watched namespace, use different names for different consumers
+func (c *ChangeStreamBuilder) Name(name string) *ChangeStreamBuilder {
+	c.name = name
+	return c
+}
+
+// Match Adds a match stage to the stream using a complex interface, the fields are the ones
+// of the change event, for example fullDocument.userId or operationType
+func (c *ChangeStreamBuilder) Match(filter interface{}) *ChangeStreamBuilder {
+	c.pipeline = append(c.pipeline, bson.D{{Key: "$match", Value: filter}})
+	return c
+}
+
+// Filter Adds a match stage to the stream using a odata type of query, if the expression fails
+// to parse it will not be added to the stream, for example:
+//		builder.Filter("fullDocument.userId eq 'some_id'")
+func (c *ChangeStreamBuilder) Filter(query string) *ChangeStreamBuilder {
+	parsedFilter, err := NewFilterParser(query).Parse()
+	if err != nil {
+		logger.Error("There was an error applying the filter, %v", err.Error())
+		return c
+	}
+
+	return c.Match(parsedFilter)
+}
+
+// Pipeline Adds the stages generated by a pipeline builder to the stream, only the stages
+// supported by change streams like $match, $project or $addFields can be used. The scope of
+// the repository of the pipeline builder is not added as the fields of the events are different
+func (c *ChangeStreamBuilder) Pipeline(builder *PipelineBuilder) *ChangeStreamBuilder {
+	c.pipeline = append(c.pipeline, builder.buildStages()...)
+	return c
+}
+
+// OperationTypes Only delivers the events of the operation types
+func (c *ChangeStreamBuilder) OperationTypes(operationTypes ...changeOperationType) *ChangeStreamBuilder {
+	return c.Match(bson.M{"operationType": bson.M{"$in": operationTypes}})
+}
+
+// FullDocument Includes the current version of the document in the update events
+func (c *ChangeStreamBuilder) FullDocument() *ChangeStreamBuilder {
+	c.fullDocument = true
+	return c
+}
+
+// BatchSize Sets the maximum number of events returned by the server in each batch
+func (c *ChangeStreamBuilder) BatchSize(batchSize int32) *ChangeStreamBuilder {
+	c.batchSize = batchSize
+	return c
+}
+
+// MaxAwaitTime Sets the maximum time the server waits for new events before returning an
+// empty batch
+func (c *ChangeStreamBuilder) MaxAwaitTime(duration time.Duration) *ChangeStreamBuilder {
+	c.maxAwaitTime = duration
+	return c
+}
+
+// ResumeAfter Starts the stream after the event of the resume token, this takes precedence
+// over the token in the resume token store
+func (c *ChangeStreamBuilder) ResumeAfter(token bson.Raw) *ChangeStreamBuilder {
+	c.resumeAfter = token
+	return c
+}
+
+// ResumeTokenStore Sets the store used to load the token to resume from when starting and to
+// save the token of each event after it was processed
+func (c *ChangeStreamBuilder) ResumeTokenStore(store ResumeTokenStore) *ChangeStreamBuilder {
+	c.store = store
+	return c
+}
+
+// open Opens the change stream resuming from the stored token if there is one
+func (c *ChangeStreamBuilder) open(ctx context.Context) (*mongo.ChangeStream, error) {
+	streamOptions := options.ChangeStream()
+	if c.fullDocument {
+		streamOptions.SetFullDocument(options.UpdateLookup)
+	}
+	if c.batchSize > 0 {
+		streamOptions.SetBatchSize(c.batchSize)
+	}
+	if c.maxAwaitTime > 0 {
+		streamOptions.SetMaxAwaitTime(c.maxAwaitTime)
+	}
+
+	resumeToken := c.resumeAfter
+	if resumeToken == nil && c.store != nil {
+		token, err := c.store.Load(c.name)
+		if err != nil {
+			logger.Exception(err, "There was an error loading the resume token for %v", c.name)
+			return nil, err
+		}
+		resumeToken = token
+	}
+	if resumeToken != nil {
+		streamOptions.SetResumeAfter(resumeToken)
+	}
+
+	switch {
+	case c.collection != nil:
+		return c.collection.Watch(ctx, c.getPipeline(), streamOptions)
+	case c.database != nil:
+		return c.database.Watch(ctx, c.getPipeline(), streamOptions)
+	default:
+		return nil, ErrNoWatchTarget
+	}
+}
+
+// getPipeline Gets the stages of the stream, the scope of the repository is the first stage
+func (c *ChangeStreamBuilder) getPipeline() bson.A {
+	if c.scope == nil {
+		return c.pipeline
+	}
+
+	return append(bson.A{bson.D{{Key: "$match", Value: c.scope}}}, c.pipeline...)
+}
+
+// WatchEach Opens the change stream and calls the handler for each event, it blocks until the
+// context is cancelled, returning nil, or until the stream or the handler return an error.
+// The resume token is saved after the handler returns so events are delivered at least once
+//
+// Example:
+//		err := WatchEach(ctx, repository.Watch().FullDocument(), func(event ChangeEvent[User]) error {
+//			logger.Info("user %v was %v", event.DocumentKey["_id"], event.OperationType)
+//			return nil
+//		})
+func WatchEach[T any](ctx context.Context, builder *ChangeStreamBuilder, handler func(event ChangeEvent[T]) error) error {
+	stream, err := builder.open(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// the context might already be cancelled so we close it with a new one
+		closeCtx, cancel := context.WithTimeout(context.Background(), defaultOperationTimeout)
+		defer cancel()
+		stream.Close(closeCtx)
+	}()
+
+	for stream.Next(ctx) {
+		var event ChangeEvent[T]
+		if err := stream.Decode(&event); err != nil {
+			logger.Exception(err, "There was an error decoding the change event")
+			return err
+		}
+
+		if err := handler(event); err != nil {
+			return err
+		}
+
+		if builder.store != nil {
+			if err := builder.store.Save(builder.name, stream.ResumeToken()); err != nil {
+				logger.Exception(err, "There was an error saving the resume token for %v", builder.name)
+				return err
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	return stream.Err()
+}
+
+// WatchChannel Opens the change stream and delivers the events over a channel, both channels
+// are closed when the context is cancelled or the stream fails, in which case the error
+// is sent in the error channel before closing it
+func WatchChannel[T any](ctx context.Context, builder *ChangeStreamBuilder) (<-chan ChangeEvent[T], <-chan error) {
+	events := make(chan ChangeEvent[T])
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(events)
+		defer close(errs)
+
+		err := WatchEach(ctx, builder, func(event ChangeEvent[T]) error {
+			select {
+			case events <- event:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+
+		if err != nil && ctx.Err() == nil {
+			errs <- err
+		}
+	}()
+
+	return events, errs
+}
+
+type mongoResumeToken struct {
+	Name      string    `bson:"_id"`
+	Token     bson.Raw  `bson:"token"`
+	UpdatedOn time.Time `bson:"updatedOn"`
+}
+
+// MongoResumeTokenStore Resume token store that keeps the tokens in a collection
+type MongoResumeTokenStore struct {
+	collection *mongoCollection
+}
+
+// NewMongoResumeTokenStore Creates a resume token store in the factory database, if the
+// collection is empty the DefaultResumeTokenCollection is used
+func NewMongoResumeTokenStore(factory *MongoFactory, collection string) *MongoResumeTokenStore {
+	if collection == "" {
+		collection = DefaultResumeTokenCollection
+	}
+
+	return &MongoResumeTokenStore{
+		collection: factory.GetCollection(collection),
+	}
+}
+
+// Load Loads the last saved token for the stream, returns nil if there is none
+func (s *MongoResumeTokenStore) Load(name string) (bson.Raw, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultOperationTimeout)
+	defer cancel()
+
+	var token mongoResumeToken
+	err := s.collection.coll.FindOne(ctx, bson.M{"_id": name}).Decode(&token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error loading resume token %v: %w", name, err)
+	}
+
+	return token.Token, nil
+}
+
+// Save Saves the token for the stream
+func (s *MongoResumeTokenStore) Save(name string, token bson.Raw) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultOperationTimeout)
+	defer cancel()
+
+	entity := mongoResumeToken{
+		Name:      name,
+		Token:     token,
+		UpdatedOn: time.Now(),
+	}
+
+	_, err := s.collection.coll.ReplaceOne(ctx, bson.M{"_id": name}, entity, options.Replace().SetUpsert(true))
+	return err
+}
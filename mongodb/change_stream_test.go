@@ -0,0 +1,83 @@
+package mongodb
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMongoDefaultRepository_Watch(t *testing.T) {
+	database := &mongoDatabase{name: "shop"}
+	collection := &mongoCollection{name: "orders"}
+	stage := bson.D{{Key: "$match", Value: bson.M{"operationType": "insert"}}}
+
+	tests := []struct {
+		name             string
+		repository       *MongoDefaultRepository
+		wantFullDocument bool
+		expect           bson.A
+	}{
+		{
+			name:       "unscoped repository",
+			repository: &MongoDefaultRepository{Database: database, Collection: collection},
+			expect:     bson.A{stage},
+		},
+		{
+			name:       "soft delete repository",
+			repository: &MongoDefaultRepository{Database: database, Collection: collection, softDelete: NewSoftDeleteOptions()},
+			expect:     bson.A{stage},
+		},
+		{
+			name:             "tenant repository",
+			repository:       &MongoDefaultRepository{Database: database, Collection: collection, tenantField: "customer", tenantId: "tenant1"},
+			wantFullDocument: true,
+			expect: bson.A{
+				bson.D{{Key: "$match", Value: bson.M{"fullDocument.customer": "tenant1"}}},
+				stage,
+			},
+		},
+		{
+			name:             "tenant repository without tenant",
+			repository:       &MongoDefaultRepository{Database: database, Collection: collection, tenantField: "customer"},
+			wantFullDocument: true,
+			expect: bson.A{
+				bson.D{{Key: "$match", Value: bson.M{"fullDocument.customer": bson.M{"$in": bson.A{}}}}},
+				stage,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := tt.repository.Watch().Match(bson.M{"operationType": "insert"})
+
+			if builder.name != "shop.orders" {
+				t.Errorf("Watch() name = %v, expected shop.orders", builder.name)
+			}
+			if builder.fullDocument != tt.wantFullDocument {
+				t.Errorf("Watch() fullDocument = %v, expected %v", builder.fullDocument, tt.wantFullDocument)
+			}
+			if got := builder.getPipeline(); !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("Watch() pipeline = %v, expected %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestChangeStreamBuilder_Pipeline(t *testing.T) {
+	pipeline := NewEmptyPipeline(nil).
+		withScope(bson.M{"deletedAt": nil}).
+		Match(bson.M{"fullDocument.status": "paid"})
+
+	builder := newChangeStreamBuilder("orders", nil, nil).
+		OperationTypes(InsertChange, UpdateChange).
+		Pipeline(pipeline)
+
+	expect := bson.A{
+		bson.D{{Key: "$match", Value: bson.M{"operationType": bson.M{"$in": []changeOperationType{InsertChange, UpdateChange}}}}},
+		bson.D{{Key: "$match", Value: bson.M{"fullDocument.status": "paid"}}},
+	}
+	if got := builder.getPipeline(); !reflect.DeepEqual(got, expect) {
+		t.Errorf("Pipeline() = %v, expected %v", got, expect)
+	}
+}
@@ -0,0 +1,126 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrStopIteration Error that can be returned by the Each handler to stop the iteration early
+// without Each returning an error
+var ErrStopIteration = errors.New("stop iteration")
+
+// ErrInvalidCursor Error returned when iterating a cursor that failed to be created
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// documentCursor Cursor iterated by Each, it is implemented by the mongoCursor
+type documentCursor interface {
+	Next(ctx context.Context) bool
+	Decode(destination interface{}) error
+	Err() error
+	Close(ctx context.Context) error
+}
+
+// Each Iterates the cursor decoding one document at a time into T and calling the handler,
+// only the current batch is kept in memory so this can be used to export large collections.
+// The iteration stops when the cursor is exhausted, the context is cancelled or the handler
+// returns an error, return ErrStopIteration to stop early without an error.
+// The cursor is always closed when Each returns
+//
+// Example:
+//		cursor, err := repository.WithBatchSize(1000).Find("status eq 'active'")
+//		err = Each(ctx, cursor, func(user User) error {
+//			return writer.Write(user)
+//		})
+func Each[T any](ctx context.Context, cursor *mongoCursor, handler func(element T) error) error {
+	if cursor == nil || cursor.cursor == nil {
+		return ErrInvalidCursor
+	}
+
+	return eachDocument(ctx, cursor, handler)
+}
+
+// eachDocument Iterates a cursor decoding the documents into T, the cursor is always closed
+func eachDocument[T any](ctx context.Context, cursor documentCursor, handler func(element T) error) error {
+	defer closeCursor(cursor)
+
+	for cursor.Next(ctx) {
+		var element T
+		if err := cursor.Decode(&element); err != nil {
+			return err
+		}
+
+		if err := handler(element); err != nil {
+			if errors.Is(err, ErrStopIteration) {
+				return nil
+			}
+
+			return err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return cursor.Err()
+}
+
+// Stream Iterates the cursor in the background delivering each document decoded into T over a
+// channel, the buffer size sets how many documents can be decoded ahead of the consumer.
+// Both channels are closed once the cursor is exhausted or fails, in which case the error is
+// sent in the error channel before closing it. To stop early cancel the context, the cursor
+// will then be closed
+//
+// Example:
+//		users, errs := Stream[User](ctx, cursor, 100)
+//		for user := range users {
+//			...
+//		}
+//		if err := <-errs; err != nil {
+//			...
+//		}
+func Stream[T any](ctx context.Context, cursor *mongoCursor, bufferSize int) (<-chan T, <-chan error) {
+	return streamDocuments(ctx, bufferSize, func(handler func(element T) error) error {
+		return Each(ctx, cursor, handler)
+	})
+}
+
+// streamDocuments Runs the iteration in the background delivering the documents over a channel
+func streamDocuments[T any](ctx context.Context, bufferSize int, iterate func(handler func(element T) error) error) (<-chan T, <-chan error) {
+	if bufferSize < 0 {
+		bufferSize = 0
+	}
+
+	elements := make(chan T, bufferSize)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(elements)
+		defer close(errs)
+
+		err := iterate(func(element T) error {
+			select {
+			case elements <- element:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+
+		if err != nil {
+			errs <- err
+		}
+	}()
+
+	return elements, errs
+}
+
+// closeCursor Closes the cursor with a new context as the iteration one might be cancelled
+func closeCursor(cursor documentCursor) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultOperationTimeout)
+	defer cancel()
+
+	if err := cursor.Close(ctx); err != nil {
+		logger.Exception(err, "There was an error closing the cursor")
+	}
+}
@@ -0,0 +1,209 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type testCursor struct {
+	documents []bson.M
+	index     int
+	err       error
+	decodeErr error
+	closed    int
+}
+
+func (c *testCursor) Next(ctx context.Context) bool {
+	if ctx.Err() != nil || c.index >= len(c.documents) {
+		return false
+	}
+
+	c.index++
+	return true
+}
+
+func (c *testCursor) Decode(destination interface{}) error {
+	if c.decodeErr != nil {
+		return c.decodeErr
+	}
+
+	marshalled, err := bson.Marshal(c.documents[c.index-1])
+	if err != nil {
+		return err
+	}
+
+	return bson.Unmarshal(marshalled, destination)
+}
+
+func (c *testCursor) Err() error {
+	return c.err
+}
+
+func (c *testCursor) Close(ctx context.Context) error {
+	c.closed++
+	return nil
+}
+
+type testCursorElement struct {
+	Name string `bson:"name"`
+}
+
+func TestEachDocument(t *testing.T) {
+	documents := []bson.M{{"name": "john"}, {"name": "jane"}, {"name": "joe"}}
+	cursorErr := errors.New("cursor failed")
+	handlerErr := errors.New("handler failed")
+
+	tests := []struct {
+		name      string
+		cursor    *testCursor
+		stopAt    int
+		handleErr error
+		expect    []string
+		wantErr   error
+	}{
+		{
+			name:   "all the documents",
+			cursor: &testCursor{documents: documents},
+			expect: []string{"john", "jane", "joe"},
+		},
+		{
+			name:      "stop iteration",
+			cursor:    &testCursor{documents: documents},
+			stopAt:    2,
+			handleErr: ErrStopIteration,
+			expect:    []string{"john", "jane"},
+		},
+		{
+			name:      "handler error",
+			cursor:    &testCursor{documents: documents},
+			stopAt:    1,
+			handleErr: handlerErr,
+			expect:    []string{"john"},
+			wantErr:   handlerErr,
+		},
+		{
+			name:    "cursor error",
+			cursor:  &testCursor{documents: documents[:1], err: cursorErr},
+			expect:  []string{"john"},
+			wantErr: cursorErr,
+		},
+		{
+			name:    "decode error",
+			cursor:  &testCursor{documents: documents, decodeErr: cursorErr},
+			expect:  []string{},
+			wantErr: cursorErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := make([]string, 0)
+			err := eachDocument(context.Background(), tt.cursor, func(element testCursorElement) error {
+				names = append(names, element.Name)
+				if len(names) == tt.stopAt {
+					return tt.handleErr
+				}
+				return nil
+			})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("eachDocument() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(names, tt.expect) {
+				t.Errorf("eachDocument() elements = %v, expected %v", names, tt.expect)
+			}
+			if tt.cursor.closed != 1 {
+				t.Errorf("eachDocument() closed the cursor %v times, expected 1", tt.cursor.closed)
+			}
+		})
+	}
+}
+
+func TestEachDocumentCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cursor := &testCursor{documents: []bson.M{{"name": "john"}, {"name": "jane"}}}
+
+	err := eachDocument(ctx, cursor, func(element testCursorElement) error {
+		cancel()
+		return nil
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("eachDocument() error = %v, wantErr %v", err, context.Canceled)
+	}
+	if cursor.index != 1 || cursor.closed != 1 {
+		t.Errorf("eachDocument() read %v documents and closed %v times, expected 1 and 1", cursor.index, cursor.closed)
+	}
+}
+
+func TestEachInvalidCursor(t *testing.T) {
+	handler := func(element testCursorElement) error { return nil }
+
+	if err := Each(context.Background(), nil, handler); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Each() error = %v, wantErr %v", err, ErrInvalidCursor)
+	}
+	if err := Each(context.Background(), &mongoCursor{}, handler); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Each() error = %v, wantErr %v", err, ErrInvalidCursor)
+	}
+
+	elements, errs := Stream[testCursorElement](context.Background(), nil, 1)
+	for range elements {
+		t.Errorf("Stream() delivered an element of an invalid cursor")
+	}
+	if err := <-errs; !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Stream() error = %v, wantErr %v", err, ErrInvalidCursor)
+	}
+}
+
+func TestStreamDocuments(t *testing.T) {
+	cursorErr := errors.New("cursor failed")
+	cursor := &testCursor{documents: []bson.M{{"name": "john"}, {"name": "jane"}}, err: cursorErr}
+
+	ctx := context.Background()
+	elements, errs := streamDocuments(ctx, 0, func(handler func(element testCursorElement) error) error {
+		return eachDocument(ctx, cursor, handler)
+	})
+
+	names := make([]string, 0)
+	for element := range elements {
+		names = append(names, element.Name)
+	}
+
+	if err := <-errs; !errors.Is(err, cursorErr) {
+		t.Errorf("streamDocuments() error = %v, wantErr %v", err, cursorErr)
+	}
+	if _, open := <-errs; open {
+		t.Errorf("streamDocuments() error channel is not closed")
+	}
+	expect := []string{"john", "jane"}
+	if !reflect.DeepEqual(names, expect) {
+		t.Errorf("streamDocuments() elements = %v, expected %v", names, expect)
+	}
+	if cursor.closed != 1 {
+		t.Errorf("streamDocuments() closed the cursor %v times, expected 1", cursor.closed)
+	}
+}
+
+func TestStreamDocumentsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cursor := &testCursor{documents: []bson.M{{"name": "john"}, {"name": "jane"}, {"name": "joe"}}}
+
+	elements, errs := streamDocuments(ctx, 0, func(handler func(element testCursorElement) error) error {
+		return eachDocument(ctx, cursor, handler)
+	})
+
+	<-elements
+	cancel()
+	for range elements {
+	}
+
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("streamDocuments() error = %v, wantErr %v", err, context.Canceled)
+	}
+	if cursor.closed != 1 {
+		t.Errorf("streamDocuments() closed the cursor %v times, expected 1", cursor.closed)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -67,18 +66,15 @@ func (c *DeleteOneBuilder) Build() (*MongoDeleteOneModel, error) {
 
 	// Processing the filter elements, this can be the literal or just the operations
 	if len(c.Filters) > 0 {
-		filterPrimitives := bson.M{}
+		conditions := make([]string, 0)
 		for _, filterElement := range c.Filters {
-			stringFilter := getOperationString(filterElement.key, filterElement.filterOperation, filterElement.value)
-
-			filterParser := NewFilterParser(stringFilter)
-			parsedFilter, err := filterParser.Parse()
-			if err != nil {
-				return nil, err
-			}
-			filterPrimitives[filterElement.key] = parsedFilter.(primitive.M)[filterElement.key]
+			conditions = append(conditions, getOperationString(filterElement.key, filterElement.filterOperation, filterElement.value))
 		}
 
+		filterPrimitives, err := getOperationsFilter(conditions)
+		if err != nil {
+			return nil, err
+		}
 		model.Filter = filterPrimitives
 	} else if len(c.LiteralFilter) > 0 {
 		filterParser := NewFilterParser(c.LiteralFilter)
@@ -12,6 +12,18 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+const (
+	// transactionRetryTimeout Maximum time we will keep retrying a transaction or its commit
+	transactionRetryTimeout = 120 * time.Second
+	// transientTransactionErrorLabel Server label for errors where the whole transaction can be retried
+	transientTransactionErrorLabel = "TransientTransactionError"
+	// unknownTransactionCommitResultLabel Server label for commits that can be retried
+	unknownTransactionCommitResultLabel = "UnknownTransactionCommitResult"
+)
+
+// ErrNoClient Error returned when the factory was not able to connect to the server
+var ErrNoClient = errors.New("mongodb client is not connected")
+
 type MongoClient interface {
 	Database(string) MongoDatabaseClient
 	Connect() error
@@ -90,11 +102,41 @@ func (cursor mongoCursor) Current() interface{} {
 	return cursor.cursor.Current
 }
 
+// TryNext Gets the next document if it is already available without blocking for the next batch
+func (cursor mongoCursor) TryNext(ctx context.Context) bool {
+	return cursor.cursor.TryNext(ctx)
+}
+
+// Err Returns the last error seen by the cursor, this should be checked once Next returns false
+func (cursor mongoCursor) Err() error {
+	return cursor.cursor.Err()
+}
+
+// Close Closes the cursor in the server, this needs to be called if the cursor is not exhausted
+func (cursor mongoCursor) Close(ctx context.Context) error {
+	return cursor.cursor.Close(ctx)
+}
+
+// ID Returns the server id of the cursor, this will be 0 once the cursor is exhausted
+func (cursor mongoCursor) ID() int64 {
+	return cursor.cursor.ID()
+}
+
+// RemainingBatchLength Returns the number of documents left in the current batch
+func (cursor mongoCursor) RemainingBatchLength() int {
+	return cursor.cursor.RemainingBatchLength()
+}
+
 type mongoSingleResult struct {
-	sr *mongo.SingleResult
+	sr  *mongo.SingleResult
+	err error
 }
 
 func (cursor mongoSingleResult) Decode(destination interface{}) error {
+	if cursor.err != nil {
+		return cursor.err
+	}
+
 	var destType = reflect.TypeOf(destination)
 	if destType.Kind() != reflect.Ptr {
 		return errors.New("dest must be a pointer type")
@@ -104,6 +146,10 @@ func (cursor mongoSingleResult) Decode(destination interface{}) error {
 }
 
 func (cursor mongoSingleResult) Err() error {
+	if cursor.err != nil {
+		return cursor.err
+	}
+
 	return cursor.sr.Err()
 }
 
@@ -158,6 +204,7 @@ func (result *mongoBulkWriteResult) FromMongo(entity *mongo.BulkWriteResult) {
 	result.InsertedCount = entity.InsertedCount
 	result.MatchedCount = entity.MatchedCount
 	result.ModifiedCount = entity.ModifiedCount
+	result.DeletedCount = entity.DeletedCount
 	result.UpsertedCount = entity.UpsertedCount
 	result.UpsertedIDs = entity.UpsertedIDs
 }
@@ -204,6 +251,22 @@ func (f *MongoFactory) WithDatabase(databaseName string) *MongoFactory {
 	return f
 }
 
+// ForDatabase Creates a factory for another database that shares the client of the factory,
+// this avoids opening a client for each database of the same cluster
+// returns a pointer to a MongoFactory object
+func (f *MongoFactory) ForDatabase(databaseName string) *MongoFactory {
+	factory := MongoFactory{
+		Context: f.Context,
+		Client:  f.Client,
+		Logger:  f.Logger,
+		DatabaseContext: &MongoDatabaseContext{
+			ConnectionString: f.DatabaseContext.ConnectionString,
+		},
+	}
+
+	return factory.WithDatabase(databaseName)
+}
+
 // GetClient This will either return an already initiated client or the current
 // active client in the factory, this will avoid having unclosed clients
 // if you need a brand new client please use the NewFactory method to create a brand
@@ -296,3 +359,89 @@ func (f *MongoFactory) StartSession() (mongo.Session, error) {
 	session, err := f.Client.cl.StartSession()
 	return &mongoSession{Session: session}, err
 }
+
+// WithTransaction Runs the callback inside a multi-document transaction, the session context
+// passed to the callback needs to be used by the repositories, using WithContext, so the
+// operations participate in the transaction.
+// The whole transaction is retried if the server labels the error as TransientTransactionError
+// and the commit is retried if it is labeled as UnknownTransactionCommitResult, retries stop
+// after the transactionRetryTimeout is reached.
+//
+// Example:
+//		err := factory.WithTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+//			users := factory.NewRepository("users").WithContext(sessCtx)
+//			if _, err := users.InsertOne(user); err != nil {
+//				return err
+//			}
+//			_, err := factory.NewRepository("audit").WithContext(sessCtx).InsertOne(entry)
+//			return err
+//		})
+func (f *MongoFactory) WithTransaction(ctx context.Context, fn func(sessCtx mongo.SessionContext) error, opts ...*options.TransactionOptions) error {
+	if f.Client == nil {
+		return ErrNoClient
+	}
+
+	// We need the driver session itself, the driver only finds sessions it created in the context
+	session, err := f.Client.cl.StartSession()
+	if err != nil {
+		f.Logger.Exception(err, "There was an error starting the session")
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	timeout := time.Now().Add(transactionRetryTimeout)
+	for {
+		if err := session.StartTransaction(opts...); err != nil {
+			f.Logger.Exception(err, "There was an error starting the transaction")
+			return err
+		}
+
+		sessCtx := mongo.NewSessionContext(ctx, session)
+		if err := fn(sessCtx); err != nil {
+			_ = session.AbortTransaction(ctx)
+			if hasErrorLabel(err, transientTransactionErrorLabel) && time.Now().Before(timeout) {
+				f.Logger.Debug("Transient transaction error, retrying the transaction")
+				continue
+			}
+
+			return err
+		}
+
+		err := f.commitTransaction(sessCtx, timeout)
+		if err != nil && hasErrorLabel(err, transientTransactionErrorLabel) && time.Now().Before(timeout) {
+			f.Logger.Debug("Transient transaction error during commit, retrying the transaction")
+			continue
+		}
+
+		return err
+	}
+}
+
+// commitTransaction Commits the transaction in the session context retrying while the result
+// of the commit is unknown
+func (f *MongoFactory) commitTransaction(sessCtx mongo.SessionContext, timeout time.Time) error {
+	for {
+		err := sessCtx.CommitTransaction(sessCtx)
+		if err == nil {
+			return nil
+		}
+
+		if hasErrorLabel(err, unknownTransactionCommitResultLabel) && time.Now().Before(timeout) {
+			f.Logger.Debug("Unknown transaction commit result, retrying the commit")
+			continue
+		}
+
+		f.Logger.Exception(err, "There was an error committing the transaction")
+		return err
+	}
+}
+
+// hasErrorLabel Checks if a server error, or any error it wraps, has the label
+func hasErrorLabel(err error, label string) bool {
+	var serverError mongo.ServerError
+	if errors.As(err, &serverError) {
+		return serverError.HasErrorLabel(label)
+	}
+
+	return false
+}
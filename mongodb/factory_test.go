@@ -0,0 +1,109 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cjlapao/common-go/log"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// newTransactionTestFactory Creates a factory with a client that is not connected to a server,
+// the transactions without operations are started, committed and aborted in the client
+func newTransactionTestFactory(t *testing.T) *MongoFactory {
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatalf("error creating the client: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = client.Disconnect(context.Background())
+	})
+
+	factory := &MongoFactory{Logger: log.Get()}
+	factory.Client = &mongoClient{factory: factory, cl: client}
+	return factory
+}
+
+func TestMongoFactory_WithTransaction(t *testing.T) {
+	transientErr := mongo.CommandError{Code: 112, Message: "write conflict", Labels: []string{transientTransactionErrorLabel}}
+	callbackErr := errors.New("callback failed")
+
+	tests := []struct {
+		name      string
+		errors    []error
+		wantCalls int
+		wantErr   error
+	}{
+		{
+			name:      "commits the callback",
+			errors:    []error{nil},
+			wantCalls: 1,
+		},
+		{
+			name:      "retries transient errors",
+			errors:    []error{transientErr, transientErr, nil},
+			wantCalls: 3,
+		},
+		{
+			name:      "returns other errors",
+			errors:    []error{callbackErr, nil},
+			wantCalls: 1,
+			wantErr:   callbackErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			factory := newTransactionTestFactory(t)
+
+			calls := 0
+			err := factory.WithTransaction(context.Background(), func(sessCtx mongo.SessionContext) error {
+				if mongo.SessionFromContext(sessCtx) == nil {
+					t.Errorf("callback context has no session")
+				}
+				err := tt.errors[calls]
+				calls++
+				return err
+			})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("MongoFactory.WithTransaction() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("MongoFactory.WithTransaction() calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestMongoFactory_WithTransactionWithoutClient(t *testing.T) {
+	factory := &MongoFactory{Logger: log.Get()}
+
+	err := factory.WithTransaction(context.Background(), func(sessCtx mongo.SessionContext) error {
+		t.Errorf("callback called without a client")
+		return nil
+	})
+
+	if !errors.Is(err, ErrNoClient) {
+		t.Errorf("MongoFactory.WithTransaction() error = %v, wantErr %v", err, ErrNoClient)
+	}
+}
+
+func TestHasErrorLabel(t *testing.T) {
+	labeled := mongo.CommandError{Labels: []string{unknownTransactionCommitResultLabel}}
+
+	if !hasErrorLabel(labeled, unknownTransactionCommitResultLabel) {
+		t.Errorf("hasErrorLabel() = false, want true")
+	}
+	if !hasErrorLabel(fmt.Errorf("commit: %w", labeled), unknownTransactionCommitResultLabel) {
+		t.Errorf("hasErrorLabel() of wrapped error = false, want true")
+	}
+	if hasErrorLabel(labeled, transientTransactionErrorLabel) {
+		t.Errorf("hasErrorLabel() of other label = true, want false")
+	}
+	if hasErrorLabel(errors.New("plain"), transientTransactionErrorLabel) {
+		t.Errorf("hasErrorLabel() of plain error = true, want false")
+	}
+}
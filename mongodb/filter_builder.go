@@ -1,8 +1,8 @@
 package mongodb
 
 import (
-	"encoding/hex"
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/cjlapao/common-go/parser"
@@ -32,9 +32,32 @@ const (
 )
 
 type FilterParser struct {
-	globalFilterTokenizer *parser.Tokenizer
-	globalFilterParser    *parser.Parser
-	filter                string
+	filter     string
+	collection string
+}
+
+// filterQueryOperators Mongodb operators for the comparison operators, they are the same in
+// queries and aggregation expressions
+var filterQueryOperators = map[string]string{
+	"eq": "$eq",
+	"ne": "$ne",
+	"gt": "$gt",
+	"ge": "$gte",
+	"lt": "$lt",
+	"le": "$lte",
+}
+
+// filterFunctionOperators Aggregation operators for the functions with a single parameter
+var filterFunctionOperators = map[string]string{
+	"tolower": "$toLower",
+	"toupper": "$toUpper",
+	"length":  "$strLenCP",
+	"year":    "$year",
+	"month":   "$month",
+	"day":     "$dayOfMonth",
+	"hour":    "$hour",
+	"minute":  "$minute",
+	"second":  "$second",
 }
 
 // NewFilterParser Creates a nem Filter Parser from a odata type of query
@@ -43,19 +66,45 @@ func NewFilterParser(filter string) *FilterParser {
 		filter: filter,
 	}
 
-	result.globalFilterParser = result.filterParser()
-	result.globalFilterTokenizer = result.filterTokenizer()
-
 	return &result
 }
 
-// Parse Creates a MongoDB compatible filter from a odata type of query
+// WithCollection Translates the field names of the filter with the names registered for the
+// collection with RegisterODataModel or RegisterODataFieldNames and converts the values into
+// the types of the model fields
+//
+// Example:
+//		NewFilterParser("createdAt gt 2022-01-01").WithCollection("users").Parse()
+func (filterParser *FilterParser) WithCollection(collection string) *FilterParser {
+	filterParser.collection = collection
+	return filterParser
+}
+
+// Parse Creates a MongoDB compatible filter from a odata type of query, it supports the
+// logical operators (and, or, not), the comparison operators (eq, ne, gt, ge, lt, le, in,
+// has, regex), the arithmetic operators (add, sub, mul, div, mod), the string functions
+// (contains, startswith, endswith, tolower, toupper, length, indexof, substring, trim,
+// concat), the date functions (year, month, day, hour, minute, second, now), null and the
+// any and all lambdas over arrays. Paths can use the odata / navigation.
+// Comparisons using functions or arithmetic are converted into $expr queries. The dates and
+// datetimes are compared as dates, the guids as uuid binaries, the numbers with the m suffix as
+// decimals and the strings compared with the _id, or the RegisterFilterIdFields, as ObjectIDs
+//
+// Example:
+//		NewFilterParser("tolower(name) eq 'john' and age add 1 gt 18").Parse()
+//		NewFilterParser("not (status in ('deleted', 'archived'))").Parse()
+//		NewFilterParser("items/any(i: i/qty gt 0 and i/product/sku eq 'A1')").Parse()
 func (filterParser *FilterParser) Parse() (interface{}, error) {
 	parsedFilter, err := filterParser.parseFilterString(filterParser.filter)
 	if err != nil {
 		return nil, err
 	}
 
+	if filterParser.collection != "" {
+		mapFilterFields(parsedFilter, getODataFieldNames(filterParser.collection), "")
+		coerceFilterValues(parsedFilter, getODataFieldTypes(filterParser.collection), "")
+	}
+
 	result, err := ApplyFilter(parsedFilter)
 
 	if err != nil {
@@ -68,12 +117,7 @@ func (filterParser *FilterParser) Parse() (interface{}, error) {
 // parseFilterString Converts an input string from a base odata type of query into a parse
 // tree that can be used by providers to create a compatible mongodb filter query.
 func (fp FilterParser) parseFilterString(filter string) (*parser.ParseNode, error) {
-	tokens, err := fp.globalFilterTokenizer.Tokenize(filter)
-	if err != nil {
-		return nil, err
-	}
-
-	tree, err := fp.globalFilterParser.Parse(tokens)
+	tree, err := parseFilterExpression(filter)
 	if err != nil {
 		return nil, err
 	}
@@ -81,227 +125,588 @@ func (fp FilterParser) parseFilterString(filter string) (*parser.ParseNode, erro
 	return tree, nil
 }
 
-// filterTokenizer Creates a tokenizer capable of tokenizing filter statements
-func (fp *FilterParser) filterTokenizer() *parser.Tokenizer {
-	tokenizer := parser.Tokenizer{}
-	tokenizer.Add("^\\(", parser.FilterTokenOpenParen)
-	tokenizer.Add("^\\)", parser.FilterTokenCloseParen)
-	tokenizer.Add("^,", parser.FilterTokenComma)
-	tokenizer.Add("^(eq|ne|gt|ge|lt|le|and|or|regex) ", parser.FilterTokenLogical)
-	tokenizer.Add("^(contains|endswith|startswith)", parser.FilterTokenFunc)
-	tokenizer.Add("^-?[0-9]+\\.[0-9]+", parser.FilterTokenFloat)
-	tokenizer.Add("^-?[0-9]+", parser.FilterTokenInteger)
-	tokenizer.Add("^(?i:true|false)", parser.FilterTokenBoolean)
-	tokenizer.Add("^'(''|[^'])*'", parser.FilterTokenString)
-	tokenizer.Add("^-?[0-9]{4,4}-[0-9]{2,2}-[0-9]{2,2}", parser.FilterTokenDate)
-	tokenizer.Add("^[0-9]{2,2}:[0-9]{2,2}(:[0-9]{2,2}(.[0-9]+)?)?", parser.FilterTokenTime)
-	tokenizer.Add("^[0-9]{4,4}-[0-9]{2,2}-[0-9]{2,2}T[0-9]{2,2}:[0-9]{2,2}(:[0-9]{2,2}(.[0-9]+)?)?(Z|[+-][0-9]{2,2}:[0-9]{2,2})", parser.FilterTokenDateTime)
-	tokenizer.Add("^[a-zA-Z][a-zA-Z0-9_.]*", parser.FilterTokenLiteral)
-	tokenizer.Add("^_id", parser.FilterTokenLiteral)
-	tokenizer.Ignore("^ ", parser.FilterTokenWhitespace)
-
-	fp.globalFilterTokenizer = &tokenizer
-	return fp.globalFilterTokenizer
-}
-
-// filterParser creates the definitions for operators and functions
-func (fp *FilterParser) filterParser() *parser.Parser {
-	filterParser := parser.EmptyParser()
-	filterParser.DefineOperator("regex", 2, parser.OpAssociationLeft, 4)
-	filterParser.DefineOperator("gt", 2, parser.OpAssociationLeft, 4)
-	filterParser.DefineOperator("ge", 2, parser.OpAssociationLeft, 4)
-	filterParser.DefineOperator("lt", 2, parser.OpAssociationLeft, 4)
-	filterParser.DefineOperator("le", 2, parser.OpAssociationLeft, 4)
-	filterParser.DefineOperator("eq", 2, parser.OpAssociationLeft, 3)
-	filterParser.DefineOperator("ne", 2, parser.OpAssociationLeft, 3)
-	filterParser.DefineOperator("and", 2, parser.OpAssociationLeft, 2)
-	filterParser.DefineOperator("or", 2, parser.OpAssociationLeft, 1)
-	filterParser.DefineFunction("contains", 2)
-	filterParser.DefineFunction("endswith", 2)
-	filterParser.DefineFunction("startswith", 2)
-
-	fp.globalFilterParser = filterParser
-	return fp.globalFilterParser
-}
-
+// ApplyFilter Converts a filter parse tree into a mongodb filter, the tree needs to be a
+// boolean expression
 func ApplyFilter(node *parser.ParseNode) (bson.M, error) {
+	if !isBooleanFilterNode(node) {
+		return nil, ErrInvalidInput
+	}
 
 	filter := make(bson.M)
+	operation := node.Token.Value.(string)
 
-	if _, ok := node.Token.Value.(string); ok {
-		switch node.Token.Value {
+	switch operation {
+	case "and", "or":
+		leftFilter, err := ApplyFilter(node.Children[0]) // Left children
+		if err != nil {
+			return nil, err
+		}
+		rightFilter, err := ApplyFilter(node.Children[1]) // Right children
+		if err != nil {
+			return nil, err
+		}
+		filter["$"+operation] = []bson.M{leftFilter, rightFilter}
 
-		case "eq":
-			// Escape single quotes in the case of strings
-			if _, valueOk := node.Children[1].Token.Value.(string); valueOk {
-				node.Children[1].Token.Value = strings.Replace(node.Children[1].Token.Value.(string), "'", "", -1)
-			}
-			value := bson.M{"$" + node.Token.Value.(string): node.Children[1].Token.Value}
-			if _, keyOk := node.Children[0].Token.Value.(string); !keyOk {
-				return nil, ErrInvalidInput
-			}
-			filter[node.Children[0].Token.Value.(string)] = value
+	case "not":
+		innerFilter, err := ApplyFilter(node.Children[0])
+		if err != nil {
+			return nil, err
+		}
+		filter["$nor"] = []bson.M{innerFilter}
 
-		case "ne":
-			// Escape single quotes in the case of strings
-			if _, valueOk := node.Children[1].Token.Value.(string); valueOk {
-				node.Children[1].Token.Value = strings.Replace(node.Children[1].Token.Value.(string), "'", "", -1)
-			}
-			value := bson.M{"$" + node.Token.Value.(string): node.Children[1].Token.Value}
-			if _, keyOk := node.Children[0].Token.Value.(string); !keyOk {
-				return nil, ErrInvalidInput
-			}
-			filter[node.Children[0].Token.Value.(string)] = value
+	case "eq", "ne", "gt", "ge", "lt", "le":
+		if isExpressionFilterNode(node.Children[0]) || isExpressionFilterNode(node.Children[1]) {
+			return applyExpressionFilter(node)
+		}
 
-		case "gt":
-			var keyString string
-			if keyString, ok = node.Children[0].Token.Value.(string); !ok {
-				return nil, ErrInvalidInput
-			}
+		field, err := getFilterField(node.Children[0])
+		if err != nil {
+			return nil, err
+		}
+		value, err := getFilterValue(node.Children[1])
+		if err != nil {
+			return nil, err
+		}
 
-			var value bson.M
-			if keyString == "_id" {
-				var idString string
-				if _, ok := node.Children[1].Token.Value.(string); ok {
-					idString = strings.Replace(node.Children[1].Token.Value.(string), "'", "", -1)
-				}
-				decodedString, err := hex.DecodeString(idString)
-				if err != nil || len(decodedString) != 12 {
-					return nil, ErrInvalidInput
-				}
-				objectId := primitive.NewObjectID()
-				objectId.UnmarshalText(decodedString)
-				value = bson.M{"$" + node.Token.Value.(string): objectId}
-			} else {
-				value = bson.M{"$" + node.Token.Value.(string): node.Children[1].Token.Value}
-			}
-			filter[keyString] = value
+		filter[field] = bson.M{filterQueryOperators[operation]: getFilterIdValue(field, node.Children[1], value)}
 
-		case "ge":
-			value := bson.M{"$gte": node.Children[1].Token.Value}
-			if _, ok := node.Children[0].Token.Value.(string); !ok {
-				return nil, ErrInvalidInput
-			}
-			filter[node.Children[0].Token.Value.(string)] = value
+	case "in":
+		if isExpressionFilterNode(node.Children[0]) {
+			return applyExpressionFilter(node)
+		}
 
-		case "lt":
-			value := bson.M{"$" + node.Token.Value.(string): node.Children[1].Token.Value}
-			if _, ok := node.Children[0].Token.Value.(string); !ok {
-				return nil, ErrInvalidInput
+		field, err := getFilterField(node.Children[0])
+		if err != nil {
+			return nil, err
+		}
+		values := bson.A{}
+		for _, child := range node.Children[1:] {
+			value, err := getFilterValue(child)
+			if err != nil {
+				return nil, err
 			}
-			filter[node.Children[0].Token.Value.(string)] = value
+			values = append(values, getFilterIdValue(field, child, value))
+		}
+		filter[field] = bson.M{"$in": values}
 
-		case "le":
-			value := bson.M{"$lte": node.Children[1].Token.Value}
-			if _, ok := node.Children[0].Token.Value.(string); !ok {
-				return nil, ErrInvalidInput
-			}
-			filter[node.Children[0].Token.Value.(string)] = value
+	case "has":
+		field, err := getFilterField(node.Children[0])
+		if err != nil {
+			return nil, err
+		}
 
-		case "regex":
-			// Escape single quotes in the case of strings
-			if _, valueOk := node.Children[1].Token.Value.(string); valueOk {
-				node.Children[1].Token.Value = strings.Replace(node.Children[1].Token.Value.(string), "'", "", -1)
-			}
-			// value := bson.M{"$regex": node.Children[1].Token.Value, "$options": "i"}
-			value := primitive.Regex{
-				Pattern: node.Children[1].Token.Value.(string),
-				Options: "gi",
-			}
+		// integers are treated as bit flags and strings as elements of an array
+		switch node.Children[1].Token.Type {
+		case parser.FilterTokenInteger:
+			filter[field] = bson.M{"$bitsAllSet": node.Children[1].Token.Value}
+		case parser.FilterTokenString:
+			filter[field] = bson.M{"$all": bson.A{unquoteFilterString(node.Children[1].Token.Value.(string))}}
+		default:
+			return nil, ErrInvalidInput
+		}
 
-			if _, ok := node.Children[0].Token.Value.(string); !ok {
-				return nil, ErrInvalidInput
-			}
-			filter[node.Children[0].Token.Value.(string)] = value
+	case "regex":
+		field, err := getFilterField(node.Children[0])
+		if err != nil {
+			return nil, err
+		}
+		pattern, ok := getFilterStringValue(node.Children[1])
+		if !ok {
+			return nil, ErrInvalidInput
+		}
+
+		filter[field] = primitive.Regex{
+			Pattern: pattern,
+			Options: "gi",
+		}
+
+	case "any", "all":
+		return applyLambdaFilter(operation, node.Children[0].Token.Value.(string), node.Children[1:]...)
+
+	//Functions
+	case "startswith", "endswith", "contains":
+		value, ok := getFilterStringValue(node.Children[1])
+		if !ok {
+			return nil, ErrInvalidInput
+		}
+
+		// the value is matched as it is and not as a regular expression
+		pattern := regexp.QuoteMeta(value)
 
-		case "and":
-			leftFilter, err := ApplyFilter(node.Children[0]) // Left children
+		switch operation {
+		case "startswith":
+			pattern = "^" + pattern
+		case "endswith":
+			pattern = pattern + "$"
+		}
+
+		// functions applied to expressions need to use the aggregation regex
+		if node.Children[0].Token.Type != parser.FilterTokenLiteral {
+			input, err := getFilterExpression(node.Children[0])
 			if err != nil {
 				return nil, err
 			}
-			rightFilter, _ := ApplyFilter(node.Children[1]) // Right children
-			if err != nil {
-				return nil, err
+
+			filter["$expr"] = bson.M{"$regexMatch": bson.M{"input": input, "regex": pattern, "options": "i"}}
+			return filter, nil
+		}
+
+		filter[node.Children[0].Token.Value.(string)] = primitive.Regex{
+			Pattern: pattern,
+			Options: "gi",
+		}
+	}
+
+	return filter, nil
+}
+
+// applyLambdaFilter Converts an any lambda into an $elemMatch and an all lambda into a $not
+// $elemMatch of the negated condition, conditions on the element itself are used for arrays
+// of values and conditions on the element fields for arrays of documents
+func applyLambdaFilter(operation string, field string, condition ...*parser.ParseNode) (bson.M, error) {
+	if len(condition) == 0 {
+		return bson.M{field: bson.M{"$exists": true, "$ne": bson.A{}}}, nil
+	}
+
+	// any(a or b) is any(a) or any(b) and all(a and b) is all(a) and all(b), splitting them
+	// allows conditions on the element itself that $elemMatch cannot combine
+	if split, ok := condition[0].Token.Value.(string); ok && condition[0].Token.Type == parser.FilterTokenLogical &&
+		((operation == "any" && split == "or") || (operation == "all" && split == "and")) {
+		leftFilter, err := applyLambdaFilter(operation, field, condition[0].Children[0])
+		if err != nil {
+			return nil, err
+		}
+		rightFilter, err := applyLambdaFilter(operation, field, condition[0].Children[1])
+		if err != nil {
+			return nil, err
+		}
+
+		return bson.M{"$" + split: []bson.M{leftFilter, rightFilter}}, nil
+	}
+
+	conditionFilter, err := ApplyFilter(condition[0])
+	if err != nil {
+		return nil, err
+	}
+	if hasFilterKey(conditionFilter, "$expr") {
+		return nil, fmt.Errorf("%w: functions and arithmetic are not supported inside %v", ErrInvalidInput, operation)
+	}
+
+	elementCondition, isValue, err := getElementCondition(conditionFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	if operation == "all" {
+		if negated, ok := elementCondition["$not"]; ok && isValue && len(elementCondition) == 1 {
+			elementCondition = negated.(bson.M)
+		} else if isValue {
+			elementCondition = bson.M{"$not": elementCondition}
+		} else {
+			elementCondition = bson.M{"$nor": []bson.M{elementCondition}}
+		}
+
+		return bson.M{field: bson.M{"$not": bson.M{"$elemMatch": elementCondition}}}, nil
+	}
+
+	return bson.M{field: bson.M{"$elemMatch": elementCondition}}, nil
+}
+
+// getElementCondition Gets the $elemMatch condition of a lambda filter, the conditions on the
+// element itself are stored in the empty field and are merged into a single operators document
+func getElementCondition(filter bson.M) (bson.M, bool, error) {
+	if !hasFilterKey(filter, "") {
+		return filter, false, nil
+	}
+
+	if len(filter) == 1 {
+		if value, ok := filter[""]; ok {
+			if regex, ok := value.(primitive.Regex); ok {
+				return bson.M{"$regex": regex}, true, nil
 			}
-			filter["$and"] = []bson.M{leftFilter, rightFilter}
+			return value.(bson.M), true, nil
+		}
 
-		case "or":
-			leftFilter, err := ApplyFilter(node.Children[0]) // Left children
-			if err != nil {
-				return nil, err
+		if conditions, ok := filter["$and"].([]bson.M); ok {
+			result := bson.M{}
+			for _, condition := range conditions {
+				elementCondition, isValue, err := getElementCondition(condition)
+				if err != nil {
+					return nil, false, err
+				}
+				for key, value := range elementCondition {
+					if _, exists := result[key]; exists || !isValue {
+						return nil, false, fmt.Errorf("%w: unsupported combination of conditions on the lambda element", ErrInvalidInput)
+					}
+					result[key] = value
+				}
 			}
-			rightFilter, err := ApplyFilter(node.Children[1]) // Right children
+			return result, true, nil
+		}
+
+		if conditions, ok := filter["$nor"].([]bson.M); ok {
+			elementCondition, isValue, err := getElementCondition(conditions[0])
 			if err != nil {
-				return nil, err
+				return nil, false, err
 			}
-			filter["$or"] = []bson.M{leftFilter, rightFilter}
-
-		//Functions
-		case "startswith":
-			if _, ok := node.Children[1].Token.Value.(string); !ok {
-				return nil, ErrInvalidInput
+			if isValue {
+				return bson.M{"$not": elementCondition}, true, nil
 			}
-			node.Children[1].Token.Value = strings.Replace(node.Children[1].Token.Value.(string), "'", "", -1)
+		}
+	}
 
-			value := primitive.Regex{
-				Pattern: "^" + node.Children[1].Token.Value.(string),
-				Options: "gi",
-			}
+	return nil, false, fmt.Errorf("%w: unsupported combination of conditions on the lambda element", ErrInvalidInput)
+}
 
-			filter[node.Children[0].Token.Value.(string)] = value
+// hasFilterKey Checks if the key is used anywhere in the filter
+func hasFilterKey(filter bson.M, key string) bool {
+	for filterKey, value := range filter {
+		if filterKey == key {
+			return true
+		}
 
-		case "endswith":
-			if _, ok := node.Children[1].Token.Value.(string); !ok {
-				return nil, ErrInvalidInput
+		switch value := value.(type) {
+		case bson.M:
+			if hasFilterKey(value, key) {
+				return true
 			}
-			node.Children[1].Token.Value = strings.Replace(node.Children[1].Token.Value.(string), "'", "", -1)
-
-			value := primitive.Regex{
-				Pattern: node.Children[1].Token.Value.(string) + "$",
-				Options: "gi",
+		case []bson.M:
+			for _, element := range value {
+				if hasFilterKey(element, key) {
+					return true
+				}
 			}
-			filter[node.Children[0].Token.Value.(string)] = value
+		}
+	}
 
-		case "contains":
-			if _, ok := node.Children[1].Token.Value.(string); !ok {
-				return nil, ErrInvalidInput
-			}
-			node.Children[1].Token.Value = strings.Replace(node.Children[1].Token.Value.(string), "'", "", -1)
+	return false
+}
+
+// applyExpressionFilter Converts a comparison using functions or arithmetic into an $expr query
+func applyExpressionFilter(node *parser.ParseNode) (bson.M, error) {
+	operation := node.Token.Value.(string)
+	params := bson.A{}
+	for _, child := range node.Children {
+		param, err := getFilterExpression(child)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, param)
+	}
+
+	if operation == "in" {
+		return bson.M{"$expr": bson.M{"$in": bson.A{params[0], params[1:]}}}, nil
+	}
+
+	return bson.M{"$expr": bson.M{filterQueryOperators[operation]: params}}, nil
+}
+
+// getFilterExpression Converts a node into an aggregation expression, literals are field paths
+func getFilterExpression(node *parser.ParseNode) (interface{}, error) {
+	if isBooleanFilterNode(node) && node.Token.Type != parser.FilterTokenFunc {
+		return nil, ErrInvalidInput
+	}
+
+	switch node.Token.Type {
+	case parser.FilterTokenLiteral:
+		return "$" + node.Token.Value.(string), nil
+
+	case parser.FilterTokenString:
+		value := unquoteFilterString(node.Token.Value.(string))
+		// strings starting with $ would be taken as field paths
+		if strings.HasPrefix(value, "$") {
+			return bson.M{"$literal": value}, nil
+		}
+		return value, nil
+
+	case parser.FilterTokenLogical:
+		operator, ok := filterArithmeticOperators[node.Token.Value.(string)]
+		if !ok {
+			return nil, ErrInvalidInput
+		}
+
+		left, err := getFilterExpression(node.Children[0])
+		if err != nil {
+			return nil, err
+		}
+		right, err := getFilterExpression(node.Children[1])
+		if err != nil {
+			return nil, err
+		}
+
+		return bson.M{operator: bson.A{left, right}}, nil
+
+	case parser.FilterTokenFunc:
+		return getFunctionExpression(node)
+
+	default:
+		return getFilterTokenValue(node.Token)
+	}
+}
+
+// getFunctionExpression Converts a function node into an aggregation expression
+func getFunctionExpression(node *parser.ParseNode) (interface{}, error) {
+	function := node.Token.Value.(string)
+
+	switch function {
+	case "now":
+		return "$$NOW", nil
+	case "contains", "startswith", "endswith":
+		filter, err := ApplyFilter(node)
+		if err != nil {
+			return nil, err
+		}
+		if expression, ok := filter["$expr"]; ok {
+			return expression, nil
+		}
+
+		// the function was applied to a field so we need to build the aggregation regex
+		pattern := filter[node.Children[0].Token.Value.(string)].(primitive.Regex).Pattern
+		return bson.M{"$regexMatch": bson.M{"input": "$" + node.Children[0].Token.Value.(string), "regex": pattern, "options": "i"}}, nil
+	}
+
+	params := bson.A{}
+	for _, child := range node.Children {
+		param, err := getFilterExpression(child)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, param)
+	}
+
+	if operator, ok := filterFunctionOperators[function]; ok {
+		return bson.M{operator: params[0]}, nil
+	}
+
+	switch function {
+	case "trim":
+		return bson.M{"$trim": bson.M{"input": params[0]}}, nil
+	case "indexof":
+		return bson.M{"$indexOfCP": params}, nil
+	case "substring":
+		// without length the substring goes until the end of the string
+		if len(params) == 2 {
+			params = append(params, bson.M{"$strLenCP": params[0]})
+		}
+		return bson.M{"$substrCP": params}, nil
+	case "concat":
+		return bson.M{"$concat": params}, nil
+	}
+
+	return nil, ErrInvalidInput
+}
+
+// getFilterField Gets the field name of the left side of a comparison
+func getFilterField(node *parser.ParseNode) (string, error) {
+	if node.Token.Type != parser.FilterTokenLiteral {
+		return "", ErrInvalidInput
+	}
+
+	return node.Token.Value.(string), nil
+}
+
+// getFilterValue Gets the value of the right side of a comparison, strings are unquoted,
+// literals are taken as strings and the other values are converted into their bson type
+func getFilterValue(node *parser.ParseNode) (interface{}, error) {
+	if isBooleanFilterNode(node) || isExpressionFilterNode(node) {
+		return nil, ErrInvalidInput
+	}
+
+	if value, ok := getFilterStringValue(node); ok {
+		return value, nil
+	}
+
+	return getFilterTokenValue(node.Token)
+}
+
+// getFilterStringValue Gets the unquoted value of a string or literal node
+func getFilterStringValue(node *parser.ParseNode) (string, bool) {
+	switch node.Token.Type {
+	case parser.FilterTokenString:
+		return unquoteFilterString(node.Token.Value.(string)), true
+	case parser.FilterTokenLiteral:
+		if len(node.Children) == 0 {
+			return node.Token.Value.(string), true
+		}
+	}
+
+	return "", false
+}
+
+// unquoteFilterString Removes the quotes of a odata string and unescapes the inner quotes
+func unquoteFilterString(value string) string {
+	if len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+		value = value[1 : len(value)-1]
+	}
+
+	return strings.ReplaceAll(value, "''", "'")
+}
+
+// isBooleanFilterNode Checks if the node is a logical operation, a comparison or a boolean function
+func isBooleanFilterNode(node *parser.ParseNode) bool {
+	if node == nil || node.Token == nil {
+		return false
+	}
+
+	name, ok := node.Token.Value.(string)
+	if !ok {
+		return false
+	}
 
-			value := primitive.Regex{
-				Pattern: node.Children[1].Token.Value.(string),
-				Options: "gi",
+	switch node.Token.Type {
+	case parser.FilterTokenLogical:
+		return filterComparisonOperators[name] || name == "and" || name == "or" || name == "not"
+	case parser.FilterTokenFunc:
+		return filterFunctions[name].boolean
+	case filterTokenLambda:
+		return true
+	}
+
+	return false
+}
+
+// isExpressionFilterNode Checks if the node is an arithmetic operation or a function returning a value
+func isExpressionFilterNode(node *parser.ParseNode) bool {
+	name, ok := node.Token.Value.(string)
+	if !ok {
+		return false
+	}
+
+	switch node.Token.Type {
+	case parser.FilterTokenLogical:
+		_, isArithmetic := filterArithmeticOperators[name]
+		return isArithmetic
+	case parser.FilterTokenFunc:
+		function, isFunction := filterFunctions[name]
+		return isFunction && !function.boolean
+	}
+
+	return false
+}
+
+// visitFilterFields Calls visit with the literal nodes of a filter tree that are field paths,
+// the literals compared with a field are values and only the expressions use fields on both
+// sides. The paths inside a lambda are relative to the array so the prefix is the array path
+func visitFilterFields(node *parser.ParseNode, prefix string, visit func(node *parser.ParseNode, prefix string) error) error {
+	name, _ := node.Token.Value.(string)
+
+	switch node.Token.Type {
+	case parser.FilterTokenLiteral:
+		return visit(node, prefix)
+
+	case filterTokenLambda:
+		arrayPath := joinFieldPath(prefix, node.Children[0].Token.Value.(string))
+		if err := visit(node.Children[0], prefix); err != nil {
+			return err
+		}
+		for _, child := range node.Children[1:] {
+			if err := visitFilterFields(child, arrayPath, visit); err != nil {
+				return err
 			}
-			filter[node.Children[0].Token.Value.(string)] = value
+		}
+		return nil
+
+	case parser.FilterTokenLogical:
+		isFieldComparison := false
+		switch name {
+		case "eq", "ne", "gt", "ge", "lt", "le":
+			isFieldComparison = !isExpressionFilterNode(node.Children[0]) && !isExpressionFilterNode(node.Children[1])
+		case "in":
+			isFieldComparison = !isExpressionFilterNode(node.Children[0])
+		case "has", "regex":
+			isFieldComparison = true
+		}
+		if isFieldComparison {
+			return visitFilterFields(node.Children[0], prefix, visit)
+		}
 
+	case parser.FilterTokenFunc:
+		// the second parameter of the string functions is the value being searched
+		if name == "contains" || name == "startswith" || name == "endswith" {
+			return visitFilterFields(node.Children[0], prefix, visit)
 		}
 	}
-	return filter, nil
+
+	for _, child := range node.Children {
+		if err := visitFilterFields(child, prefix, visit); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
+// getOperationsFilter Parses the conditions of the FilterBy methods and joins them, conditions
+// on different fields are merged into a document and the ones sharing a field are joined with
+// $and. The fields are taken from the parsed conditions so the navigation paths use the dot
+// notation
+func getOperationsFilter(conditions []string) (bson.M, error) {
+	filters := make([]bson.M, 0)
+	for _, condition := range conditions {
+		parsedFilter, err := NewFilterParser(condition).Parse()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, parsedFilter.(primitive.M))
+	}
+
+	result := bson.M{}
+	for _, filter := range filters {
+		for key, value := range filter {
+			if _, exists := result[key]; exists {
+				return bson.M{"$and": filters}, nil
+			}
+			result[key] = value
+		}
+	}
+
+	return result, nil
+}
+
+// getOperationString Converts the field, operation and value of the FilterBy methods into an
+// odata type of query, the values keep their types and the string functions quote them
 func getOperationString(field string, operation filterOperation, value interface{}) string {
+	literal := getFilterLiteral(value)
 	switch operation {
 	case "gt":
-		return fmt.Sprintf("%v gt '%v'", field, value)
+		return fmt.Sprintf("%v gt %v", field, literal)
 	case "ge":
-		return fmt.Sprintf("%v ge '%v'", field, value)
+		return fmt.Sprintf("%v ge %v", field, literal)
 	case "lt":
-		return fmt.Sprintf("%v lt '%v'", field, value)
+		return fmt.Sprintf("%v lt %v", field, literal)
 	case "le":
-		return fmt.Sprintf("%v le '%v'", field, value)
+		return fmt.Sprintf("%v le %v", field, literal)
 	case "eq":
-		return fmt.Sprintf("%v eq '%v'", field, value)
+		return fmt.Sprintf("%v eq %v", field, literal)
 	case "ne":
-		return fmt.Sprintf("%v ne '%v'", field, value)
+		return fmt.Sprintf("%v ne %v", field, literal)
 	case "regex":
 		return fmt.Sprintf("%v regex %v", field, value)
 	case "contains":
-		return fmt.Sprintf("contains(%v, '%v')", field, value)
+		return fmt.Sprintf("contains(%v, %v)", field, getFilterStringLiteral(value))
 	case "endswith":
-		return fmt.Sprintf("endswith(%v, '%v')", field, value)
+		return fmt.Sprintf("endswith(%v, %v)", field, getFilterStringLiteral(value))
 	case "startswith":
-		return fmt.Sprintf("startswith(%v, '%v')", field, value)
+		return fmt.Sprintf("startswith(%v, %v)", field, getFilterStringLiteral(value))
 	default:
-		return fmt.Sprintf("%v eq '%v'", field, value)
+		return fmt.Sprintf("%v eq %v", field, literal)
+	}
+}
+
+// parseFilter Converts a filter into a mongodb compatible filter, the filter can be a valid
+// bson document or an odata type of query, empty filters will match all documents
+func parseFilter(filter interface{}) (interface{}, error) {
+	if filter == nil {
+		return bson.D{}, nil
 	}
+
+	if stringFilter, ok := filter.(string); ok {
+		if stringFilter == "" {
+			return bson.D{}, nil
+		}
+
+		return NewFilterParser(stringFilter).Parse()
+	}
+
+	return filter, nil
 }
@@ -0,0 +1,422 @@
+package mongodb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFilterParser_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  string
+		expect  interface{}
+		wantErr error
+	}{
+		{
+			name:   "equal string",
+			filter: "name eq 'O''Brien'",
+			expect: bson.M{"name": bson.M{"$eq": "O'Brien"}},
+		},
+		{
+			name:   "comparison operators",
+			filter: "age ge 18 and age lt 65.5",
+			expect: bson.M{"$and": []bson.M{
+				{"age": bson.M{"$gte": 18}},
+				{"age": bson.M{"$lt": 65.5}},
+			}},
+		},
+		{
+			name:   "precedence",
+			filter: "a eq 1 or b eq 2 and c eq 3",
+			expect: bson.M{"$or": []bson.M{
+				{"a": bson.M{"$eq": 1}},
+				{"$and": []bson.M{
+					{"b": bson.M{"$eq": 2}},
+					{"c": bson.M{"$eq": 3}},
+				}},
+			}},
+		},
+		{
+			name:   "null and boolean",
+			filter: "deletedOn eq null and active ne false",
+			expect: bson.M{"$and": []bson.M{
+				{"deletedOn": bson.M{"$eq": nil}},
+				{"active": bson.M{"$ne": false}},
+			}},
+		},
+		{
+			name:   "not",
+			filter: "not (status eq 'deleted')",
+			expect: bson.M{"$nor": []bson.M{{"status": bson.M{"$eq": "deleted"}}}},
+		},
+		{
+			name:   "in",
+			filter: "status in ('active', 'pending', 1)",
+			expect: bson.M{"status": bson.M{"$in": bson.A{"active", "pending", 1}}},
+		},
+		{
+			name:   "has flags",
+			filter: "permissions has 5",
+			expect: bson.M{"permissions": bson.M{"$bitsAllSet": 5}},
+		},
+		{
+			name:   "has element",
+			filter: "tags has 'admin'",
+			expect: bson.M{"tags": bson.M{"$all": bson.A{"admin"}}},
+		},
+		{
+			name:   "string function on field",
+			filter: "startswith(name, 'jo')",
+			expect: bson.M{"name": primitive.Regex{Pattern: "^jo", Options: "gi"}},
+		},
+		{
+			name:   "string function with regular expression characters",
+			filter: "contains(name, 'a.b') or endswith(name, '.*')",
+			expect: bson.M{"$or": []bson.M{
+				{"name": primitive.Regex{Pattern: `a\.b`, Options: "gi"}},
+				{"name": primitive.Regex{Pattern: `\.\*$`, Options: "gi"}},
+			}},
+		},
+		{
+			name:   "string function on expression with regular expression characters",
+			filter: "startswith(tolower(name), '(a)')",
+			expect: bson.M{"$expr": bson.M{"$regexMatch": bson.M{"input": bson.M{"$toLower": "$name"}, "regex": `^\(a\)`, "options": "i"}}},
+		},
+		{
+			name:   "string function on expression",
+			filter: "contains(tolower(name), 'jo')",
+			expect: bson.M{"$expr": bson.M{"$regexMatch": bson.M{"input": bson.M{"$toLower": "$name"}, "regex": "jo", "options": "i"}}},
+		},
+		{
+			name:   "function comparison",
+			filter: "length(trim(name)) gt 3",
+			expect: bson.M{"$expr": bson.M{"$gt": bson.A{bson.M{"$strLenCP": bson.M{"$trim": bson.M{"input": "$name"}}}, 3}}},
+		},
+		{
+			name:   "substring without length",
+			filter: "substring(code, 2) eq 'AB'",
+			expect: bson.M{"$expr": bson.M{"$eq": bson.A{bson.M{"$substrCP": bson.A{"$code", 2, bson.M{"$strLenCP": "$code"}}}, "AB"}}},
+		},
+		{
+			name:   "arithmetic",
+			filter: "price mul quantity add 10 le 100",
+			expect: bson.M{"$expr": bson.M{"$lte": bson.A{bson.M{"$add": bson.A{bson.M{"$multiply": bson.A{"$price", "$quantity"}}, 10}}, 100}}},
+		},
+		{
+			name:   "arithmetic operators",
+			filter: "total sub discount div 2 mod 3 gt 0",
+			expect: bson.M{"$expr": bson.M{"$gt": bson.A{bson.M{"$subtract": bson.A{"$total", bson.M{"$mod": bson.A{bson.M{"$divide": bson.A{"$discount", 2}}, 3}}}}, 0}}},
+		},
+		{
+			name:   "string functions",
+			filter: "indexof(toupper(concat(first, last)), 'SMITH') ge 0",
+			expect: bson.M{"$expr": bson.M{"$gte": bson.A{bson.M{"$indexOfCP": bson.A{bson.M{"$toUpper": bson.M{"$concat": bson.A{"$first", "$last"}}}, "SMITH"}}, 0}}},
+		},
+		{
+			name:   "month day hour",
+			filter: "month(createdOn) eq 12 and day(createdOn) eq 25 and hour(createdOn) lt 12",
+			expect: bson.M{"$and": []bson.M{
+				{"$and": []bson.M{
+					{"$expr": bson.M{"$eq": bson.A{bson.M{"$month": "$createdOn"}, 12}}},
+					{"$expr": bson.M{"$eq": bson.A{bson.M{"$dayOfMonth": "$createdOn"}, 25}}},
+				}},
+				{"$expr": bson.M{"$lt": bson.A{bson.M{"$hour": "$createdOn"}, 12}}},
+			}},
+		},
+		{
+			name:   "date functions",
+			filter: "year(createdOn) eq year(now())",
+			expect: bson.M{"$expr": bson.M{"$eq": bson.A{bson.M{"$year": "$createdOn"}, bson.M{"$year": "$$NOW"}}}},
+		},
+		{
+			name:   "object id",
+			filter: "_id gt '5f1b9c8e8e4b2a3d4c5e6f70'",
+			expect: bson.M{"_id": bson.M{"$gt": primitive.ObjectID{0x5f, 0x1b, 0x9c, 0x8e, 0x8e, 0x4b, 0x2a, 0x3d, 0x4c, 0x5e, 0x6f, 0x70}}},
+		},
+		{
+			name:   "object id in every operator",
+			filter: "_id in ('5f1b9c8e8e4b2a3d4c5e6f70', 'legacy')",
+			expect: bson.M{"_id": bson.M{"$in": bson.A{primitive.ObjectID{0x5f, 0x1b, 0x9c, 0x8e, 0x8e, 0x4b, 0x2a, 0x3d, 0x4c, 0x5e, 0x6f, 0x70}, "legacy"}}},
+		},
+		{
+			name:   "datetime and date",
+			filter: "createdOn ge 2022-01-01T10:30:00+01:00 and birthday eq 2000-02-29",
+			expect: bson.M{"$and": []bson.M{
+				{"createdOn": bson.M{"$gte": time.Date(2022, 1, 1, 9, 30, 0, 0, time.UTC)}},
+				{"birthday": bson.M{"$eq": time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC)}},
+			}},
+		},
+		{
+			name:   "guid",
+			filter: "token eq 01234567-89ab-cdef-0123-456789abcdef",
+			expect: bson.M{"token": bson.M{"$eq": primitive.Binary{Subtype: 0x04, Data: []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}}}},
+		},
+		{
+			name:   "decimal and large integers",
+			filter: "price eq 10.25m or total gt 99999999999999999999",
+			expect: bson.M{"$or": []bson.M{
+				{"price": bson.M{"$eq": decimalTestValue("10.25")}},
+				{"total": bson.M{"$gt": decimalTestValue("99999999999999999999")}},
+			}},
+		},
+		{
+			name:   "path navigation",
+			filter: "address/city eq 'Lisbon'",
+			expect: bson.M{"address.city": bson.M{"$eq": "Lisbon"}},
+		},
+		{
+			name:   "any on values",
+			filter: "tags/any(t: t eq 'x')",
+			expect: bson.M{"tags": bson.M{"$elemMatch": bson.M{"$eq": "x"}}},
+		},
+		{
+			name:   "any with range",
+			filter: "scores/any(s: s ge 80 and s lt 90)",
+			expect: bson.M{"scores": bson.M{"$elemMatch": bson.M{"$gte": 80, "$lt": 90}}},
+		},
+		{
+			name:   "any with or",
+			filter: "tags/any(t: t eq 'a' or startswith(t, 'b'))",
+			expect: bson.M{"$or": []bson.M{
+				{"tags": bson.M{"$elemMatch": bson.M{"$eq": "a"}}},
+				{"tags": bson.M{"$elemMatch": bson.M{"$regex": primitive.Regex{Pattern: "^b", Options: "gi"}}}},
+			}},
+		},
+		{
+			name:   "all on documents",
+			filter: "items/all(i: i/qty gt 0)",
+			expect: bson.M{"items": bson.M{"$not": bson.M{"$elemMatch": bson.M{"$nor": []bson.M{{"qty": bson.M{"$gt": 0}}}}}}},
+		},
+		{
+			name:   "all on values",
+			filter: "tags/all(t: t ne 'x')",
+			expect: bson.M{"tags": bson.M{"$not": bson.M{"$elemMatch": bson.M{"$not": bson.M{"$ne": "x"}}}}},
+		},
+		{
+			name:   "nested lambdas",
+			filter: "orders/any(o: o/status eq 'open' and o/lines/any(l: l/sku eq 'A1'))",
+			expect: bson.M{"orders": bson.M{"$elemMatch": bson.M{"$and": []bson.M{
+				{"status": bson.M{"$eq": "open"}},
+				{"lines": bson.M{"$elemMatch": bson.M{"sku": bson.M{"$eq": "A1"}}}},
+			}}}},
+		},
+		{
+			name:   "any without lambda",
+			filter: "tags/any()",
+			expect: bson.M{"tags": bson.M{"$exists": true, "$ne": bson.A{}}},
+		},
+		{
+			name:    "lambda without variable",
+			filter:  "tags/any(t: name eq 'x')",
+			wantErr: ErrInvalidInput,
+		},
+		{
+			name:    "all without lambda",
+			filter:  "tags/all()",
+			wantErr: ErrInvalidInput,
+		},
+		{
+			name:    "empty filter",
+			filter:  "",
+			wantErr: ErrInvalidInput,
+		},
+		{
+			name:    "integer key",
+			filter:  "0 eq epc_item_type",
+			wantErr: ErrInvalidInput,
+		},
+		{
+			name:    "unknown operator",
+			filter:  "name eqs epc_item_type",
+			wantErr: ErrInvalidInput,
+		},
+		{
+			name:    "non boolean operand",
+			filter:  "epc_item_type ne 0 and name",
+			wantErr: ErrInvalidInput,
+		},
+		{
+			name:    "function with operator parameter",
+			filter:  "contains(and, epc_item_type)",
+			wantErr: ErrInvalidInput,
+		},
+		{
+			name:    "wrong number of parameters",
+			filter:  "substring(name)",
+			wantErr: ErrInvalidInput,
+		},
+		{
+			name:    "mismatched parenthesis",
+			filter:  "(name eq 'a'",
+			wantErr: ErrInvalidInput,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFilterParser(tt.filter).Parse()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if !reflect.DeepEqual(result, tt.expect) {
+				t.Errorf("Parse() = %v, expected %v", result, tt.expect)
+			}
+		})
+	}
+}
+
+func decimalTestValue(value string) primitive.Decimal128 {
+	decimal, _ := primitive.ParseDecimal128(value)
+	return decimal
+}
+
+func TestGetOperationString(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex("5f1b9c8e8e4b2a3d4c5e6f70")
+
+	tests := []struct {
+		name      string
+		operation filterOperation
+		value     interface{}
+		expect    string
+	}{
+		{name: "string", operation: Equal, value: "john", expect: "name eq 'john'"},
+		{name: "string with quotes", operation: NotEqual, value: "O'Brien", expect: "name ne 'O''Brien'"},
+		{name: "integer", operation: GreaterThan, value: 5, expect: "name gt 5"},
+		{name: "negative integer", operation: LowerThan, value: int64(-5), expect: "name lt -5"},
+		{name: "float", operation: GreaterOrEqualThan, value: 2.5, expect: "name ge 2.5"},
+		{name: "whole float", operation: Equal, value: float64(3), expect: "name eq 3.0"},
+		{name: "boolean", operation: Equal, value: true, expect: "name eq true"},
+		{name: "time", operation: LowerOrEqualThan, value: time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC), expect: "name le 2022-03-01T10:00:00Z"},
+		{name: "nil", operation: Equal, value: nil, expect: "name eq null"},
+		{name: "contains an integer", operation: Contains, value: 5, expect: "contains(name, '5')"},
+		{name: "object id", operation: Equal, value: id, expect: "name eq '5f1b9c8e8e4b2a3d4c5e6f70'"},
+		{name: "contains", operation: Contains, value: "jo", expect: "contains(name, 'jo')"},
+		{name: "starts with", operation: StartsWith, value: "it's", expect: "startswith(name, 'it''s')"},
+		{name: "regex", operation: RegEx, value: "'^jo'", expect: "name regex '^jo'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getOperationString("name", tt.operation, tt.value); got != tt.expect {
+				t.Errorf("getOperationString() = %v, expected %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestGetOperationStringFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  interface{}
+		expect interface{}
+	}{
+		{name: "string", value: "john", expect: bson.M{"name": bson.M{"$eq": "john"}}},
+		{name: "integer", value: 30, expect: bson.M{"name": bson.M{"$eq": 30}}},
+		{name: "float", value: 2.5, expect: bson.M{"name": bson.M{"$eq": 2.5}}},
+		{name: "boolean", value: true, expect: bson.M{"name": bson.M{"$eq": true}}},
+		{name: "time", value: time.Date(2022, 3, 1, 10, 0, 0, 0, time.FixedZone("", 3600)), expect: bson.M{"name": bson.M{"$eq": time.Date(2022, 3, 1, 9, 0, 0, 0, time.UTC)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFilterParser(getOperationString("name", Equal, tt.value)).Parse()
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expect) {
+				t.Errorf("Parse() = %v, expected %v", result, tt.expect)
+			}
+		})
+	}
+}
+
+func TestGetOperationsFilter(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []string
+		expect     bson.M
+		wantErr    bool
+	}{
+		{
+			name:       "fields are merged",
+			conditions: []string{"name eq 'john'", "age gt '30'"},
+			expect:     bson.M{"name": bson.M{"$eq": "john"}, "age": bson.M{"$gt": "30"}},
+		},
+		{
+			name:       "navigation paths use the dot notation",
+			conditions: []string{"user/id eq 'U1'", "address/city eq 'Lisbon'"},
+			expect:     bson.M{"user.id": bson.M{"$eq": "U1"}, "address.city": bson.M{"$eq": "Lisbon"}},
+		},
+		{
+			name:       "conditions sharing a field are joined",
+			conditions: []string{"age gt '30'", "age lt '40'"},
+			expect: bson.M{"$and": []bson.M{
+				{"age": bson.M{"$gt": "30"}},
+				{"age": bson.M{"$lt": "40"}},
+			}},
+		},
+		{
+			name:       "invalid condition",
+			conditions: []string{"name eq"},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := getOperationsFilter(tt.conditions)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getOperationsFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(result, tt.expect) {
+				t.Errorf("getOperationsFilter() = %v, expected %v", result, tt.expect)
+			}
+		})
+	}
+}
+
+func TestFilterByNavigationPath(t *testing.T) {
+	expect := bson.M{"user.id": bson.M{"$eq": "U1"}}
+
+	update, err := NewUpdateOneModelBuilder().FilterBy("user/id", Equal, "U1").Set("name", "john").Build()
+	if err != nil {
+		t.Fatalf("UpdateOneModelBuilder.Build() error = %v", err)
+	}
+	if !reflect.DeepEqual(update.Filter, expect) {
+		t.Errorf("UpdateOneModelBuilder.Build() filter = %v, expected %v", update.Filter, expect)
+	}
+
+	deleteModel, err := NewDeleteOneBuilder().FilterBy("user/id", Equal, "U1").Build()
+	if err != nil {
+		t.Fatalf("DeleteOneBuilder.Build() error = %v", err)
+	}
+	if !reflect.DeepEqual(deleteModel.Filter, expect) {
+		t.Errorf("DeleteOneBuilder.Build() filter = %v, expected %v", deleteModel.Filter, expect)
+	}
+
+	pipeline := *NewEmptyPipeline(nil).FilterBy("user/id", Equal, "U1").buildPipeline()
+	expectedPipeline := bson.A{bson.D{{Key: "$match", Value: expect}}}
+	if !reflect.DeepEqual(pipeline, expectedPipeline) {
+		t.Errorf("PipelineBuilder.FilterBy() = %v, expected %v", pipeline, expectedPipeline)
+	}
+}
+
+func TestFilterByTypedValues(t *testing.T) {
+	model, err := NewUpdateOneModelBuilder().
+		FilterBy("age", GreaterThan, 30).
+		FilterBy("active", Equal, true).
+		Set("name", "john").
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	expect := bson.M{"age": bson.M{"$gt": 30}, "active": bson.M{"$eq": true}}
+	if !reflect.DeepEqual(model.Filter, expect) {
+		t.Errorf("Build() filter = %v, expected %v", model.Filter, expect)
+	}
+}
@@ -0,0 +1,561 @@
+package mongodb
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cjlapao/common-go/parser"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Token types the common parser has no definition for, the lambda type is used for the nodes
+// of the any and all operators and the value type for values already converted to their bson
+// type
+const (
+	filterTokenNull = parser.FilterTokenLiteral + 1 + iota
+	filterTokenColon
+	filterTokenLambda
+	filterTokenGuid
+	filterTokenDecimal
+	filterTokenValue
+)
+
+type filterTokenMatcher struct {
+	regexp    *regexp.Regexp
+	tokenType int
+}
+
+type filterFunction struct {
+	minParams int
+	maxParams int
+	boolean   bool
+}
+
+// filterTokenMatchers Token definitions in the order they are matched, guids and dates need to
+// be matched before the numbers or they would be split into integers and decimals, numbers with
+// the m suffix, before the floats
+var filterTokenMatchers = []filterTokenMatcher{
+	{regexp.MustCompile(`^\(`), parser.FilterTokenOpenParen},
+	{regexp.MustCompile(`^\)`), parser.FilterTokenCloseParen},
+	{regexp.MustCompile(`^,`), parser.FilterTokenComma},
+	{regexp.MustCompile(`^:`), filterTokenColon},
+	{regexp.MustCompile(`^'(''|[^'])*'`), parser.FilterTokenString},
+	{regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`), filterTokenGuid},
+	{regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}:[0-9]{2}(:[0-9]{2}(\.[0-9]+)?)?(Z|[+-][0-9]{2}:[0-9]{2})`), parser.FilterTokenDateTime},
+	{regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}`), parser.FilterTokenDate},
+	{regexp.MustCompile(`^[0-9]{2}:[0-9]{2}(:[0-9]{2}(\.[0-9]+)?)?`), parser.FilterTokenTime},
+	{regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?[mM]\b`), filterTokenDecimal},
+	{regexp.MustCompile(`^-?[0-9]+\.[0-9]+`), parser.FilterTokenFloat},
+	{regexp.MustCompile(`^-?[0-9]+`), parser.FilterTokenInteger},
+	{regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_./]*`), parser.FilterTokenLiteral},
+}
+
+var filterWhitespace = regexp.MustCompile(`^\s+`)
+
+// filterComparisonOperators Operators comparing a value with another one
+var filterComparisonOperators = map[string]bool{
+	"eq":    true,
+	"ne":    true,
+	"gt":    true,
+	"ge":    true,
+	"lt":    true,
+	"le":    true,
+	"has":   true,
+	"in":    true,
+	"regex": true,
+}
+
+// filterArithmeticOperators Arithmetic operators, the value is the mongodb aggregation operator
+var filterArithmeticOperators = map[string]string{
+	"add": "$add",
+	"sub": "$subtract",
+	"mul": "$multiply",
+	"div": "$divide",
+	"mod": "$mod",
+}
+
+// filterFunctions Functions supported in the filter with the number of parameters they accept
+var filterFunctions = map[string]filterFunction{
+	"contains":   {2, 2, true},
+	"startswith": {2, 2, true},
+	"endswith":   {2, 2, true},
+	"tolower":    {1, 1, false},
+	"toupper":    {1, 1, false},
+	"length":     {1, 1, false},
+	"trim":       {1, 1, false},
+	"indexof":    {2, 2, false},
+	"substring":  {2, 3, false},
+	"concat":     {2, 2, false},
+	"year":       {1, 1, false},
+	"month":      {1, 1, false},
+	"day":        {1, 1, false},
+	"hour":       {1, 1, false},
+	"minute":     {1, 1, false},
+	"second":     {1, 1, false},
+	"now":        {0, 0, false},
+}
+
+// tokenizeFilter Splits a filter into tokens, the values are converted to the go types except
+// strings, that keep the quotes, and guids, dates and times that are kept as they were written
+// so they can be converted using the type of the field they are compared with
+func tokenizeFilter(filter string) ([]*parser.Token, error) {
+	result := make([]*parser.Token, 0)
+	target := filter
+
+	for len(target) > 0 {
+		if whitespace := filterWhitespace.FindString(target); whitespace != "" {
+			target = target[len(whitespace):]
+			continue
+		}
+
+		matched := false
+		for _, matcher := range filterTokenMatchers {
+			value := matcher.regexp.FindString(target)
+			if value == "" {
+				continue
+			}
+
+			token, err := newFilterToken(value, matcher.tokenType)
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, token)
+			target = target[len(value):]
+			matched = true
+			break
+		}
+
+		if !matched {
+			return nil, fmt.Errorf("%w: no matching token for %v", ErrInvalidInput, target)
+		}
+	}
+
+	return result, nil
+}
+
+// newFilterToken Creates a token converting the value to the token type
+func newFilterToken(value string, tokenType int) (*parser.Token, error) {
+	token := parser.Token{
+		Value: value,
+		Type:  tokenType,
+	}
+
+	switch tokenType {
+	case parser.FilterTokenInteger:
+		intValue, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			// integers out of the int64 range keep their precision as decimals
+			return newFilterToken(value+"m", filterTokenDecimal)
+		}
+		if intValue == int64(int(intValue)) {
+			token.Value = int(intValue)
+		} else {
+			token.Value = intValue
+		}
+	case parser.FilterTokenFloat:
+		floatValue, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid number %v", ErrInvalidInput, value)
+		}
+		token.Value = floatValue
+	case filterTokenDecimal:
+		decimalValue, err := primitive.ParseDecimal128(strings.TrimRight(value, "mM"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid decimal %v", ErrInvalidInput, value)
+		}
+		token.Value = decimalValue
+	case parser.FilterTokenLiteral:
+		switch strings.ToLower(value) {
+		case "true", "false":
+			token.Type = parser.FilterTokenBoolean
+			token.Value = strings.EqualFold(value, "true")
+		case "null":
+			token.Type = filterTokenNull
+			token.Value = nil
+		}
+	}
+
+	return &token, nil
+}
+
+type filterExpressionParser struct {
+	tokens          []*parser.Token
+	position        int
+	lambdaVariables []string
+}
+
+// parseFilterExpression Parses a filter into a parse tree, operators have the following
+// precedence from lower to higher: or, and, not, comparison (eq, ne, gt, ge, lt, le, has,
+// in, regex), additive (add, sub) and multiplicative (mul, div, mod).
+// Paths use the odata / navigation and are converted to the dot notation, inside the any
+// and all lambdas the paths are relative to the array element
+func parseFilterExpression(filter string) (*parser.ParseNode, error) {
+	tokens, err := tokenizeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	expressionParser := filterExpressionParser{
+		tokens: tokens,
+	}
+
+	node, err := expressionParser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if token := expressionParser.peek(); token != nil {
+		return nil, fmt.Errorf("%w: unexpected token %v", ErrInvalidInput, token.Value)
+	}
+
+	return node, nil
+}
+
+func (p *filterExpressionParser) parseOr() (*parser.ParseNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peekKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = newFilterNode("or", parser.FilterTokenLogical, left, right)
+	}
+
+	return left, nil
+}
+
+func (p *filterExpressionParser) parseAnd() (*parser.ParseNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peekKeyword("and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = newFilterNode("and", parser.FilterTokenLogical, left, right)
+	}
+
+	return left, nil
+}
+
+func (p *filterExpressionParser) parseNot() (*parser.ParseNode, error) {
+	if p.peekKeyword("not") {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return newFilterNode("not", parser.FilterTokenLogical, operand), nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *filterExpressionParser) parseComparison() (*parser.ParseNode, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	token := p.peek()
+	if token == nil || token.Type != parser.FilterTokenLiteral {
+		return left, nil
+	}
+
+	operator := token.Value.(string)
+	if !filterComparisonOperators[operator] {
+		return left, nil
+	}
+	p.next()
+
+	if operator == "in" {
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+
+		return newFilterNode(operator, parser.FilterTokenLogical, append([]*parser.ParseNode{left}, values...)...), nil
+	}
+
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	return newFilterNode(operator, parser.FilterTokenLogical, left, right), nil
+}
+
+func (p *filterExpressionParser) parseAdditive() (*parser.ParseNode, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peekKeyword("add") || p.peekKeyword("sub") {
+		operator := p.next().Value.(string)
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = newFilterNode(operator, parser.FilterTokenLogical, left, right)
+	}
+
+	return left, nil
+}
+
+func (p *filterExpressionParser) parseMultiplicative() (*parser.ParseNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peekKeyword("mul") || p.peekKeyword("div") || p.peekKeyword("mod") {
+		operator := p.next().Value.(string)
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		left = newFilterNode(operator, parser.FilterTokenLogical, left, right)
+	}
+
+	return left, nil
+}
+
+func (p *filterExpressionParser) parsePrimary() (*parser.ParseNode, error) {
+	token := p.next()
+	if token == nil {
+		return nil, fmt.Errorf("%w: unexpected end of filter", ErrInvalidInput)
+	}
+
+	switch token.Type {
+	case parser.FilterTokenOpenParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(parser.FilterTokenCloseParen); err != nil {
+			return nil, err
+		}
+		return node, nil
+
+	case parser.FilterTokenLiteral:
+		name := token.Value.(string)
+		if next := p.peek(); next != nil && next.Type == parser.FilterTokenOpenParen {
+			if strings.HasSuffix(name, "/any") || strings.HasSuffix(name, "/all") {
+				return p.parseLambda(name)
+			}
+			return p.parseFunction(name)
+		}
+		if filterComparisonOperators[name] || isFilterKeyword(name) {
+			return nil, fmt.Errorf("%w: unexpected operator %v", ErrInvalidInput, name)
+		}
+
+		path, err := p.resolvePath(name)
+		if err != nil {
+			return nil, err
+		}
+		return newFilterNode(path, parser.FilterTokenLiteral), nil
+
+	case parser.FilterTokenCloseParen, parser.FilterTokenComma, filterTokenColon:
+		return nil, fmt.Errorf("%w: unexpected token %v", ErrInvalidInput, token.Value)
+
+	default:
+		return &parser.ParseNode{Token: token, Children: make([]*parser.ParseNode, 0)}, nil
+	}
+}
+
+// parseFunction Parses the parameters of a function call and validates them
+func (p *filterExpressionParser) parseFunction(name string) (*parser.ParseNode, error) {
+	function, ok := filterFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown function %v", ErrInvalidInput, name)
+	}
+
+	params, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(params) < function.minParams || len(params) > function.maxParams {
+		return nil, fmt.Errorf("%w: function %v has an invalid number of parameters", ErrInvalidInput, name)
+	}
+
+	return newFilterNode(name, parser.FilterTokenFunc, params...), nil
+}
+
+// parseLambda Parses an any or all lambda, the lambda node children are the array path and
+// the condition, any can also be used without condition to check the array is not empty
+func (p *filterExpressionParser) parseLambda(name string) (*parser.ParseNode, error) {
+	index := strings.LastIndex(name, "/")
+	operation := name[index+1:]
+
+	path, err := p.resolvePath(name[:index])
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, fmt.Errorf("%w: %v needs to be applied to an array field", ErrInvalidInput, operation)
+	}
+	pathNode := newFilterNode(path, parser.FilterTokenLiteral)
+
+	if err := p.expect(parser.FilterTokenOpenParen); err != nil {
+		return nil, err
+	}
+
+	if next := p.peek(); next != nil && next.Type == parser.FilterTokenCloseParen {
+		p.next()
+		if operation == "all" {
+			return nil, fmt.Errorf("%w: all needs a lambda expression", ErrInvalidInput)
+		}
+		return newFilterNode(operation, filterTokenLambda, pathNode), nil
+	}
+
+	variable := p.next()
+	if variable == nil || variable.Type != parser.FilterTokenLiteral || strings.ContainsAny(variable.Value.(string), "/.") {
+		return nil, fmt.Errorf("%w: invalid lambda variable in %v", ErrInvalidInput, name)
+	}
+	if err := p.expect(filterTokenColon); err != nil {
+		return nil, err
+	}
+
+	p.lambdaVariables = append(p.lambdaVariables, variable.Value.(string))
+	condition, err := p.parseOr()
+	p.lambdaVariables = p.lambdaVariables[:len(p.lambdaVariables)-1]
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expect(parser.FilterTokenCloseParen); err != nil {
+		return nil, err
+	}
+
+	return newFilterNode(operation, filterTokenLambda, pathNode, condition), nil
+}
+
+// resolvePath Converts a path to the dot notation, inside a lambda the path needs to start with
+// the lambda variable and is made relative to the array element, the element itself is an
+// empty path
+func (p *filterExpressionParser) resolvePath(name string) (string, error) {
+	if len(p.lambdaVariables) == 0 {
+		return strings.ReplaceAll(name, "/", "."), nil
+	}
+
+	variable := p.lambdaVariables[len(p.lambdaVariables)-1]
+	if name == variable {
+		return "", nil
+	}
+	if strings.HasPrefix(name, variable+"/") {
+		return strings.ReplaceAll(strings.TrimPrefix(name, variable+"/"), "/", "."), nil
+	}
+
+	return "", fmt.Errorf("%w: %v needs to be accessed through the lambda variable %v", ErrInvalidInput, name, variable)
+}
+
+// parseList Parses a comma separated list of expressions between parenthesis
+func (p *filterExpressionParser) parseList() ([]*parser.ParseNode, error) {
+	if err := p.expect(parser.FilterTokenOpenParen); err != nil {
+		return nil, err
+	}
+
+	result := make([]*parser.ParseNode, 0)
+	if next := p.peek(); next != nil && next.Type == parser.FilterTokenCloseParen {
+		p.next()
+		return result, nil
+	}
+
+	for {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, node)
+
+		token := p.next()
+		if token == nil {
+			return nil, fmt.Errorf("%w: mismatched parenthesis", ErrInvalidInput)
+		}
+		if token.Type == parser.FilterTokenCloseParen {
+			return result, nil
+		}
+		if token.Type != parser.FilterTokenComma {
+			return nil, fmt.Errorf("%w: unexpected token %v", ErrInvalidInput, token.Value)
+		}
+	}
+}
+
+func (p *filterExpressionParser) peek() *parser.Token {
+	if p.position >= len(p.tokens) {
+		return nil
+	}
+
+	return p.tokens[p.position]
+}
+
+func (p *filterExpressionParser) next() *parser.Token {
+	token := p.peek()
+	if token != nil {
+		p.position++
+	}
+
+	return token
+}
+
+func (p *filterExpressionParser) peekKeyword(keyword string) bool {
+	token := p.peek()
+	if token == nil || token.Type != parser.FilterTokenLiteral {
+		return false
+	}
+
+	return token.Value.(string) == keyword
+}
+
+func (p *filterExpressionParser) expect(tokenType int) error {
+	token := p.next()
+	if token == nil {
+		return fmt.Errorf("%w: unexpected end of filter", ErrInvalidInput)
+	}
+	if token.Type != tokenType {
+		return fmt.Errorf("%w: unexpected token %v", ErrInvalidInput, token.Value)
+	}
+
+	return nil
+}
+
+// isFilterKeyword Checks if the name is a logical or arithmetic keyword
+func isFilterKeyword(name string) bool {
+	if _, ok := filterArithmeticOperators[name]; ok {
+		return true
+	}
+
+	return name == "and" || name == "or" || name == "not"
+}
+
+// newFilterNode Creates an operator or function node
+func newFilterNode(value string, tokenType int, children ...*parser.ParseNode) *parser.ParseNode {
+	node := parser.ParseNode{
+		Token: &parser.Token{
+			Value: value,
+			Type:  tokenType,
+		},
+		Children: children,
+	}
+
+	for _, child := range children {
+		child.Parent = &node
+	}
+
+	return &node
+}
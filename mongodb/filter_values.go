@@ -0,0 +1,268 @@
+package mongodb
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cjlapao/common-go/parser"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var filterIdFields = map[string]bool{"_id": true}
+var filterIdFieldsLock sync.RWMutex
+
+var objectIdType = reflect.TypeOf(primitive.ObjectID{})
+var dateTimeType = reflect.TypeOf(primitive.DateTime(0))
+var decimalType = reflect.TypeOf(primitive.Decimal128{})
+var binaryType = reflect.TypeOf(primitive.Binary{})
+
+// filterDateLayouts Layouts of the odata dates and datetimes, the seconds are optional
+var filterDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+}
+
+// RegisterFilterIdFields Registers fields that hold ObjectIDs besides the _id, the string values
+// compared with them in the filters are converted to ObjectIDs when they are valid hex ids
+//
+// Example:
+//		RegisterFilterIdFields("userId", "orderId")
+//		NewFilterParser("userId eq '5f1b9c8e8e4b2a3d4c5e6f70'").Parse()
+func RegisterFilterIdFields(fields ...string) {
+	filterIdFieldsLock.Lock()
+	defer filterIdFieldsLock.Unlock()
+
+	for _, field := range fields {
+		filterIdFields[strings.ReplaceAll(field, "/", ".")] = true
+	}
+}
+
+// isFilterIdField Checks if the field holds ObjectIDs
+func isFilterIdField(field string) bool {
+	filterIdFieldsLock.RLock()
+	defer filterIdFieldsLock.RUnlock()
+
+	return filterIdFields[field]
+}
+
+// getFilterIdValue Converts the string values compared with an id field to ObjectIDs, the
+// values that are not valid hex ids are kept as they are
+func getFilterIdValue(field string, node *parser.ParseNode, value interface{}) interface{} {
+	if node.Token.Type != parser.FilterTokenString && node.Token.Type != parser.FilterTokenLiteral {
+		return value
+	}
+
+	idString, ok := value.(string)
+	if !ok || !isFilterIdField(field) {
+		return value
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return value
+	}
+
+	return objectId
+}
+
+// getFilterLiteral Converts a value into its odata literal, the numbers and booleans are written
+// as they are, the dates as datetimes, the decimals with the m suffix and the ObjectIDs as their
+// hex so they are converted back when compared with an id field
+func getFilterLiteral(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%v", value)
+	case float32:
+		return getFilterLiteral(float64(value))
+	case float64:
+		literal := strconv.FormatFloat(value, 'f', -1, 64)
+		if !strings.Contains(literal, ".") {
+			literal += ".0"
+		}
+		return literal
+	case time.Time:
+		return value.UTC().Format(time.RFC3339Nano)
+	case primitive.DateTime:
+		return value.Time().UTC().Format(time.RFC3339Nano)
+	case primitive.Decimal128:
+		return value.String() + "m"
+	}
+
+	return getFilterStringLiteral(value)
+}
+
+// getFilterStringLiteral Quotes a value as an odata string, the quotes in the value are escaped
+// and the ObjectIDs use their hex
+func getFilterStringLiteral(value interface{}) string {
+	if id, ok := value.(primitive.ObjectID); ok {
+		value = id.Hex()
+	}
+
+	return "'" + strings.ReplaceAll(fmt.Sprintf("%v", value), "'", "''") + "'"
+}
+
+// getFilterTokenValue Converts a value token into its bson type, the dates and datetimes are
+// converted into dates and the guids into uuid binaries
+func getFilterTokenValue(token *parser.Token) (interface{}, error) {
+	switch token.Type {
+	case parser.FilterTokenDate, parser.FilterTokenDateTime:
+		date, err := parseFilterDate(token.Value.(string))
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid date %v", ErrInvalidInput, token.Value)
+		}
+		return date, nil
+
+	case filterTokenGuid:
+		guid, err := parseFilterGuid(token.Value.(string))
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid guid %v", ErrInvalidInput, token.Value)
+		}
+		return primitive.Binary{Subtype: 0x04, Data: guid}, nil
+	}
+
+	return token.Value, nil
+}
+
+// coerceFilterValues Converts the values compared with the fields of a filter tree using the
+// field types, the converted values are replaced by value tokens. The paths inside a lambda are
+// relative to the array so the prefix is the array path
+func coerceFilterValues(node *parser.ParseNode, types map[string]reflect.Type, prefix string) {
+	if len(types) == 0 {
+		return
+	}
+
+	name, _ := node.Token.Value.(string)
+
+	switch node.Token.Type {
+	case filterTokenLambda:
+		arrayPath := joinFieldPath(prefix, node.Children[0].Token.Value.(string))
+		for _, child := range node.Children[1:] {
+			coerceFilterValues(child, types, arrayPath)
+		}
+
+	case parser.FilterTokenLogical:
+		switch {
+		case name == "and" || name == "or" || name == "not":
+			for _, child := range node.Children {
+				coerceFilterValues(child, types, prefix)
+			}
+			return
+		case !filterComparisonOperators[name] || name == "has" || name == "regex":
+			return
+		}
+
+		field := node.Children[0]
+		if field.Token.Type != parser.FilterTokenLiteral || len(field.Children) > 0 {
+			return
+		}
+		fieldType, ok := types[joinFieldPath(prefix, field.Token.Value.(string))]
+		if !ok {
+			return
+		}
+
+		for _, child := range node.Children[1:] {
+			if len(child.Children) > 0 {
+				continue
+			}
+			if value, ok := coerceFilterToken(child.Token, fieldType); ok {
+				child.Token = &parser.Token{Value: value, Type: filterTokenValue}
+			}
+		}
+	}
+}
+
+// coerceFilterToken Converts a value token into the type of the field, returns false if the
+// value is kept with its token type
+func coerceFilterToken(token *parser.Token, fieldType reflect.Type) (interface{}, bool) {
+	var text string
+	switch token.Type {
+	case parser.FilterTokenString:
+		text = unquoteFilterString(token.Value.(string))
+	case parser.FilterTokenLiteral, parser.FilterTokenDate, parser.FilterTokenDateTime, parser.FilterTokenTime, filterTokenGuid:
+		text = token.Value.(string)
+	case parser.FilterTokenInteger, parser.FilterTokenFloat, filterTokenDecimal:
+		text = fmt.Sprintf("%v", token.Value)
+	default:
+		return nil, false
+	}
+
+	switch {
+	case fieldType == objectIdType:
+		if objectId, err := primitive.ObjectIDFromHex(text); err == nil {
+			return objectId, true
+		}
+
+	case fieldType == timeType || fieldType == dateTimeType:
+		if date, err := parseFilterDate(text); err == nil {
+			return date, true
+		}
+
+	case fieldType == decimalType:
+		if decimal, err := primitive.ParseDecimal128(text); err == nil {
+			return decimal, true
+		}
+
+	case fieldType == binaryType:
+		if guid, err := parseFilterGuid(text); err == nil {
+			return primitive.Binary{Subtype: 0x04, Data: guid}, true
+		}
+
+	case fieldType.Kind() == reflect.Array && fieldType.Len() == 16 && fieldType.Elem().Kind() == reflect.Uint8:
+		// byte arrays like the uuid types are stored as generic binaries
+		if guid, err := parseFilterGuid(text); err == nil {
+			return primitive.Binary{Subtype: 0x00, Data: guid}, true
+		}
+
+	case fieldType.Kind() == reflect.String:
+		if token.Type != parser.FilterTokenInteger && token.Type != parser.FilterTokenFloat && token.Type != filterTokenDecimal {
+			return text, true
+		}
+
+	case fieldType.Kind() == reflect.Int64:
+		if intValue, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return intValue, true
+		}
+
+	case fieldType.Kind() == reflect.Float32 || fieldType.Kind() == reflect.Float64:
+		if token.Type == parser.FilterTokenInteger {
+			floatValue, _ := strconv.ParseFloat(text, 64)
+			return floatValue, true
+		}
+	}
+
+	return nil, false
+}
+
+// parseFilterDate Parses an odata date or datetime, the dates are taken as UTC
+func parseFilterDate(value string) (time.Time, error) {
+	var err error
+	for _, layout := range filterDateLayouts {
+		var date time.Time
+		if date, err = time.Parse(layout, value); err == nil {
+			return date.UTC(), nil
+		}
+	}
+
+	return time.Time{}, err
+}
+
+// parseFilterGuid Parses the 16 bytes of a guid with or without the dashes
+func parseFilterGuid(value string) ([]byte, error) {
+	guid, err := hex.DecodeString(strings.ReplaceAll(value, "-", ""))
+	if err != nil {
+		return nil, err
+	}
+	if len(guid) != 16 {
+		return nil, fmt.Errorf("%w: invalid guid %v", ErrInvalidInput, value)
+	}
+
+	return guid, nil
+}
@@ -0,0 +1,549 @@
+package mongodb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cjlapao/common-go/guard"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// IndexTagName Struct tag used to declare the indexes of a document
+const IndexTagName = "index"
+
+// ErrInvalidIndexTag Error returned when a document index tag cannot be parsed
+var ErrInvalidIndexTag = errors.New("invalid index tag")
+
+type indexKeyType string
+
+const (
+	AscendingIndex  indexKeyType = "asc"
+	DescendingIndex indexKeyType = "desc"
+	TextIndex       indexKeyType = "text"
+)
+
+// MongoIndex Index definition as returned by the server when listing the collection indexes
+type MongoIndex struct {
+	Name                    string `bson:"name"`
+	Version                 int32  `bson:"v"`
+	Keys                    bson.D `bson:"key"`
+	Unique                  bool   `bson:"unique,omitempty"`
+	Sparse                  bool   `bson:"sparse,omitempty"`
+	ExpireAfterSeconds      *int32 `bson:"expireAfterSeconds,omitempty"`
+	PartialFilterExpression bson.M `bson:"partialFilterExpression,omitempty"`
+	Weights                 bson.M `bson:"weights,omitempty"`
+	DefaultLanguage         string `bson:"default_language,omitempty"`
+	Hidden                  bool   `bson:"hidden,omitempty"`
+	Namespace               string `bson:"ns,omitempty"`
+}
+
+type MongoIndexModel struct {
+	model *mongo.IndexModel
+	Name  string
+	Keys  bson.D
+}
+
+// Transforms the model into a json string representation
+func (model MongoIndexModel) String() string {
+	result, err := json.MarshalIndent(model.model, "", "  ")
+	if err != nil {
+		return ""
+	}
+
+	return string(result)
+}
+
+type indexKey struct {
+	field   string
+	keyType indexKeyType
+}
+
+type IndexModelBuilder struct {
+	name            string
+	keys            []indexKey
+	unique          bool
+	sparse          bool
+	expireAfter     *time.Duration
+	partialFilter   interface{}
+	defaultLanguage string
+	weights         bson.D
+}
+
+// NewIndexModelBuilder Creates a new builder for an index model
+func NewIndexModelBuilder() *IndexModelBuilder {
+	return &IndexModelBuilder{
+		keys:    make([]indexKey, 0),
+		weights: bson.D{},
+	}
+}
+
+// Name Sets the name of the index, if no name is set one will be generated from the keys
+// in the same way the server does, for example `userId_1_createdOn_-1`
+func (c *IndexModelBuilder) Name(name string) *IndexModelBuilder {
+	c.name = name
+	return c
+}
+
+// Ascending Adds fields to the index keys in ascending order, the order of the calls is kept
+// when building compound indexes
+func (c *IndexModelBuilder) Ascending(fields ...string) *IndexModelBuilder {
+	return c.addKeys(AscendingIndex, fields...)
+}
+
+// Descending Adds fields to the index keys in descending order, the order of the calls is kept
+// when building compound indexes
+func (c *IndexModelBuilder) Descending(fields ...string) *IndexModelBuilder {
+	return c.addKeys(DescendingIndex, fields...)
+}
+
+// Text Adds fields to a text index, a collection can only have one text index but it can
+// contain several fields
+func (c *IndexModelBuilder) Text(fields ...string) *IndexModelBuilder {
+	return c.addKeys(TextIndex, fields...)
+}
+
+// TextWeight Sets the weight of a field in a text index, fields without weight default to 1
+func (c *IndexModelBuilder) TextWeight(field string, weight int) *IndexModelBuilder {
+	guard.FatalEmptyOrNil(field)
+
+	for idx, element := range c.weights {
+		if element.Key == field {
+			c.weights[idx].Value = weight
+			return c
+		}
+	}
+
+	c.weights = append(c.weights, bson.E{Key: field, Value: weight})
+	return c
+}
+
+// DefaultLanguage Sets the language used by a text index to tokenize and stem the words
+func (c *IndexModelBuilder) DefaultLanguage(language string) *IndexModelBuilder {
+	c.defaultLanguage = language
+	return c
+}
+
+// Unique Makes the index reject documents with duplicated keys
+func (c *IndexModelBuilder) Unique() *IndexModelBuilder {
+	c.unique = true
+	return c
+}
+
+// Sparse Makes the index only contain documents that have the indexed fields
+func (c *IndexModelBuilder) Sparse() *IndexModelBuilder {
+	c.sparse = true
+	return c
+}
+
+// ExpireAfter Creates a TTL index, documents will be removed by the server once the indexed
+// date field is older than the duration, TTL indexes can only have a single field
+func (c *IndexModelBuilder) ExpireAfter(duration time.Duration) *IndexModelBuilder {
+	c.expireAfter = &duration
+	return c
+}
+
+// PartialFilter Creates a partial index, only the documents matching the filter will be indexed
+// the filter can be a valid bson document or you can use a odata type of query, for example:
+//		builder.PartialFilter("deleted eq false")
+func (c *IndexModelBuilder) PartialFilter(filter interface{}) *IndexModelBuilder {
+	c.partialFilter = filter
+	return c
+}
+
+// Build Builds the index model to use when creating the index
+func (c *IndexModelBuilder) Build() (*MongoIndexModel, error) {
+	if len(c.keys) == 0 {
+		return nil, ErrNoElements
+	}
+
+	keys := bson.D{}
+	for _, key := range c.keys {
+		switch key.keyType {
+		case DescendingIndex:
+			keys = append(keys, bson.E{Key: key.field, Value: -1})
+		case TextIndex:
+			keys = append(keys, bson.E{Key: key.field, Value: "text"})
+		default:
+			keys = append(keys, bson.E{Key: key.field, Value: 1})
+		}
+	}
+
+	name := c.name
+	if name == "" {
+		name = getIndexName(keys)
+	}
+
+	indexOptions := options.Index().SetName(name)
+	if c.unique {
+		indexOptions.SetUnique(true)
+	}
+	if c.sparse {
+		indexOptions.SetSparse(true)
+	}
+
+	if c.expireAfter != nil {
+		if len(keys) > 1 {
+			return nil, fmt.Errorf("index %v: ttl indexes can only have one field", name)
+		}
+		indexOptions.SetExpireAfterSeconds(int32(c.expireAfter.Seconds()))
+	}
+
+	if c.partialFilter != nil {
+		partialFilter, err := parseFilter(c.partialFilter)
+		if err != nil {
+			return nil, err
+		}
+		indexOptions.SetPartialFilterExpression(partialFilter)
+	}
+
+	if len(c.weights) > 0 {
+		indexOptions.SetWeights(c.weights)
+	}
+	if c.defaultLanguage != "" {
+		indexOptions.SetDefaultLanguage(c.defaultLanguage)
+	}
+
+	return &MongoIndexModel{
+		model: &mongo.IndexModel{
+			Keys:    keys,
+			Options: indexOptions,
+		},
+		Name: name,
+		Keys: keys,
+	}, nil
+}
+
+// addKeys Adds the fields to the index keys, repeating fields will only update the key type
+func (c *IndexModelBuilder) addKeys(keyType indexKeyType, fields ...string) *IndexModelBuilder {
+	for _, field := range fields {
+		guard.FatalEmptyOrNil(field)
+
+		has, idx := c.hasKey(field)
+		if !has {
+			c.keys = append(c.keys, indexKey{field: field, keyType: keyType})
+		} else {
+			c.keys[idx].keyType = keyType
+		}
+	}
+
+	return c
+}
+
+// hasKey Checks if a field already exists in the index keys
+func (c *IndexModelBuilder) hasKey(field string) (bool, int) {
+	for idx, key := range c.keys {
+		if key.field == field {
+			return true, idx
+		}
+	}
+
+	return false, -1
+}
+
+// getIndexName Generates the index name the same way the server does
+func getIndexName(keys bson.D) string {
+	parts := make([]string, 0)
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%v_%v", key.Key, key.Value))
+	}
+
+	return strings.Join(parts, "_")
+}
+
+// defaultTextIndexLanguage Language the server uses for text indexes without a default language
+const defaultTextIndexLanguage = "english"
+
+// matches Checks if an existing index has the same keys and options as the model, the text
+// keys are compared in the form the server stores them, with the _fts and _ftsx keys and the
+// fields in the weights
+func (model MongoIndexModel) matches(index MongoIndex) bool {
+	indexOptions := model.model.Options
+	if indexOptions == nil {
+		indexOptions = options.Index()
+	}
+
+	keys := bson.D{}
+	weights := bson.M{}
+	for _, key := range model.Keys {
+		if key.Value != "text" {
+			keys = append(keys, bson.E{Key: key.Key, Value: getIndexNumber(key.Value)})
+			continue
+		}
+
+		if len(weights) == 0 {
+			keys = append(keys, bson.E{Key: "_fts", Value: "text"}, bson.E{Key: "_ftsx", Value: float64(1)})
+		}
+		weights[key.Key] = float64(1)
+	}
+
+	existingKeys := bson.D{}
+	for _, key := range index.Keys {
+		existingKeys = append(existingKeys, bson.E{Key: key.Key, Value: getIndexNumber(key.Value)})
+	}
+	if !reflect.DeepEqual(keys, existingKeys) {
+		return false
+	}
+
+	if getIndexFlag(indexOptions.Unique) != index.Unique || getIndexFlag(indexOptions.Sparse) != index.Sparse {
+		return false
+	}
+
+	if (indexOptions.ExpireAfterSeconds == nil) != (index.ExpireAfterSeconds == nil) ||
+		(index.ExpireAfterSeconds != nil && *indexOptions.ExpireAfterSeconds != *index.ExpireAfterSeconds) {
+		return false
+	}
+
+	if !reflect.DeepEqual(getIndexDocument(indexOptions.PartialFilterExpression), getIndexDocument(index.PartialFilterExpression)) {
+		return false
+	}
+
+	if len(weights) == 0 {
+		return len(index.Weights) == 0
+	}
+
+	for field, weight := range getIndexDocument(indexOptions.Weights) {
+		weights[field] = getIndexNumber(weight)
+	}
+	existingWeights := bson.M{}
+	for field, weight := range index.Weights {
+		existingWeights[field] = getIndexNumber(weight)
+	}
+	if !reflect.DeepEqual(weights, existingWeights) {
+		return false
+	}
+
+	language := defaultTextIndexLanguage
+	if indexOptions.DefaultLanguage != nil {
+		language = *indexOptions.DefaultLanguage
+	}
+	existingLanguage := index.DefaultLanguage
+	if existingLanguage == "" {
+		existingLanguage = defaultTextIndexLanguage
+	}
+
+	return language == existingLanguage
+}
+
+// getIndexNumber Converts the numbers of the index keys and weights into float64, the server
+// can return them with a different type than the one used to create the index
+func getIndexNumber(value interface{}) interface{} {
+	switch number := value.(type) {
+	case int:
+		return float64(number)
+	case int32:
+		return float64(number)
+	case int64:
+		return float64(number)
+	}
+
+	return value
+}
+
+func getIndexFlag(value *bool) bool {
+	return value != nil && *value
+}
+
+// getIndexDocument Converts an index option document into a bson.M with the types the server
+// returns, a nil or empty document returns nil
+func getIndexDocument(document interface{}) bson.M {
+	if document == nil {
+		return nil
+	}
+
+	marshalled, err := bson.Marshal(document)
+	if err != nil {
+		return nil
+	}
+
+	var result bson.M
+	if err := bson.Unmarshal(marshalled, &result); err != nil || len(result) == 0 {
+		return nil
+	}
+
+	return result
+}
+
+// GetIndexModels Creates the index models declared in the document struct tags, the document
+// can be a struct or a pointer to a struct. Fields use the bson name of the field and the tag
+// accepts the following comma separated options:
+//		asc, desc or text: the key type, defaults to asc
+//		unique: creates an unique index
+//		sparse: creates a sparse index
+//		ttl=<seconds>: creates a ttl index that expires documents after the seconds
+//		name=<name>: name of the index, fields with the same name create a compound index
+//		partial=<odata filter>: partial filter for the index, needs to be the last option
+//
+// for example:
+//		type User struct {
+//			Email     string    `bson:"email" index:"unique"`
+//			TenantId  string    `bson:"tenantId" index:"name=tenant_created"`
+//			CreatedOn time.Time `bson:"createdOn" index:"desc,name=tenant_created"`
+//		}
+func GetIndexModels(document interface{}) ([]*MongoIndexModel, error) {
+	documentType := reflect.TypeOf(document)
+	for documentType != nil && documentType.Kind() == reflect.Ptr {
+		documentType = documentType.Elem()
+	}
+
+	if documentType == nil || documentType.Kind() != reflect.Struct {
+		return nil, errors.New("document must be a struct or a pointer to a struct")
+	}
+
+	builders := make([]*IndexModelBuilder, 0)
+	builderNames := make(map[string]*IndexModelBuilder)
+	if err := getIndexBuilders(documentType, "", &builders, builderNames); err != nil {
+		return nil, err
+	}
+
+	result := make([]*MongoIndexModel, 0)
+	for _, builder := range builders {
+		model, err := builder.Build()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, model)
+	}
+
+	return result, nil
+}
+
+// getIndexBuilders Walks the struct fields creating the index builders for the tagged fields
+func getIndexBuilders(documentType reflect.Type, prefix string, builders *[]*IndexModelBuilder, builderNames map[string]*IndexModelBuilder) error {
+	for i := 0; i < documentType.NumField(); i++ {
+		field := documentType.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		fieldName, inline, skip := getBsonFieldName(field)
+		if skip {
+			continue
+		}
+
+		// inlined structs keep the fields at the same level of the document
+		if inline {
+			if field.Type.Kind() == reflect.Struct {
+				if err := getIndexBuilders(field.Type, prefix, builders, builderNames); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		fieldName = prefix + fieldName
+		if tag, ok := field.Tag.Lookup(IndexTagName); ok {
+			if err := parseIndexTag(fieldName, tag, builders, builderNames); err != nil {
+				return err
+			}
+		}
+
+		if field.Type.Kind() == reflect.Struct && field.Type != tTime {
+			if err := getIndexBuilders(field.Type, fieldName+".", builders, builderNames); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseIndexTag Parses the index tag of a field and adds it to the builders
+func parseIndexTag(field string, tag string, builders *[]*IndexModelBuilder, builderNames map[string]*IndexModelBuilder) error {
+	keyType := AscendingIndex
+	name := ""
+	unique := false
+	sparse := false
+	var expireAfter *time.Duration
+	partialFilter := ""
+
+	tagOptions := strings.Split(tag, ",")
+	for idx := 0; idx < len(tagOptions); idx++ {
+		option := strings.TrimSpace(tagOptions[idx])
+		key, value, _ := strings.Cut(option, "=")
+		switch strings.ToLower(key) {
+		case "":
+			continue
+		case string(AscendingIndex), string(DescendingIndex), string(TextIndex):
+			keyType = indexKeyType(strings.ToLower(key))
+		case "unique":
+			unique = true
+		case "sparse":
+			sparse = true
+		case "name":
+			name = value
+		case "ttl":
+			seconds, err := strconv.Atoi(value)
+			if err != nil || seconds < 0 {
+				return fmt.Errorf("%w: field %v has an invalid ttl %v", ErrInvalidIndexTag, field, value)
+			}
+			duration := time.Duration(seconds) * time.Second
+			expireAfter = &duration
+		case "partial":
+			// the partial filter can contain commas so it takes the rest of the tag
+			partialFilter = strings.TrimSpace(strings.Join(append([]string{value}, tagOptions[idx+1:]...), ","))
+			idx = len(tagOptions)
+		default:
+			return fmt.Errorf("%w: field %v has an unknown option %v", ErrInvalidIndexTag, field, option)
+		}
+	}
+
+	builder, exists := builderNames[name]
+	if name == "" || !exists {
+		builder = NewIndexModelBuilder().Name(name)
+		*builders = append(*builders, builder)
+		if name != "" {
+			builderNames[name] = builder
+		}
+	}
+
+	builder.addKeys(keyType, field)
+	if unique {
+		builder.Unique()
+	}
+	if sparse {
+		builder.Sparse()
+	}
+	if expireAfter != nil {
+		builder.ExpireAfter(*expireAfter)
+	}
+	if partialFilter != "" {
+		builder.PartialFilter(partialFilter)
+	}
+
+	return nil
+}
+
+// getBsonFieldName Gets the name of the field when it is stored in mongodb, if the field is
+// inlined or should be skipped
+func getBsonFieldName(field reflect.StructField) (string, bool, bool) {
+	tag, ok := field.Tag.Lookup("bson")
+	if !ok {
+		return strings.ToLower(field.Name), false, false
+	}
+
+	parts := strings.Split(tag, ",")
+	if parts[0] == "-" && len(parts) == 1 {
+		return "", false, true
+	}
+
+	inline := false
+	for _, part := range parts[1:] {
+		if part == "inline" {
+			inline = true
+		}
+	}
+
+	name := parts[0]
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+
+	return name, inline, false
+}
@@ -0,0 +1,209 @@
+package mongodb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type indexTestAddress struct {
+	City    string `bson:"city" index:""`
+	Country string `bson:"country"`
+}
+
+type IndexTestAudit struct {
+	CreatedOn time.Time `bson:"createdOn" index:"desc,name=tenant_created"`
+}
+
+type indexTestDocument struct {
+	ID             string           `bson:"_id"`
+	Email          string           `bson:"email" index:"unique"`
+	TenantId       string           `bson:"tenantId" index:"name=tenant_created"`
+	Name           string           `bson:"name" index:"text"`
+	ExpiresOn      time.Time        `bson:"expiresOn" index:"ttl=3600"`
+	Status         string           `bson:"status" index:"sparse,partial=status ne 'deleted'"`
+	Address        indexTestAddress `bson:"address"`
+	Ignored        string           `bson:"-" index:"asc"`
+	IndexTestAudit `bson:",inline"`
+}
+
+type indexTestInvalidDocument struct {
+	Email string `bson:"email" index:"unknown"`
+}
+
+func TestGetIndexModels(t *testing.T) {
+	type expect struct {
+		name string
+		keys bson.D
+	}
+	tests := []struct {
+		name     string
+		document interface{}
+		expect   []expect
+		wantErr  error
+	}{
+		{
+			name:     "tagged document",
+			document: &indexTestDocument{},
+			expect: []expect{
+				{name: "email_1", keys: bson.D{{Key: "email", Value: 1}}},
+				{name: "tenant_created", keys: bson.D{{Key: "tenantId", Value: 1}, {Key: "createdOn", Value: -1}}},
+				{name: "name_text", keys: bson.D{{Key: "name", Value: "text"}}},
+				{name: "expiresOn_1", keys: bson.D{{Key: "expiresOn", Value: 1}}},
+				{name: "status_1", keys: bson.D{{Key: "status", Value: 1}}},
+				{name: "address.city_1", keys: bson.D{{Key: "address.city", Value: 1}}},
+			},
+		},
+		{
+			name:     "unknown option",
+			document: indexTestInvalidDocument{},
+			wantErr:  ErrInvalidIndexTag,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models, err := GetIndexModels(tt.document)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetIndexModels() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(models) != len(tt.expect) {
+				t.Fatalf("GetIndexModels() returned %v models, expected %v", len(models), len(tt.expect))
+			}
+			for idx, model := range models {
+				if model.Name != tt.expect[idx].name {
+					t.Errorf("GetIndexModels() name = %v, expected %v", model.Name, tt.expect[idx].name)
+				}
+				if !reflect.DeepEqual(model.Keys, tt.expect[idx].keys) {
+					t.Errorf("GetIndexModels() keys = %v, expected %v", model.Keys, tt.expect[idx].keys)
+				}
+			}
+		})
+	}
+}
+
+func TestIndexModelBuilder_Build(t *testing.T) {
+	model, err := NewIndexModelBuilder().Ascending("createdOn").ExpireAfter(time.Hour).PartialFilter("deleted eq false").Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if *model.model.Options.ExpireAfterSeconds != 3600 {
+		t.Errorf("Build() expireAfterSeconds = %v, expected 3600", *model.model.Options.ExpireAfterSeconds)
+	}
+	expectedFilter := bson.M{"deleted": bson.M{"$eq": false}}
+	if !reflect.DeepEqual(model.model.Options.PartialFilterExpression, expectedFilter) {
+		t.Errorf("Build() partialFilterExpression = %v, expected %v", model.model.Options.PartialFilterExpression, expectedFilter)
+	}
+
+	if _, err := NewIndexModelBuilder().Ascending("a", "b").ExpireAfter(time.Hour).Build(); err == nil {
+		t.Errorf("Build() expected an error for a compound ttl index")
+	}
+}
+
+func TestMongoIndexModel_Matches(t *testing.T) {
+	expireAfter := int32(3600)
+	otherExpireAfter := int32(60)
+
+	tests := []struct {
+		name    string
+		builder *IndexModelBuilder
+		index   MongoIndex
+		expect  bool
+	}{
+		{
+			name:    "same keys",
+			builder: NewIndexModelBuilder().Ascending("tenantId").Descending("createdOn"),
+			index:   MongoIndex{Name: "tenantId_1_createdOn_-1", Keys: bson.D{{Key: "tenantId", Value: int32(1)}, {Key: "createdOn", Value: int32(-1)}}},
+			expect:  true,
+		},
+		{
+			name:    "changed key order",
+			builder: NewIndexModelBuilder().Name("tenant").Ascending("tenantId").Descending("createdOn"),
+			index:   MongoIndex{Name: "tenant", Keys: bson.D{{Key: "createdOn", Value: int32(-1)}, {Key: "tenantId", Value: int32(1)}}},
+			expect:  false,
+		},
+		{
+			name:    "changed key direction",
+			builder: NewIndexModelBuilder().Name("email").Descending("email"),
+			index:   MongoIndex{Name: "email", Keys: bson.D{{Key: "email", Value: int32(1)}}},
+			expect:  false,
+		},
+		{
+			name:    "changed unique",
+			builder: NewIndexModelBuilder().Ascending("email").Unique(),
+			index:   MongoIndex{Name: "email_1", Keys: bson.D{{Key: "email", Value: int32(1)}}},
+			expect:  false,
+		},
+		{
+			name:    "same ttl",
+			builder: NewIndexModelBuilder().Ascending("expiresOn").ExpireAfter(time.Hour),
+			index:   MongoIndex{Name: "expiresOn_1", Keys: bson.D{{Key: "expiresOn", Value: int32(1)}}, ExpireAfterSeconds: &expireAfter},
+			expect:  true,
+		},
+		{
+			name:    "changed ttl",
+			builder: NewIndexModelBuilder().Ascending("expiresOn").ExpireAfter(time.Hour),
+			index:   MongoIndex{Name: "expiresOn_1", Keys: bson.D{{Key: "expiresOn", Value: int32(1)}}, ExpireAfterSeconds: &otherExpireAfter},
+			expect:  false,
+		},
+		{
+			name:    "same partial filter",
+			builder: NewIndexModelBuilder().Ascending("status").PartialFilter("deleted eq false"),
+			index:   MongoIndex{Name: "status_1", Keys: bson.D{{Key: "status", Value: int32(1)}}, PartialFilterExpression: bson.M{"deleted": bson.M{"$eq": false}}},
+			expect:  true,
+		},
+		{
+			name:    "changed partial filter",
+			builder: NewIndexModelBuilder().Ascending("status").PartialFilter("deleted eq false"),
+			index:   MongoIndex{Name: "status_1", Keys: bson.D{{Key: "status", Value: int32(1)}}, PartialFilterExpression: bson.M{"deleted": bson.M{"$eq": true}}},
+			expect:  false,
+		},
+		{
+			name:    "same text index",
+			builder: NewIndexModelBuilder().Name("search").Text("name", "description").TextWeight("name", 10),
+			index: MongoIndex{
+				Name:            "search",
+				Keys:            bson.D{{Key: "_fts", Value: "text"}, {Key: "_ftsx", Value: int32(1)}},
+				Weights:         bson.M{"name": int32(10), "description": int32(1)},
+				DefaultLanguage: "english",
+			},
+			expect: true,
+		},
+		{
+			name:    "changed text weights",
+			builder: NewIndexModelBuilder().Name("search").Text("name", "description"),
+			index: MongoIndex{
+				Name:            "search",
+				Keys:            bson.D{{Key: "_fts", Value: "text"}, {Key: "_ftsx", Value: int32(1)}},
+				Weights:         bson.M{"name": int32(1)},
+				DefaultLanguage: "english",
+			},
+			expect: false,
+		},
+		{
+			name:    "changed text language",
+			builder: NewIndexModelBuilder().Name("search").Text("name").DefaultLanguage("spanish"),
+			index: MongoIndex{
+				Name:            "search",
+				Keys:            bson.D{{Key: "_fts", Value: "text"}, {Key: "_ftsx", Value: int32(1)}},
+				Weights:         bson.M{"name": int32(1)},
+				DefaultLanguage: "english",
+			},
+			expect: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model, err := tt.builder.Build()
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+
+			if got := model.matches(tt.index); got != tt.expect {
+				t.Errorf("MongoIndexModel.matches() = %v, expected %v", got, tt.expect)
+			}
+		})
+	}
+}
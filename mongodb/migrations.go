@@ -0,0 +1,339 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cjlapao/common-go-database/migrations"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrationTimeout Timeout applied to each of the migration steps, schema changes like index
+// creation can take longer than normal operations
+const migrationTimeout = 5 * time.Minute
+
+// migrationNotRun Number of applied steps of a migration whose Up was not called in this run
+const migrationNotRun = -1
+
+type migrationStep struct {
+	name string
+	up   func(ctx context.Context, db *mongo.Database) error
+	down func(ctx context.Context, db *mongo.Database) error
+}
+
+// MongoMigration Migration made of schema changes steps, like indexes, validators, capped
+// collections or renames, it implements the migrations.Migration interface so it can be
+// registered in the migrations.SqlMigrationService and be versioned like the sql ones
+type MongoMigration struct {
+	name         string
+	order        int
+	factory      *MongoFactory
+	steps        []migrationStep
+	appliedSteps int
+}
+
+var _ migrations.Migration = &MongoMigration{}
+
+// NewMongoMigration Creates an empty migration for the factory database, use the building
+// blocks to add the steps to it
+//
+// Example:
+//		migration := NewMongoMigration(factory, "users email index", 1).
+//			CreateIndex("users", NewIndexModelBuilder().Ascending("email").Unique()).
+//			RenameCollection("user_logs", "user_audit")
+//		service := migrations.NewMigrationService(NewMongoMigrationRepoForFactory(factory))
+//		service.Register(migration)
+//		service.Run()
+func NewMongoMigration(factory *MongoFactory, name string, order int) *MongoMigration {
+	return &MongoMigration{
+		name:         name,
+		order:        order,
+		factory:      factory,
+		steps:        make([]migrationStep, 0),
+		appliedSteps: migrationNotRun,
+	}
+}
+
+// Name Gets the migration name
+func (m *MongoMigration) Name() string {
+	return m.name
+}
+
+// Order Gets the migration order
+func (m *MongoMigration) Order() int {
+	return m.order
+}
+
+// Up Applies all the steps in order, if a step fails it will stop and return false, the
+// migration service will then call Down to revert the steps that were applied
+func (m *MongoMigration) Up() bool {
+	db, err := m.getDatabase()
+	if err != nil {
+		logger.Exception(err, "error running migration %v", m.name)
+		return false
+	}
+
+	m.appliedSteps = 0
+	for _, step := range m.steps {
+		ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+		err := step.up(ctx, db)
+		cancel()
+
+		if err != nil {
+			logger.Exception(err, "error applying step %v of migration %v", step.name, m.name)
+			return false
+		}
+
+		logger.Debug("Step %v of migration %v was applied successfully", step.name, m.name)
+		m.appliedSteps++
+	}
+
+	return true
+}
+
+// Down Reverts the applied steps in reverse order, if Up failed only the steps that succeeded
+// are reverted. If Up was not called in this run the migration was applied in a previous run
+// and all the steps will be reverted
+func (m *MongoMigration) Down() bool {
+	db, err := m.getDatabase()
+	if err != nil {
+		logger.Exception(err, "error reverting migration %v", m.name)
+		return false
+	}
+
+	appliedSteps := m.appliedSteps
+	if appliedSteps == migrationNotRun {
+		appliedSteps = len(m.steps)
+	}
+
+	result := true
+	for i := appliedSteps - 1; i >= 0; i-- {
+		step := m.steps[i]
+		if step.down == nil {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+		err := step.down(ctx, db)
+		cancel()
+
+		if err != nil {
+			logger.Exception(err, "error reverting step %v of migration %v", step.name, m.name)
+			result = false
+		}
+	}
+
+	m.appliedSteps = 0
+	return result
+}
+
+// CreateCollection Adds a step that creates a collection, reverting it drops the collection
+func (m *MongoMigration) CreateCollection(collection string) *MongoMigration {
+	return m.createCollection(fmt.Sprintf("create collection %v", collection), collection, options.CreateCollection())
+}
+
+// CreateCappedCollection Adds a step that creates a capped collection with a maximum size in
+// bytes and optionally a maximum number of documents, use 0 for no documents limit.
+// Reverting it drops the collection
+func (m *MongoMigration) CreateCappedCollection(collection string, sizeInBytes int64, maxDocuments int64) *MongoMigration {
+	collectionOptions := options.CreateCollection().SetCapped(true).SetSizeInBytes(sizeInBytes)
+	if maxDocuments > 0 {
+		collectionOptions.SetMaxDocuments(maxDocuments)
+	}
+
+	return m.createCollection(fmt.Sprintf("create capped collection %v", collection), collection, collectionOptions)
+}
+
+// DropCollection Adds a step that drops a collection, this cannot be reverted
+func (m *MongoMigration) DropCollection(collection string) *MongoMigration {
+	m.steps = append(m.steps, migrationStep{
+		name: fmt.Sprintf("drop collection %v", collection),
+		up: func(ctx context.Context, db *mongo.Database) error {
+			return db.Collection(collection).Drop(ctx)
+		},
+	})
+
+	return m
+}
+
+// RenameCollection Adds a step that renames a collection, reverting it renames it back
+func (m *MongoMigration) RenameCollection(from string, to string) *MongoMigration {
+	m.steps = append(m.steps, migrationStep{
+		name: fmt.Sprintf("rename collection %v to %v", from, to),
+		up: func(ctx context.Context, db *mongo.Database) error {
+			return renameCollection(ctx, db, from, to)
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			return renameCollection(ctx, db, to, from)
+		},
+	})
+
+	return m
+}
+
+// CreateIndex Adds a step that creates an index built with the IndexModelBuilder, reverting it
+// drops the index
+func (m *MongoMigration) CreateIndex(collection string, builder *IndexModelBuilder) *MongoMigration {
+	model, buildErr := builder.Build()
+	m.steps = append(m.steps, migrationStep{
+		name: fmt.Sprintf("create index on %v", collection),
+		up: func(ctx context.Context, db *mongo.Database) error {
+			if buildErr != nil {
+				return buildErr
+			}
+
+			_, err := db.Collection(collection).Indexes().CreateOne(ctx, *model.model)
+			return err
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			if buildErr != nil {
+				return nil
+			}
+
+			_, err := db.Collection(collection).Indexes().DropOne(ctx, model.Name)
+			return err
+		},
+	})
+
+	return m
+}
+
+// DropIndex Adds a step that drops an index, the builder needs to describe the existing index
+// so reverting it can create the index again
+func (m *MongoMigration) DropIndex(collection string, builder *IndexModelBuilder) *MongoMigration {
+	model, buildErr := builder.Build()
+	m.steps = append(m.steps, migrationStep{
+		name: fmt.Sprintf("drop index on %v", collection),
+		up: func(ctx context.Context, db *mongo.Database) error {
+			if buildErr != nil {
+				return buildErr
+			}
+
+			_, err := db.Collection(collection).Indexes().DropOne(ctx, model.Name)
+			return err
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			if buildErr != nil {
+				return nil
+			}
+
+			_, err := db.Collection(collection).Indexes().CreateOne(ctx, *model.model)
+			return err
+		},
+	})
+
+	return m
+}
+
+// SetValidator Adds a step that sets the collection schema validator, for example a
+// $jsonSchema document, with the validation level (off, strict or moderate) and action
+// (error or warn). Reverting it restores the validator the collection had before
+func (m *MongoMigration) SetValidator(collection string, validator interface{}, level string, action string) *MongoMigration {
+	var previous bson.M
+	m.steps = append(m.steps, migrationStep{
+		name: fmt.Sprintf("set validator on %v", collection),
+		up: func(ctx context.Context, db *mongo.Database) error {
+			current, err := getCollectionValidator(ctx, db, collection)
+			if err != nil {
+				return err
+			}
+
+			previous = current
+			return setCollectionValidator(ctx, db, collection, validator, level, action)
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			if previous == nil {
+				return setCollectionValidator(ctx, db, collection, bson.M{}, "off", "error")
+			}
+
+			return setCollectionValidator(ctx, db, collection, previous["validator"], fmt.Sprintf("%v", previous["validationLevel"]), fmt.Sprintf("%v", previous["validationAction"]))
+		},
+	})
+
+	return m
+}
+
+// Step Adds a custom step to the migration, the down function is optional and is used to
+// revert the step
+func (m *MongoMigration) Step(name string, up func(ctx context.Context, db *mongo.Database) error, down func(ctx context.Context, db *mongo.Database) error) *MongoMigration {
+	m.steps = append(m.steps, migrationStep{
+		name: name,
+		up:   up,
+		down: down,
+	})
+
+	return m
+}
+
+// createCollection Adds a step that creates a collection with options
+func (m *MongoMigration) createCollection(name string, collection string, collectionOptions *options.CreateCollectionOptions) *MongoMigration {
+	m.steps = append(m.steps, migrationStep{
+		name: name,
+		up: func(ctx context.Context, db *mongo.Database) error {
+			return db.CreateCollection(ctx, collection, collectionOptions)
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			return db.Collection(collection).Drop(ctx)
+		},
+	})
+
+	return m
+}
+
+// getDatabase Gets the driver database of the migration factory
+func (m *MongoMigration) getDatabase() (*mongo.Database, error) {
+	if m.factory == nil || m.factory.Database == nil {
+		return nil, errors.New("migration factory has no database")
+	}
+
+	return m.factory.Database.db, nil
+}
+
+// renameCollection Renames a collection in the same database
+func renameCollection(ctx context.Context, db *mongo.Database, from string, to string) error {
+	command := bson.D{
+		{Key: "renameCollection", Value: db.Name() + "." + from},
+		{Key: "to", Value: db.Name() + "." + to},
+	}
+
+	return db.Client().Database("admin").RunCommand(ctx, command).Err()
+}
+
+// getCollectionValidator Gets the validator options of a collection or nil if it has none
+func getCollectionValidator(ctx context.Context, db *mongo.Database, collection string) (bson.M, error) {
+	specifications, err := db.ListCollectionSpecifications(ctx, bson.M{"name": collection})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(specifications) == 0 {
+		return nil, fmt.Errorf("collection %v does not exist", collection)
+	}
+
+	var collectionOptions bson.M
+	if err := bson.Unmarshal(specifications[0].Options, &collectionOptions); err != nil {
+		return nil, err
+	}
+
+	if _, ok := collectionOptions["validator"]; !ok {
+		return nil, nil
+	}
+
+	return collectionOptions, nil
+}
+
+// setCollectionValidator Sets the validator of a collection using the collMod command
+func setCollectionValidator(ctx context.Context, db *mongo.Database, collection string, validator interface{}, level string, action string) error {
+	command := bson.D{
+		{Key: "collMod", Value: collection},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: level},
+		{Key: "validationAction", Value: action},
+	}
+
+	return db.RunCommand(ctx, command).Err()
+}
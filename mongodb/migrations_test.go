@@ -0,0 +1,109 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func newMigrationTestFactory(t *testing.T) *MongoFactory {
+	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatalf("error creating the client: %v", err)
+	}
+
+	return &MongoFactory{
+		Database: &mongoDatabase{name: "migrations", db: client.Database("migrations")},
+	}
+}
+
+func newMigrationTestStep(name string, fail bool, calls *[]string) (func(ctx context.Context, db *mongo.Database) error, func(ctx context.Context, db *mongo.Database) error) {
+	up := func(ctx context.Context, db *mongo.Database) error {
+		*calls = append(*calls, "up "+name)
+		if fail {
+			return errors.New("step failed")
+		}
+		return nil
+	}
+	down := func(ctx context.Context, db *mongo.Database) error {
+		*calls = append(*calls, "down "+name)
+		return nil
+	}
+
+	return up, down
+}
+
+func TestMongoMigration_Down(t *testing.T) {
+	tests := []struct {
+		name     string
+		failStep string
+		runUp    bool
+		wantUp   bool
+		expect   []string
+	}{
+		{
+			name:   "after a successful up",
+			runUp:  true,
+			wantUp: true,
+			expect: []string{"up first", "up second", "up third", "down third", "down second", "down first"},
+		},
+		{
+			name:     "after the first step failed",
+			failStep: "first",
+			runUp:    true,
+			expect:   []string{"up first"},
+		},
+		{
+			name:     "after a partial up",
+			failStep: "third",
+			runUp:    true,
+			expect:   []string{"up first", "up second", "up third", "down second", "down first"},
+		},
+		{
+			name:   "without up",
+			expect: []string{"down third", "down second", "down first"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := make([]string, 0)
+			migration := NewMongoMigration(newMigrationTestFactory(t), "test", 1)
+			for _, name := range []string{"first", "second", "third"} {
+				up, down := newMigrationTestStep(name, name == tt.failStep, &calls)
+				migration.Step(name, up, down)
+			}
+
+			if tt.runUp {
+				if got := migration.Up(); got != tt.wantUp {
+					t.Errorf("MongoMigration.Up() = %v, want %v", got, tt.wantUp)
+				}
+			}
+			if !migration.Down() {
+				t.Errorf("MongoMigration.Down() = false, want true")
+			}
+
+			if !reflect.DeepEqual(calls, tt.expect) {
+				t.Errorf("MongoMigration steps = %v, want %v", calls, tt.expect)
+			}
+		})
+	}
+}
+
+func TestMongoMigration_DownTwice(t *testing.T) {
+	calls := make([]string, 0)
+	up, down := newMigrationTestStep("first", false, &calls)
+	migration := NewMongoMigration(newMigrationTestFactory(t), "test", 1).Step("first", up, down)
+
+	migration.Up()
+	migration.Down()
+	migration.Down()
+
+	expect := []string{"up first", "down first"}
+	if !reflect.DeepEqual(calls, expect) {
+		t.Errorf("MongoMigration steps = %v, want %v", calls, expect)
+	}
+}
@@ -0,0 +1,122 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cjlapao/common-go-database/helpers"
+	"github.com/cjlapao/common-go-database/migrations"
+	"github.com/cjlapao/common-go/log"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoMigrationEntity struct {
+	ID         string    `bson:"_id"`
+	ExecutedOn time.Time `bson:"executed_on"`
+	Name       string    `bson:"name"`
+	Status     bool      `bson:"status"`
+}
+
+type MongoMigrationsRepo struct {
+	context  context.Context
+	logger   *log.Logger
+	database *MongoFactory
+}
+
+// NewMongoMigrationRepo Creates a migrations repository that records the migrations in the
+// global database, this can be used with the migrations.SqlMigrationService
+func NewMongoMigrationRepo() *MongoMigrationsRepo {
+	return NewMongoMigrationRepoForFactory(Get().GlobalDatabase())
+}
+
+// NewMongoMigrationRepoForFactory Creates a migrations repository that records the migrations
+// in the factory database
+func NewMongoMigrationRepoForFactory(factory *MongoFactory) *MongoMigrationsRepo {
+	return &MongoMigrationsRepo{
+		context:  context.Background(),
+		logger:   log.Get(),
+		database: factory,
+	}
+}
+
+// CreateTable Creates the migrations collection if it does not exist yet
+func (m *MongoMigrationsRepo) CreateTable() error {
+	if m.database == nil || m.database.Database == nil {
+		err := fmt.Errorf("error connecting to the migrations database")
+		m.logger.Error(err.Error())
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(m.context, defaultOperationTimeout)
+	defer cancel()
+
+	collections, err := m.database.Database.db.ListCollectionNames(ctx, bson.M{"name": migrations.MIGRATION_TABLE_NAME})
+	if err != nil {
+		m.logger.Exception(err, "error listing the collections on database %v", m.database.Database.name)
+		return err
+	}
+
+	if len(collections) > 0 {
+		return nil
+	}
+
+	if err := m.database.Database.db.CreateCollection(ctx, migrations.MIGRATION_TABLE_NAME); err != nil {
+		err := fmt.Errorf("error creating migrations collection on database %v", m.database.Database.name)
+		m.logger.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetAppliedMigrations Gets all the migrations that were applied successfully
+func (m *MongoMigrationsRepo) GetAppliedMigrations() ([]migrations.MigrationEntity, error) {
+	ctx, cancel := context.WithTimeout(m.context, defaultOperationTimeout)
+	defer cancel()
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "executed_on", Value: 1}})
+	cursor, err := m.database.GetCollection(migrations.MIGRATION_TABLE_NAME).coll.Find(ctx, bson.M{"status": true}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var entities []mongoMigrationEntity
+	if err := cursor.All(ctx, &entities); err != nil {
+		return nil, err
+	}
+
+	queryResult := make([]migrations.MigrationEntity, 0)
+	for _, entity := range entities {
+		queryResult = append(queryResult, migrations.MigrationEntity{
+			ID:         entity.ID,
+			ExecutedOn: entity.ExecutedOn,
+			Name:       entity.Name,
+			Status:     entity.Status,
+		})
+	}
+
+	return queryResult, nil
+}
+
+// SaveMigrationStatus Records the migration status in the migrations collection
+func (m *MongoMigrationsRepo) SaveMigrationStatus(migration migrations.MigrationEntity) error {
+	ctx, cancel := context.WithTimeout(m.context, defaultOperationTimeout)
+	defer cancel()
+
+	entity := mongoMigrationEntity{
+		ID:         uuid.NewString(),
+		ExecutedOn: migration.ExecutedOn,
+		Name:       helpers.NormalizeName(migration.Name),
+		Status:     migration.Status,
+	}
+
+	_, err := m.database.GetCollection(migrations.MIGRATION_TABLE_NAME).coll.InsertOne(ctx, entity)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
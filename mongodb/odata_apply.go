@@ -0,0 +1,206 @@
+package mongodb
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cjlapao/common-go/parser"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// odataApply OData data aggregation keyword, the common odata parser does not define it
+const odataApply = "$apply"
+
+// odataAggregateMethods Aggregation methods and their $group accumulator, the countdistinct
+// collects the distinct values and is projected as their count
+var odataAggregateMethods = map[string]string{
+	"sum":           "$sum",
+	"average":       "$avg",
+	"avg":           "$avg",
+	"min":           "$min",
+	"max":           "$max",
+	"countdistinct": "$addToSet",
+}
+
+var odataAliasRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// odataApplyTransformation A parsed $apply transformation, the filters keep their parse tree
+// so they are validated and translated like the $filter, the other transformations are kept as
+// their pipeline stages and their properties and aggregates so the stages can be built again
+// with the stored field names
+type odataApplyTransformation struct {
+	filter     *parser.ParseNode
+	properties []string
+	aggregates []string
+	stages     []interface{}
+}
+
+// parseApply Parses the $apply transformations separated by /, the supported transformations
+// are filter, groupby, with an optional aggregate, and aggregate. The results of a
+// transformation are the input of the next one
+//
+// Example:
+//		$apply=filter(status eq 'paid')/groupby((customer/country), aggregate(amount with sum as total, $count as orders))
+func parseApply(value string) ([]odataApplyTransformation, error) {
+	transformations := make([]odataApplyTransformation, 0)
+
+	for _, transformation := range splitODataValue(value, '/') {
+		name, arguments, err := splitODataFunction(transformation)
+		if err != nil {
+			return nil, err
+		}
+
+		switch name {
+		case "filter":
+			tree, err := parseFilterExpression(arguments)
+			if err != nil {
+				return nil, err
+			}
+			transformations = append(transformations, odataApplyTransformation{filter: tree})
+
+		case "aggregate":
+			aggregates := splitODataValue(arguments, ',')
+			groupStages, err := getApplyGroupStages(nil, aggregates, nil)
+			if err != nil {
+				return nil, err
+			}
+			transformations = append(transformations, odataApplyTransformation{aggregates: aggregates, stages: groupStages})
+
+		case "groupby":
+			parts := splitODataValue(arguments, ',')
+			properties := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(properties, "(") || !strings.HasSuffix(properties, ")") {
+				return nil, fmt.Errorf("%w: groupby properties need to be between parenthesis", ErrInvalidInput)
+			}
+
+			aggregates := make([]string, 0)
+			if len(parts) > 2 {
+				return nil, fmt.Errorf("%w: groupby only accepts an aggregate transformation", ErrInvalidInput)
+			}
+			if len(parts) == 2 {
+				aggregateName, aggregateArguments, err := splitODataFunction(parts[1])
+				if err != nil {
+					return nil, err
+				}
+				if aggregateName != "aggregate" {
+					return nil, fmt.Errorf("%w: groupby does not support %v", ErrInvalidInput, aggregateName)
+				}
+				aggregates = splitODataValue(aggregateArguments, ',')
+			}
+
+			groupProperties := splitODataValue(properties[1:len(properties)-1], ',')
+			groupStages, err := getApplyGroupStages(groupProperties, aggregates, nil)
+			if err != nil {
+				return nil, err
+			}
+			transformations = append(transformations, odataApplyTransformation{properties: groupProperties, aggregates: aggregates, stages: groupStages})
+
+		default:
+			return nil, fmt.Errorf("%w: %v transformation is not supported", ErrInvalidInput, name)
+		}
+	}
+
+	return transformations, nil
+}
+
+// getApplyFilters Gets the filters of the $apply transformations, the filters before the first
+// groupby or aggregate are applied to the collection documents and the ones after it to the
+// aggregated rows
+func getApplyFilters(transformations []odataApplyTransformation) ([]*parser.ParseNode, []*parser.ParseNode) {
+	documentFilters := make([]*parser.ParseNode, 0)
+	rowFilters := make([]*parser.ParseNode, 0)
+
+	grouped := false
+	for _, transformation := range transformations {
+		switch {
+		case transformation.filter == nil:
+			grouped = true
+		case grouped:
+			rowFilters = append(rowFilters, transformation.filter)
+		default:
+			documentFilters = append(documentFilters, transformation.filter)
+		}
+	}
+
+	return documentFilters, rowFilters
+}
+
+// getApplyGroupStages Creates the $group stage of the grouped properties and aggregates and the
+// $project stage that returns the grouped properties in their original paths. The properties
+// and the aggregated properties are read from their stored paths using the field names
+func getApplyGroupStages(properties []string, aggregates []string, fields map[string]string) ([]interface{}, error) {
+	var groupId interface{}
+	project := bson.D{{Key: "_id", Value: 0}}
+
+	if len(properties) > 0 {
+		groupKeys := bson.M{}
+		for index, property := range properties {
+			path := strings.ReplaceAll(strings.TrimSpace(property), "/", ".")
+			if path == "" {
+				return nil, fmt.Errorf("%w: empty groupby property", ErrInvalidInput)
+			}
+
+			// the group keys cannot contain dots so they are projected back into their path
+			key := fmt.Sprintf("g%v", index)
+			groupKeys[key] = "$" + mapODataField(fields, path)
+			project = append(project, bson.E{Key: path, Value: "$_id." + key})
+		}
+		groupId = groupKeys
+	}
+
+	group := bson.D{{Key: "_id", Value: groupId}}
+	for _, aggregate := range aggregates {
+		alias, accumulator, projection, err := getApplyAggregate(aggregate, fields)
+		if err != nil {
+			return nil, err
+		}
+
+		group = append(group, bson.E{Key: alias, Value: accumulator})
+		project = append(project, bson.E{Key: alias, Value: projection})
+	}
+
+	return []interface{}{
+		bson.D{{Key: "$group", Value: group}},
+		bson.D{{Key: "$project", Value: project}},
+	}, nil
+}
+
+// getApplyAggregate Parses an aggregate expression, either "property with method as alias" or
+// "$count as alias", into its accumulator and the projection of the result, the property is read
+// from its stored path using the field names
+func getApplyAggregate(aggregate string, fields map[string]string) (string, bson.M, interface{}, error) {
+	parts := strings.Fields(aggregate)
+
+	if len(parts) == 3 && parts[0] == "$count" && parts[1] == "as" && odataAliasRegexp.MatchString(parts[2]) {
+		return parts[2], bson.M{"$sum": 1}, 1, nil
+	}
+
+	if len(parts) != 5 || parts[1] != "with" || parts[3] != "as" || !odataAliasRegexp.MatchString(parts[4]) {
+		return "", nil, nil, fmt.Errorf("%w: invalid aggregate expression %v", ErrInvalidInput, strings.TrimSpace(aggregate))
+	}
+
+	accumulator, ok := odataAggregateMethods[parts[2]]
+	if !ok {
+		return "", nil, nil, fmt.Errorf("%w: aggregate method %v is not supported", ErrInvalidInput, parts[2])
+	}
+
+	alias := parts[4]
+	path := "$" + mapODataField(fields, strings.ReplaceAll(parts[0], "/", "."))
+	if parts[2] == "countdistinct" {
+		return alias, bson.M{accumulator: path}, bson.M{"$size": "$" + alias}, nil
+	}
+
+	return alias, bson.M{accumulator: path}, 1, nil
+}
+
+// splitODataFunction Splits a function like transformation into its name and arguments
+func splitODataFunction(value string) (string, string, error) {
+	value = strings.TrimSpace(value)
+	index := strings.Index(value, "(")
+	if index <= 0 || !strings.HasSuffix(value, ")") {
+		return "", "", fmt.Errorf("%w: invalid transformation %v", ErrInvalidInput, value)
+	}
+
+	return strings.TrimSpace(value[:index]), value[index+1 : len(value)-1], nil
+}
@@ -0,0 +1,192 @@
+package mongodb
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestApplyODataQuery_Apply(t *testing.T) {
+	RegisterODataModel("apply_orders", fieldsTestOrder{})
+
+	tests := []struct {
+		name    string
+		query   url.Values
+		expect  bson.A
+		wantErr error
+	}{
+		{
+			name: "filter and groupby with aggregate read the model names",
+			query: url.Values{
+				"$apply": {"filter(status eq 'paid')/groupby((address/city), aggregate(total with sum as amount, $count as orders))"},
+			},
+			expect: bson.A{
+				bson.D{{Key: "$match", Value: bson.M{"status": bson.M{"$eq": "paid"}}}},
+				bson.D{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: bson.M{"g0": "$town"}},
+					{Key: "amount", Value: bson.M{"$sum": "$total"}},
+					{Key: "orders", Value: bson.M{"$sum": 1}},
+				}}},
+				bson.D{{Key: "$project", Value: bson.D{
+					{Key: "_id", Value: 0},
+					{Key: "address.city", Value: "$_id.g0"},
+					{Key: "amount", Value: 1},
+					{Key: "orders", Value: 1},
+				}}},
+			},
+		},
+		{
+			name: "document filters use the model names and types",
+			query: url.Values{
+				"$apply": {"filter(userId eq '5f1b9c8e8e4b2a3d4c5e6f70' and total gt 10)/aggregate(lines/quantity with countdistinct as quantities)"},
+			},
+			expect: bson.A{
+				bson.D{{Key: "$match", Value: bson.M{"$and": []bson.M{
+					{"user_id": bson.M{"$eq": primitive.ObjectID{0x5f, 0x1b, 0x9c, 0x8e, 0x8e, 0x4b, 0x2a, 0x3d, 0x4c, 0x5e, 0x6f, 0x70}}},
+					{"total": bson.M{"$gt": int64(10)}},
+				}}}},
+				bson.D{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: nil},
+					{Key: "quantities", Value: bson.M{"$addToSet": "$items.qty"}},
+				}}},
+				bson.D{{Key: "$project", Value: bson.D{
+					{Key: "_id", Value: 0},
+					{Key: "quantities", Value: bson.M{"$size": "$quantities"}},
+				}}},
+			},
+		},
+		{
+			name: "row filters are not translated",
+			query: url.Values{
+				"$apply": {"aggregate($count as total)/filter(total gt 10)"},
+			},
+			expect: bson.A{
+				bson.D{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: nil},
+					{Key: "total", Value: bson.M{"$sum": 1}},
+				}}},
+				bson.D{{Key: "$project", Value: bson.D{
+					{Key: "_id", Value: 0},
+					{Key: "total", Value: 1},
+				}}},
+				bson.D{{Key: "$match", Value: bson.M{"total": bson.M{"$gt": 10}}}},
+			},
+		},
+		{
+			name: "groupby of the aggregated rows is not translated",
+			query: url.Values{
+				"$apply": {"groupby((address/city, status))/groupby((address/city), aggregate($count as statuses))"},
+			},
+			expect: bson.A{
+				bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: bson.M{"g0": "$town", "g1": "$status"}}}}},
+				bson.D{{Key: "$project", Value: bson.D{
+					{Key: "_id", Value: 0},
+					{Key: "address.city", Value: "$_id.g0"},
+					{Key: "status", Value: "$_id.g1"},
+				}}},
+				bson.D{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: bson.M{"g0": "$address.city"}},
+					{Key: "statuses", Value: bson.M{"$sum": 1}},
+				}}},
+				bson.D{{Key: "$project", Value: bson.D{
+					{Key: "_id", Value: 0},
+					{Key: "address.city", Value: "$_id.g0"},
+					{Key: "statuses", Value: 1},
+				}}},
+			},
+		},
+		{
+			name:    "unsupported transformation",
+			query:   url.Values{"$apply": {"compute(total mul 2 as double)"}},
+			wantErr: ErrInvalidInput,
+		},
+		{
+			name:    "invalid aggregate",
+			query:   url.Values{"$apply": {"aggregate(total with median as middle)"}},
+			wantErr: ErrInvalidInput,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queryMap, err := parseODataQuery(tt.query)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("parseODataQuery() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseODataQuery() error = %v", err)
+			}
+			mapODataQuery("apply_orders", queryMap)
+
+			builder := NewEmptyPipeline(nil)
+			if err := applyODataQuery(builder, "apply_orders", queryMap); err != nil {
+				t.Fatalf("applyODataQuery() error = %v", err)
+			}
+
+			pipeline := *builder.buildPipeline()
+			if !reflect.DeepEqual(pipeline, tt.expect) {
+				t.Errorf("applyODataQuery() = %v, expected %v", pipeline, tt.expect)
+			}
+		})
+	}
+}
+
+func TestODataParser_ApplyPolicy(t *testing.T) {
+	policy := &ODataPolicy{
+		FilterableFields:    []string{"status"},
+		DisallowedOperators: []string{"contains"},
+		AllowApply:          true,
+	}
+
+	tests := []struct {
+		name    string
+		policy  *ODataPolicy
+		apply   string
+		wantErr error
+	}{
+		{
+			name:   "allowed filter field",
+			policy: policy,
+			apply:  "filter(status eq 'paid')/aggregate($count as orders)",
+		},
+		{
+			name:    "filter field not allowed",
+			policy:  policy,
+			apply:   "filter(total gt 10)/aggregate($count as orders)",
+			wantErr: ErrODataPolicyViolation,
+		},
+		{
+			name:   "row filters use the aggregate aliases",
+			policy: policy,
+			apply:  "aggregate($count as orders)/filter(orders gt 10)",
+		},
+		{
+			name:    "row filter operator not allowed",
+			policy:  policy,
+			apply:   "groupby((status))/filter(contains(status, 'pa'))",
+			wantErr: ErrODataPolicyViolation,
+		},
+		{
+			name:    "apply not allowed",
+			policy:  &ODataPolicy{},
+			apply:   "filter(status eq 'paid')",
+			wantErr: ErrODataPolicyViolation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			odataParser := EmptyODataParser(&mongoCollection{name: "apply_orders"}).WithPolicy(tt.policy)
+
+			_, err := odataParser.parseQuery(url.Values{"$apply": {tt.apply}})
+			if (err != nil) != (tt.wantErr != nil) || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Errorf("parseQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
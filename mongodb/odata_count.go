@@ -0,0 +1,125 @@
+package mongodb
+
+import (
+	"net/url"
+
+	"github.com/cjlapao/common-go/odata"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// odataCountExcludedKeywords Keywords that do not change the number of matching records
+var odataCountExcludedKeywords = []string{
+	odata.Top,
+	odata.Skip,
+	odata.OrderBy,
+	odata.Select,
+	odataExpand,
+	odataSkipToken,
+}
+
+type odataCountResult struct {
+	count int
+	err   error
+}
+
+// WithConcurrentCount Runs the count of the odata responses in parallel with the query instead
+// of before it, this reduces the response time of large collections at the cost of a second
+// connection from the pool
+func (odataParser *ODataParser) WithConcurrentCount() *ODataParser {
+	odataParser.concurrentCount = true
+	return odataParser
+}
+
+// Count Counts the records matching an odata query ignoring $top and $skip, this can be used to
+// implement the $count path segment that returns only the number of records
+//
+// Example:
+//		// GET /users/$count?$filter=status eq 'active'
+//		count, err := collection.OData().Count(request.URL.Query())
+func (odataParser *ODataParser) Count(query url.Values) (int, error) {
+	queryMap, err := odataParser.parseQuery(query)
+	if err != nil {
+		return 0, err
+	}
+
+	return odataParser.count(getCountQuery(queryMap))
+}
+
+// startCount Starts counting the records matching the query, with the concurrent count the
+// count runs in the background, the returned function waits for its result
+func (odataParser *ODataParser) startCount(queryMap map[string]interface{}) func() (int, error) {
+	countQuery := getCountQuery(queryMap)
+
+	if !odataParser.concurrentCount {
+		count, err := odataParser.count(countQuery)
+		return func() (int, error) {
+			return count, err
+		}
+	}
+
+	result := make(chan odataCountResult, 1)
+	go func() {
+		count, err := odataParser.count(countQuery)
+		result <- odataCountResult{count: count, err: err}
+	}()
+
+	return func() (int, error) {
+		countResult := <-result
+		return countResult.count, countResult.err
+	}
+}
+
+// count Counts the records matching a count query using the same stages as the odata query
+func (odataParser *ODataParser) count(countQuery map[string]interface{}) (int, error) {
+	builder := NewEmptyPipeline(odataParser.Collection).WithContext(odataParser.context).withScope(odataParser.scope)
+	if err := applyODataQuery(builder, odataParser.Collection.name, countQuery); err != nil {
+		return 0, err
+	}
+
+	builder.Count()
+	cursor, err := builder.Aggregate()
+	if err != nil {
+		logger.Exception(err, "There was an error counting the odata query records")
+		return 0, err
+	}
+
+	var element []bson.M
+	if err := cursor.cursor.All(odataParser.context, &element); err != nil {
+		return 0, err
+	}
+	if len(element) == 0 {
+		return 0, nil
+	}
+
+	return getCountValue(element[0]["count"]), nil
+}
+
+// getCountQuery Copies the query without the keywords that do not change the number of records
+func getCountQuery(queryMap map[string]interface{}) map[string]interface{} {
+	countQuery := make(map[string]interface{})
+	for key, value := range queryMap {
+		countQuery[key] = value
+	}
+
+	for _, key := range odataCountExcludedKeywords {
+		delete(countQuery, key)
+	}
+
+	return countQuery
+}
+
+// getCountValue Converts the $count stage result, it is an int32 or an int64 for large counts
+func getCountValue(value interface{}) int {
+	switch count := value.(type) {
+	case int32:
+		return int(count)
+	case int64:
+		return int(count)
+	case int:
+		return count
+	case float64:
+		return int(count)
+	}
+
+	return 0
+}
@@ -0,0 +1,143 @@
+package mongodb
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/cjlapao/common-go/odata"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestParseODataQuery_Count(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   url.Values
+		expect  interface{}
+		wantErr error
+	}{
+		{
+			name:   "without count",
+			query:  url.Values{},
+			expect: false,
+		},
+		{
+			name:   "true",
+			query:  url.Values{"$count": {"true"}},
+			expect: true,
+		},
+		{
+			name:   "empty value",
+			query:  url.Values{"$count": {""}},
+			expect: true,
+		},
+		{
+			name:   "false",
+			query:  url.Values{"$count": {" FALSE "}},
+			expect: false,
+		},
+		{
+			name:   "inlinecount allpages",
+			query:  url.Values{"$inlinecount": {"allpages"}},
+			expect: true,
+		},
+		{
+			name:    "invalid value",
+			query:   url.Values{"$count": {"yes"}},
+			wantErr: ErrInvalidInput,
+		},
+		{
+			name:    "duplicate",
+			query:   url.Values{"$count": {"true", "false"}},
+			wantErr: ErrInvalidInput,
+		},
+		{
+			name:    "count and inlinecount",
+			query:   url.Values{"$count": {"true"}, "$inlinecount": {"allpages"}},
+			wantErr: ErrInvalidInput,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queryMap, err := parseODataQuery(tt.query)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("parseODataQuery() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseODataQuery() error = %v", err)
+			}
+
+			if count := queryMap[odata.Count]; count != tt.expect {
+				t.Errorf("parseODataQuery() $count = %v, expected %v", count, tt.expect)
+			}
+		})
+	}
+}
+
+func TestGetCountQuery(t *testing.T) {
+	queryMap, err := parseODataQuery(url.Values{
+		"$count":   {"true"},
+		"$filter":  {"status eq 'paid'"},
+		"$orderby": {"name desc"},
+		"$select":  {"name"},
+		"$top":     {"10"},
+		"$skip":    {"20"},
+	})
+	if err != nil {
+		t.Fatalf("parseODataQuery() error = %v", err)
+	}
+	queryMap[odataSkipToken] = bson.M{"_id": bson.M{"$gt": "A1"}}
+
+	countQuery := getCountQuery(queryMap)
+
+	for _, key := range odataCountExcludedKeywords {
+		if _, ok := countQuery[key]; ok {
+			t.Errorf("getCountQuery() has %v", key)
+		}
+	}
+	for _, key := range []string{odata.Top, odata.Skip, odata.OrderBy, odata.Select, odataSkipToken} {
+		if _, ok := queryMap[key]; !ok {
+			t.Errorf("getCountQuery() removed %v from the query", key)
+		}
+	}
+
+	builder := NewEmptyPipeline(nil)
+	if err := applyODataQuery(builder, "count_orders", countQuery); err != nil {
+		t.Fatalf("applyODataQuery() error = %v", err)
+	}
+	builder.Count()
+
+	expect := bson.A{
+		bson.D{{Key: "$match", Value: bson.M{"status": bson.M{"$eq": "paid"}}}},
+		bson.D{{Key: "$count", Value: "count"}},
+	}
+	pipeline := *builder.buildPipeline()
+	if !reflect.DeepEqual(pipeline, expect) {
+		t.Errorf("getCountQuery() pipeline = %v, expected %v", pipeline, expect)
+	}
+}
+
+func TestGetCountValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  interface{}
+		expect int
+	}{
+		{name: "int32", value: int32(12), expect: 12},
+		{name: "int64", value: int64(5000000000), expect: 5000000000},
+		{name: "int", value: 7, expect: 7},
+		{name: "float64", value: float64(3), expect: 3},
+		{name: "missing", value: nil, expect: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := getCountValue(tt.value); result != tt.expect {
+				t.Errorf("getCountValue() = %v, expected %v", result, tt.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package mongodb
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// odataExpand OData keyword to expand the navigation properties, the common odata parser does
+// not define it
+const odataExpand = "$expand"
+
+// ODataRelationship Relationship of a collection with another one, it is used to expand the
+// navigation property with the related documents using a $lookup
+type ODataRelationship struct {
+	// Name Navigation property used in the $expand and where the related documents are returned,
+	// when it is the stored name of a registered model field the clients use the model name
+	Name string
+	// From Collection with the related documents
+	From string
+	// LocalField Field of the collection documents, it can be a value or an array of values
+	LocalField string
+	// ForeignField Field of the related documents matching the local field
+	ForeignField string
+	// Single Returns the related document as an object instead of an array
+	Single bool
+	// Unscoped Joins the related documents without the tenant and soft delete scope of the
+	// repository, use it for the collections that are not shared by the tenants
+	Unscoped bool
+}
+
+type odataExpandItem struct {
+	name  string
+	query map[string]interface{}
+}
+
+var odataRelationships = make(map[string]map[string]ODataRelationship)
+var odataRelationshipsLock sync.RWMutex
+
+// RegisterODataRelationship Registers the relationships of a collection so they can be expanded
+// in the odata queries, registering a relationship with the same name replaces it
+//
+// Example:
+//		RegisterODataRelationship("orders",
+//			ODataRelationship{Name: "customer", From: "customers", LocalField: "customerId", ForeignField: "_id", Single: true},
+//			ODataRelationship{Name: "lines", From: "order_lines", LocalField: "_id", ForeignField: "orderId"})
+//		// GET /orders?$expand=customer($select=name),lines($filter=quantity gt 1;$orderby=position)
+func RegisterODataRelationship(collection string, relationships ...ODataRelationship) {
+	odataRelationshipsLock.Lock()
+	defer odataRelationshipsLock.Unlock()
+
+	if _, ok := odataRelationships[collection]; !ok {
+		odataRelationships[collection] = make(map[string]ODataRelationship)
+	}
+
+	for _, relationship := range relationships {
+		odataRelationships[collection][relationship.Name] = relationship
+	}
+}
+
+// getODataRelationship Gets a registered relationship of a collection
+func getODataRelationship(collection string, name string) (ODataRelationship, bool) {
+	odataRelationshipsLock.RLock()
+	defer odataRelationshipsLock.RUnlock()
+
+	relationship, ok := odataRelationships[collection][name]
+	return relationship, ok
+}
+
+// parseExpand Parses the $expand value, each item can have its own query options between
+// parenthesis separated by semicolons, like $select, $filter, $orderby, $top, $skip or a
+// nested $expand
+func parseExpand(value string) ([]odataExpandItem, error) {
+	result := make([]odataExpandItem, 0)
+
+	for _, expandItem := range splitODataValue(value, ',') {
+		expandItem = strings.TrimSpace(expandItem)
+		if expandItem == "" {
+			return nil, fmt.Errorf("%w: empty item in %v", ErrInvalidInput, odataExpand)
+		}
+
+		item := odataExpandItem{
+			name:  expandItem,
+			query: make(map[string]interface{}),
+		}
+
+		if index := strings.Index(expandItem, "("); index >= 0 {
+			if !strings.HasSuffix(expandItem, ")") {
+				return nil, fmt.Errorf("%w: mismatched parenthesis in %v", ErrInvalidInput, expandItem)
+			}
+
+			item.name = strings.TrimSpace(expandItem[:index])
+			options := url.Values{}
+			for _, option := range splitODataValue(expandItem[index+1:len(expandItem)-1], ';') {
+				key, optionValue, found := strings.Cut(option, "=")
+				if !found {
+					return nil, fmt.Errorf("%w: invalid option %v in %v", ErrInvalidInput, option, item.name)
+				}
+				options.Add(strings.TrimSpace(key), strings.TrimSpace(optionValue))
+			}
+
+			query, err := parseODataQuery(options)
+			if err != nil {
+				return nil, err
+			}
+			item.query = query
+		}
+
+		result = append(result, item)
+	}
+
+	return result, nil
+}
+
+// applyExpand Adds the lookup stages of the expanded navigation properties to the builder,
+// the related documents are matched with the scope of the builder unless the relationship is
+// unscoped. Returns the names of the expanded properties
+func applyExpand(builder *PipelineBuilder, collection string, items []odataExpandItem) ([]string, error) {
+	expanded := make([]string, 0)
+
+	for _, item := range items {
+		relationship, ok := getODataRelationship(collection, item.name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %v is not a navigation property of %v", ErrInvalidInput, item.name, collection)
+		}
+
+		scope := builder.scope
+		if relationship.Unscoped {
+			scope = nil
+		}
+
+		if len(item.query) == 0 && scope == nil {
+			builder.Lookup(relationship.From, relationship.LocalField, relationship.ForeignField, relationship.Name)
+		} else {
+			pipeline, err := getExpandPipeline(relationship, item, scope)
+			if err != nil {
+				return nil, err
+			}
+
+			let := bson.M{"localValue": "$" + relationship.LocalField}
+			builder.LookupPipeline(relationship.From, let, pipeline, relationship.Name)
+		}
+
+		if relationship.Single {
+			builder.UnwindPreservingEmpty("$" + relationship.Name)
+		}
+
+		expanded = append(expanded, relationship.Name)
+	}
+
+	return expanded, nil
+}
+
+// getExpandPipeline Builds the lookup pipeline of an expanded property with its query options
+// and scope, the first stage joins the related documents as the local field can be a value or
+// an array
+func getExpandPipeline(relationship ODataRelationship, item odataExpandItem, scope interface{}) (bson.A, error) {
+	builder := NewEmptyPipeline(nil).withScope(scope)
+
+	localValues := bson.M{"$cond": bson.A{bson.M{"$isArray": "$$localValue"}, "$$localValue", bson.A{"$$localValue"}}}
+	builder.Match(bson.M{"$expr": bson.M{"$in": bson.A{"$" + relationship.ForeignField, localValues}}})
+
+	if err := applyODataQuery(builder, relationship.From, item.query); err != nil {
+		return nil, err
+	}
+
+	return *builder.buildPipeline(), nil
+}
+
+// splitODataValue Splits a value by the separator ignoring the separators between parenthesis
+// or inside strings
+func splitODataValue(value string, separator rune) []string {
+	result := make([]string, 0)
+	depth := 0
+	inString := false
+	start := 0
+
+	for index, character := range value {
+		switch {
+		case character == '\'':
+			inString = !inString
+		case inString:
+			continue
+		case character == '(':
+			depth++
+		case character == ')':
+			depth--
+		case character == separator && depth == 0:
+			result = append(result, value[start:index])
+			start = index + 1
+		}
+	}
+
+	return append(result, value[start:])
+}
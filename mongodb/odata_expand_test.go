@@ -0,0 +1,125 @@
+package mongodb
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestApplyODataQuery_Expand(t *testing.T) {
+	RegisterODataRelationship("expand_orders",
+		ODataRelationship{Name: "customer", From: "expand_customers", LocalField: "customerId", ForeignField: "_id", Single: true},
+		ODataRelationship{Name: "lines", From: "expand_lines", LocalField: "_id", ForeignField: "orderId"})
+
+	localValues := bson.M{"$cond": bson.A{bson.M{"$isArray": "$$localValue"}, "$$localValue", bson.A{"$$localValue"}}}
+
+	tests := []struct {
+		name    string
+		query   url.Values
+		expect  bson.A
+		wantErr error
+	}{
+		{
+			name: "expand without options",
+			query: url.Values{
+				"$expand": {"customer"},
+				"$top":    {"10"},
+				"$select": {"number"},
+			},
+			expect: bson.A{
+				bson.D{{Key: "$limit", Value: 10}},
+				bson.D{{Key: "$lookup", Value: bson.M{"from": "expand_customers", "localField": "customerId", "foreignField": "_id", "as": "customer"}}},
+				bson.D{{Key: "$unwind", Value: bson.M{"path": "$customer", "preserveNullAndEmptyArrays": true}}},
+				bson.D{{Key: "$project", Value: bson.M{"number": 1, "customer": 1}}},
+			},
+		},
+		{
+			name: "expand with options",
+			query: url.Values{
+				"$expand": {"lines($filter=quantity gt 1;$select=sku,quantity)"},
+			},
+			expect: bson.A{
+				bson.D{{Key: "$lookup", Value: bson.M{
+					"from": "expand_lines",
+					"let":  bson.M{"localValue": "$_id"},
+					"pipeline": bson.A{
+						bson.D{{Key: "$match", Value: bson.M{"$expr": bson.M{"$in": bson.A{"$orderId", localValues}}}}},
+						bson.D{{Key: "$match", Value: bson.M{"quantity": bson.M{"$gt": 1}}}},
+						bson.D{{Key: "$project", Value: bson.M{"sku": 1, "quantity": 1}}},
+					},
+					"as": "lines",
+				}}},
+			},
+		},
+		{
+			name: "unknown navigation property",
+			query: url.Values{
+				"$expand": {"invoices"},
+			},
+			wantErr: ErrInvalidInput,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queryMap, err := parseODataQuery(tt.query)
+			if err != nil {
+				t.Fatalf("parseODataQuery() error = %v", err)
+			}
+
+			builder := NewEmptyPipeline(nil)
+			err = applyODataQuery(builder, "expand_orders", queryMap)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("applyODataQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			pipeline := *builder.buildPipeline()
+			if !reflect.DeepEqual(pipeline, tt.expect) {
+				t.Errorf("applyODataQuery() = %v, expected %v", pipeline, tt.expect)
+			}
+		})
+	}
+}
+
+func TestApplyODataQuery_ExpandScope(t *testing.T) {
+	RegisterODataRelationship("scoped_orders",
+		ODataRelationship{Name: "customer", From: "scoped_customers", LocalField: "customerId", ForeignField: "_id", Single: true},
+		ODataRelationship{Name: "country", From: "countries", LocalField: "countryCode", ForeignField: "code", Single: true, Unscoped: true})
+
+	scope := bson.M{"$and": bson.A{bson.M{"tenantId": "tenant1"}, bson.M{"deletedAt": nil}}}
+	localValues := bson.M{"$cond": bson.A{bson.M{"$isArray": "$$localValue"}, "$$localValue", bson.A{"$$localValue"}}}
+
+	queryMap, err := parseODataQuery(url.Values{"$expand": {"customer,country"}})
+	if err != nil {
+		t.Fatalf("parseODataQuery() error = %v", err)
+	}
+
+	builder := NewEmptyPipeline(nil).withScope(scope)
+	if err := applyODataQuery(builder, "scoped_orders", queryMap); err != nil {
+		t.Fatalf("applyODataQuery() error = %v", err)
+	}
+
+	expect := bson.A{
+		bson.D{{Key: "$match", Value: scope}},
+		bson.D{{Key: "$lookup", Value: bson.M{
+			"from": "scoped_customers",
+			"let":  bson.M{"localValue": "$customerId"},
+			"pipeline": bson.A{
+				bson.D{{Key: "$match", Value: bson.M{"$and": bson.A{bson.M{"$expr": bson.M{"$in": bson.A{"$_id", localValues}}}, scope}}}},
+			},
+			"as": "customer",
+		}}},
+		bson.D{{Key: "$unwind", Value: bson.M{"path": "$customer", "preserveNullAndEmptyArrays": true}}},
+		bson.D{{Key: "$lookup", Value: bson.M{"from": "countries", "localField": "countryCode", "foreignField": "code", "as": "country"}}},
+		bson.D{{Key: "$unwind", Value: bson.M{"path": "$country", "preserveNullAndEmptyArrays": true}}},
+	}
+	pipeline := *builder.buildPipeline()
+	if !reflect.DeepEqual(pipeline, expect) {
+		t.Errorf("applyODataQuery() = %v, expected %v", pipeline, expect)
+	}
+}
@@ -0,0 +1,308 @@
+package mongodb
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cjlapao/common-go/odata"
+	"github.com/cjlapao/common-go/parser"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var odataFieldNames = make(map[string]map[string]string)
+var odataFieldTypes = make(map[string]map[string]reflect.Type)
+var odataFieldNamesLock sync.RWMutex
+
+var timeType = reflect.TypeOf(time.Time{})
+var primitivePackage = reflect.TypeOf(primitive.ObjectID{}).PkgPath()
+
+// RegisterODataModel Registers the model of a collection, the json names of its fields are the
+// names used by the clients in the odata queries and are translated into the bson names they
+// are stored with. The nested structs are mapped into paths, the anonymous structs are inlined
+// as in json and the bson inline fields are inlined as in bson. The field types are used to
+// convert the filter values, like ObjectIDs, dates, decimals or uuids
+//
+// Example:
+//		type User struct {
+//			ID        string    `json:"id" bson:"_id"`
+//			CreatedAt time.Time `json:"createdAt" bson:"created_on"`
+//		}
+//		RegisterODataModel("users", User{})
+//		// GET /users?$filter=createdAt gt 2022-01-01&$orderby=createdAt desc
+func RegisterODataModel(collection string, model interface{}) {
+	fields := make(map[string]string)
+	types := make(map[string]reflect.Type)
+	modelType := reflect.TypeOf(model)
+	if modelType != nil {
+		getModelFieldNames(modelType, "", "", fields, types, make(map[reflect.Type]bool))
+	}
+
+	RegisterODataFieldNames(collection, fields)
+
+	odataFieldNamesLock.Lock()
+	defer odataFieldNamesLock.Unlock()
+
+	odataFieldTypes[collection] = types
+}
+
+// RegisterODataFieldNames Registers client field names of a collection that do not follow the
+// model, like flattened fields, the paths can use the odata / navigation. They are added to
+// the names already registered for the collection
+//
+// Example:
+//		RegisterODataFieldNames("orders", map[string]string{"user/id": "userId"})
+func RegisterODataFieldNames(collection string, fields map[string]string) {
+	odataFieldNamesLock.Lock()
+	defer odataFieldNamesLock.Unlock()
+
+	collectionFields, ok := odataFieldNames[collection]
+	if !ok {
+		collectionFields = make(map[string]string)
+		odataFieldNames[collection] = collectionFields
+	}
+
+	for name, field := range fields {
+		collectionFields[strings.ReplaceAll(name, "/", ".")] = strings.ReplaceAll(field, "/", ".")
+	}
+}
+
+// getODataFieldNames Gets the registered field names of a collection
+func getODataFieldNames(collection string) map[string]string {
+	odataFieldNamesLock.RLock()
+	defer odataFieldNamesLock.RUnlock()
+
+	return odataFieldNames[collection]
+}
+
+// getODataFieldTypes Gets the types of the registered model fields of a collection by their
+// stored path, the arrays have the type of their elements
+func getODataFieldTypes(collection string) map[string]reflect.Type {
+	odataFieldNamesLock.RLock()
+	defer odataFieldNamesLock.RUnlock()
+
+	return odataFieldTypes[collection]
+}
+
+// getModelFieldNames Adds the json paths of the struct fields and their bson paths to the
+// field names and the field types by their bson paths, the types already in the path are not
+// walked again to avoid cycles
+func getModelFieldNames(modelType reflect.Type, jsonPrefix string, bsonPrefix string, fields map[string]string, types map[string]reflect.Type, visited map[reflect.Type]bool) {
+	for modelType.Kind() == reflect.Ptr || modelType.Kind() == reflect.Slice || modelType.Kind() == reflect.Array {
+		modelType = modelType.Elem()
+	}
+	if modelType.Kind() != reflect.Struct || visited[modelType] {
+		return
+	}
+
+	visited[modelType] = true
+	defer delete(visited, modelType)
+
+	for index := 0; index < modelType.NumField(); index++ {
+		field := modelType.Field(index)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		jsonName, _ := getTagName(field.Tag.Get("json"))
+		bsonName, bsonInline := getTagName(field.Tag.Get("bson"))
+		if jsonName == "-" || bsonName == "-" {
+			continue
+		}
+
+		jsonPath := jsonPrefix
+		if !field.Anonymous || jsonName != "" || !isModelStruct(field.Type) {
+			if jsonName == "" {
+				jsonName = field.Name
+			}
+			jsonPath = joinFieldPath(jsonPrefix, jsonName)
+		}
+
+		bsonPath := bsonPrefix
+		if !bsonInline {
+			if bsonName == "" {
+				bsonName = strings.ToLower(field.Name)
+			}
+			bsonPath = joinFieldPath(bsonPrefix, bsonName)
+		}
+
+		if jsonPath != jsonPrefix {
+			fields[jsonPath] = bsonPath
+		}
+		if bsonPath != bsonPrefix {
+			types[bsonPath] = getElementType(field.Type)
+		}
+		if isModelStruct(field.Type) {
+			getModelFieldNames(field.Type, jsonPath, bsonPath, fields, types, visited)
+		}
+	}
+}
+
+// getTagName Gets the name of a struct tag and if it has the inline option
+func getTagName(tag string) (string, bool) {
+	parts := strings.Split(tag, ",")
+	for _, option := range parts[1:] {
+		if option == "inline" {
+			return parts[0], true
+		}
+	}
+
+	return parts[0], false
+}
+
+// isModelStruct Checks if the type is a struct with fields to map, or an array or pointer of
+// them, the dates and the bson primitive types are values
+func isModelStruct(fieldType reflect.Type) bool {
+	for fieldType.Kind() == reflect.Ptr || fieldType.Kind() == reflect.Slice || fieldType.Kind() == reflect.Array {
+		fieldType = fieldType.Elem()
+	}
+
+	return fieldType.Kind() == reflect.Struct && fieldType != timeType && fieldType.PkgPath() != primitivePackage
+}
+
+// getElementType Gets the type of a field without pointers, the arrays have the type of their
+// elements except the byte arrays that are stored as binaries
+func getElementType(fieldType reflect.Type) reflect.Type {
+	for fieldType.Kind() == reflect.Ptr ||
+		((fieldType.Kind() == reflect.Slice || fieldType.Kind() == reflect.Array) && fieldType.Elem().Kind() != reflect.Uint8) {
+		fieldType = fieldType.Elem()
+	}
+
+	return fieldType
+}
+
+// joinFieldPath Joins a field to its parent path
+func joinFieldPath(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+
+	return prefix + "." + name
+}
+
+// mapODataField Translates a client path into the stored path, the paths without a registered
+// name keep the name of their closest registered parent or are returned as they are
+func mapODataField(fields map[string]string, path string) string {
+	if len(fields) == 0 || path == "" {
+		return path
+	}
+
+	if field, ok := fields[path]; ok {
+		return field
+	}
+	for index := strings.LastIndex(path, "."); index > 0; index = strings.LastIndex(path[:index], ".") {
+		if field, ok := fields[path[:index]]; ok {
+			return field + path[index:]
+		}
+	}
+
+	return path
+}
+
+// mapODataRelativeField Translates a path relative to the elements of an array, the prefix is
+// the client path of the array
+func mapODataRelativeField(fields map[string]string, prefix string, path string) string {
+	if prefix == "" {
+		return mapODataField(fields, path)
+	}
+	if path == "" {
+		return path
+	}
+
+	arrayField := mapODataField(fields, prefix) + "."
+	field := mapODataField(fields, prefix+"."+path)
+	if !strings.HasPrefix(field, arrayField) {
+		return path
+	}
+
+	return strings.TrimPrefix(field, arrayField)
+}
+
+// mapODataQuery Translates the client field names of a parsed query into the stored names and
+// converts the filter values into the model types, the expanded navigation properties and their
+// queries use the names of the related collection. The $apply filters and the properties of the
+// first groupby or aggregate are translated as they read the collection documents, the
+// transformations after it use the names of the aggregated rows
+func mapODataQuery(collection string, queryMap map[string]interface{}) {
+	fields := getODataFieldNames(collection)
+	types := getODataFieldTypes(collection)
+
+	if filterQuery, ok := queryMap[odata.Filter].(*parser.ParseNode); ok {
+		mapFilterFields(filterQuery, fields, "")
+		coerceFilterValues(filterQuery, types, "")
+	}
+
+	if transformations, ok := queryMap[odataApply].([]odataApplyTransformation); ok {
+		documentFilters, _ := getApplyFilters(transformations)
+		for _, filter := range documentFilters {
+			mapFilterFields(filter, fields, "")
+			coerceFilterValues(filter, types, "")
+		}
+		mapApplyGroup(transformations, fields)
+	}
+
+	if orderBySlice, ok := queryMap[odata.OrderBy].([]odata.OrderItem); ok {
+		mapped := make([]odata.OrderItem, 0)
+		for _, item := range orderBySlice {
+			field := mapODataField(fields, strings.ReplaceAll(strings.TrimSpace(item.Field), "/", "."))
+			mapped = append(mapped, odata.OrderItem{Field: field, Order: item.Order})
+		}
+		queryMap[odata.OrderBy] = mapped
+	}
+
+	if selectSlice, ok := queryMap[odata.Select].([]string); ok {
+		mapped := make([]string, 0)
+		for _, field := range selectSlice {
+			mapped = append(mapped, mapODataField(fields, strings.ReplaceAll(strings.TrimSpace(field), "/", ".")))
+		}
+		queryMap[odata.Select] = mapped
+	}
+
+	if expandItems, ok := queryMap[odataExpand].([]odataExpandItem); ok {
+		for index, item := range expandItems {
+			// the relationships can be registered with the stored name of the navigation property
+			name := mapODataField(fields, strings.ReplaceAll(item.name, "/", "."))
+			if _, ok := getODataRelationship(collection, name); ok {
+				expandItems[index].name = name
+			}
+
+			if relationship, ok := getODataRelationship(collection, expandItems[index].name); ok {
+				mapODataQuery(relationship.From, item.query)
+			}
+		}
+	}
+}
+
+// mapApplyGroup Builds the stages of the first groupby or aggregate of the $apply with the
+// stored paths of its properties, the grouped properties are still returned in their client paths
+func mapApplyGroup(transformations []odataApplyTransformation, fields map[string]string) {
+	if len(fields) == 0 {
+		return
+	}
+
+	for index, transformation := range transformations {
+		if transformation.filter != nil {
+			continue
+		}
+
+		// the transformation was already validated when parsed with the same properties
+		if stages, err := getApplyGroupStages(transformation.properties, transformation.aggregates, fields); err == nil {
+			transformations[index].stages = stages
+		}
+		return
+	}
+}
+
+// mapFilterFields Translates the field paths of a filter tree, the paths inside a lambda are
+// relative to the array so the prefix is the client path of the array
+func mapFilterFields(node *parser.ParseNode, fields map[string]string, prefix string) {
+	if len(fields) == 0 {
+		return
+	}
+
+	_ = visitFilterFields(node, prefix, func(field *parser.ParseNode, prefix string) error {
+		field.Token.Value = mapODataRelativeField(fields, prefix, field.Token.Value.(string))
+		return nil
+	})
+}
@@ -0,0 +1,149 @@
+package mongodb
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fieldsTestAudit struct {
+	CreatedAt time.Time `json:"createdAt" bson:"created_on"`
+}
+
+type fieldsTestLine struct {
+	Sku      string `json:"sku" bson:"code"`
+	Quantity int    `json:"quantity" bson:"qty"`
+}
+
+type fieldsTestOrder struct {
+	fieldsTestAudit
+	ID       string             `json:"id" bson:"_id"`
+	UserID   primitive.ObjectID `json:"userId" bson:"user_id"`
+	Code     string             `json:"code"`
+	Total    int64              `json:"total"`
+	Status   string             `json:"status"`
+	Lines    []fieldsTestLine   `json:"lines" bson:"items"`
+	Internal string             `json:"-" bson:"internal"`
+	Address  struct {
+		City string `json:"city" bson:"town"`
+	} `json:"address" bson:",inline"`
+}
+
+func TestApplyODataQuery_FieldNames(t *testing.T) {
+	RegisterODataModel("fields_orders", fieldsTestOrder{})
+	RegisterODataFieldNames("fields_orders", map[string]string{"user/id": "userId"})
+	RegisterODataModel("fields_lines", fieldsTestLine{})
+	RegisterODataRelationship("fields_orders",
+		ODataRelationship{Name: "items", From: "fields_lines", LocalField: "_id", ForeignField: "orderId"})
+
+	localValues := bson.M{"$cond": bson.A{bson.M{"$isArray": "$$localValue"}, "$$localValue", bson.A{"$$localValue"}}}
+
+	tests := []struct {
+		name   string
+		query  url.Values
+		expect bson.A
+	}{
+		{
+			name: "filter with nested and inlined fields",
+			query: url.Values{
+				"$filter": {"id eq 'A1' and user/id eq 'U1' and address/city eq 'Lisbon' and createdAt gt 2022-01-01"},
+			},
+			expect: bson.A{
+				bson.D{{Key: "$match", Value: bson.M{"$and": []bson.M{
+					{"$and": []bson.M{
+						{"$and": []bson.M{
+							{"_id": bson.M{"$eq": "A1"}},
+							{"userId": bson.M{"$eq": "U1"}},
+						}},
+						{"town": bson.M{"$eq": "Lisbon"}},
+					}},
+					{"fieldstestaudit.created_on": bson.M{"$gt": time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)}},
+				}}}},
+			},
+		},
+		{
+			name: "values with the model types",
+			query: url.Values{
+				"$filter": {"userId eq '5f1b9c8e8e4b2a3d4c5e6f70' and code eq 2022-01-01 and total gt 10"},
+			},
+			expect: bson.A{
+				bson.D{{Key: "$match", Value: bson.M{"$and": []bson.M{
+					{"$and": []bson.M{
+						{"user_id": bson.M{"$eq": primitive.ObjectID{0x5f, 0x1b, 0x9c, 0x8e, 0x8e, 0x4b, 0x2a, 0x3d, 0x4c, 0x5e, 0x6f, 0x70}}},
+						{"code": bson.M{"$eq": "2022-01-01"}},
+					}},
+					{"total": bson.M{"$gt": int64(10)}},
+				}}}},
+			},
+		},
+		{
+			name: "literal values are not translated",
+			query: url.Values{
+				"$filter": {"status eq id"},
+			},
+			expect: bson.A{
+				bson.D{{Key: "$match", Value: bson.M{"status": bson.M{"$eq": "id"}}}},
+			},
+		},
+		{
+			name: "lambda fields are relative to the array",
+			query: url.Values{
+				"$filter": {"lines/any(l: l/sku eq 'S1')"},
+			},
+			expect: bson.A{
+				bson.D{{Key: "$match", Value: bson.M{"items": bson.M{"$elemMatch": bson.M{"code": bson.M{"$eq": "S1"}}}}}},
+			},
+		},
+		{
+			name: "orderby and select",
+			query: url.Values{
+				"$orderby": {"lines/quantity desc,status"},
+				"$select":  {"id,lines/sku,unknown"},
+			},
+			expect: bson.A{
+				bson.D{{Key: "$sort", Value: bson.D{{Key: "items.qty", Value: -1}, {Key: "status", Value: 1}}}},
+				bson.D{{Key: "$project", Value: bson.M{"_id": 1, "items.code": 1, "unknown": 1}}},
+			},
+		},
+		{
+			name: "expand of a stored navigation property",
+			query: url.Values{
+				"$expand": {"lines($filter=sku eq 'S1')"},
+			},
+			expect: bson.A{
+				bson.D{{Key: "$lookup", Value: bson.M{
+					"from": "fields_lines",
+					"let":  bson.M{"localValue": "$_id"},
+					"pipeline": bson.A{
+						bson.D{{Key: "$match", Value: bson.M{"$expr": bson.M{"$in": bson.A{"$orderId", localValues}}}}},
+						bson.D{{Key: "$match", Value: bson.M{"code": bson.M{"$eq": "S1"}}}},
+					},
+					"as": "items",
+				}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queryMap, err := parseODataQuery(tt.query)
+			if err != nil {
+				t.Fatalf("parseODataQuery() error = %v", err)
+			}
+			mapODataQuery("fields_orders", queryMap)
+
+			builder := NewEmptyPipeline(nil)
+			if err := applyODataQuery(builder, "fields_orders", queryMap); err != nil {
+				t.Fatalf("applyODataQuery() error = %v", err)
+			}
+
+			pipeline := *builder.buildPipeline()
+			if !reflect.DeepEqual(pipeline, tt.expect) {
+				t.Errorf("applyODataQuery() = %v, expected %v", pipeline, tt.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package mongodb
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/cjlapao/common-go/models"
+	"github.com/cjlapao/common-go/odata"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// odataSkipToken OData keyword for the server driven paging token, the common odata parser does
+// not define it
+const odataSkipToken = "$skiptoken"
+
+// ODataPagedResponse OData response of a server driven paged query, the next link is the
+// request url with the skip token of the next page and is empty on the last page
+type ODataPagedResponse struct {
+	models.ODataResponse
+	NextLink  string `json:"@odata.nextLink,omitempty"`
+	SkipToken string `json:"-"`
+}
+
+// WithPageSize Sets the maximum number of documents returned by the queries of the parser, the
+// $top is limited to the page size and used when the query has none. The GetODataPagedResponse
+// returns the following pages with the next link. The $apply queries are not limited as they
+// cannot be paged, if the size is lower or equal than 0 the paging is disabled
+func (odataParser *ODataParser) WithPageSize(pageSize int) *ODataParser {
+	odataParser.pageSize = pageSize
+	return odataParser
+}
+
+// applyPageSize Limits the $top of a parsed query to the page size, the queries without $top
+// return a page
+func (odataParser *ODataParser) applyPageSize(queryMap map[string]interface{}) {
+	if odataParser.pageSize <= 0 {
+		return
+	}
+	if _, isAggregation := queryMap[odataApply]; isAggregation {
+		return
+	}
+
+	if top, ok := queryMap[odata.Top].(int); !ok || top > odataParser.pageSize {
+		queryMap[odata.Top] = odataParser.pageSize
+	}
+}
+
+// GetODataPagedResponse Creates a odata response from the request url returning at most a page
+// of documents and the next link to the following page. The pages use the $orderby fields, or
+// the $search relevance, plus the _id as a keyset, the skip token holds the keys of the last
+// document so the pages stay stable when documents are inserted or deleted between requests.
+// The $select is extended with the keyset fields and queries with $apply are not paged
+//
+// Example:
+//		response, err := collection.OData().WithPageSize(50).GetODataPagedResponse(request.URL)
+//		// {"value": [...], "@odata.nextLink": "https://host/users?$orderby=name&$skiptoken=..."}
+func (odataParser *ODataParser) GetODataPagedResponse(requestURL *url.URL) (*ODataPagedResponse, error) {
+	query := requestURL.Query()
+	response := ODataPagedResponse{}
+
+	queryMap, err := odataParser.parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Checks if the count flag is true and starts counting the matching records
+	var waitCount func() (int, error)
+	if count, ok := queryMap[odata.Count].(bool); ok && count {
+		waitCount = odataParser.startCount(queryMap)
+	}
+
+	_, isAggregation := queryMap[odataApply]
+	if odataParser.pageSize <= 0 || isAggregation {
+		var element []map[string]interface{}
+		cursor, err := odataParser.aggregate(queryMap)
+		if err != nil {
+			return nil, err
+		}
+		if err := cursor.cursor.All(odataParser.context, &element); err != nil {
+			return nil, err
+		}
+
+		response.Value = element
+		return odataParser.setPagedResponseCount(&response, waitCount)
+	}
+
+	sortFields := getPagingSortFields(queryMap)
+	if skipToken, ok := queryMap[odataSkipToken].(string); ok {
+		values, err := decodeSkipToken(skipToken, len(sortFields))
+		if err != nil {
+			return nil, err
+		}
+
+		// the skip was already applied to the first page
+		delete(queryMap, odata.Skip)
+		queryMap[odataSkipToken] = getKeysetFilter(sortFields, values)
+	}
+
+	// one more document than the page size is requested to know if there is a next page, the
+	// $top of the query was already limited to the page size so the requested one is used
+	top, err := strconv.Atoi(strings.TrimSpace(query.Get(odata.Top)))
+	hasTop := err == nil
+	if !hasTop || top > odataParser.pageSize {
+		queryMap[odata.Top] = odataParser.pageSize + 1
+	}
+
+	cursor, err := odataParser.aggregate(queryMap)
+	if err != nil {
+		return nil, err
+	}
+
+	var documents []bson.Raw
+	if err := cursor.cursor.All(odataParser.context, &documents); err != nil {
+		return nil, err
+	}
+
+	if len(documents) > odataParser.pageSize {
+		documents = documents[:odataParser.pageSize]
+
+		response.SkipToken, err = encodeSkipToken(documents[len(documents)-1], sortFields)
+		if err != nil {
+			return nil, err
+		}
+
+		remaining := 0
+		if hasTop {
+			remaining = top - odataParser.pageSize
+		}
+		response.NextLink = getNextLink(requestURL, response.SkipToken, remaining)
+	}
+
+	element := make([]map[string]interface{}, 0)
+	for _, document := range documents {
+		var value map[string]interface{}
+		if err := bson.Unmarshal(document, &value); err != nil {
+			return nil, err
+		}
+		element = append(element, value)
+	}
+
+	response.Value = element
+	return odataParser.setPagedResponseCount(&response, waitCount)
+}
+
+// setPagedResponseCount Waits for the count of the response if it was requested
+func (odataParser *ODataParser) setPagedResponseCount(response *ODataPagedResponse, waitCount func() (int, error)) (*ODataPagedResponse, error) {
+	if waitCount != nil {
+		count, err := waitCount()
+		if err != nil {
+			return nil, err
+		}
+		response.Count = count
+	}
+
+	return response, nil
+}
+
+// getPagingSortFields Gets the keyset of the paging, the $orderby fields followed by the _id,
+// the query sort and select are updated to include them. Without $orderby the $search results
+// are paged by relevance, the score is null in every document when the regex search is used
+func getPagingSortFields(queryMap map[string]interface{}) []odata.OrderItem {
+	sortFields, hasOrderBy := queryMap[odata.OrderBy].([]odata.OrderItem)
+	if _, isSearch := queryMap[odataSearch]; isSearch && !hasOrderBy {
+		sortFields = []odata.OrderItem{{Field: ODataSearchScoreField, Order: odata.Descendent}}
+	}
+
+	hasId := false
+	for _, item := range sortFields {
+		hasId = hasId || item.Field == "_id"
+	}
+	if !hasId {
+		sortFields = append(sortFields, odata.OrderItem{Field: "_id", Order: odata.Ascendent})
+	}
+	queryMap[odata.OrderBy] = sortFields
+
+	if selectSlice, ok := queryMap[odata.Select].([]string); ok {
+		for _, item := range sortFields {
+			selected := false
+			for _, field := range selectSlice {
+				selected = selected || strings.TrimSpace(field) == item.Field
+			}
+			if !selected {
+				selectSlice = append(selectSlice, item.Field)
+			}
+		}
+		queryMap[odata.Select] = selectSlice
+	}
+
+	return sortFields
+}
+
+// getKeysetFilter Creates the filter matching the documents after the keys in the sort order,
+// for the keys a, b it matches a after the value or a equal and b after the value
+func getKeysetFilter(sortFields []odata.OrderItem, values []interface{}) bson.M {
+	conditions := make([]bson.M, 0)
+
+	for index, item := range sortFields {
+		after, ok := getKeyAfterFilter(item, values[index])
+		if !ok {
+			continue
+		}
+
+		condition := make([]bson.M, 0)
+		for previous := 0; previous < index; previous++ {
+			condition = append(condition, bson.M{sortFields[previous].Field: bson.M{"$eq": values[previous]}})
+		}
+		condition = append(condition, after)
+
+		conditions = append(conditions, bson.M{"$and": condition})
+	}
+
+	// there are no documents after the keys
+	if len(conditions) == 0 {
+		return bson.M{"$expr": false}
+	}
+
+	return bson.M{"$or": conditions}
+}
+
+// getKeyAfterFilter Creates the condition matching the values after a key, the null and missing
+// values sort before any other value and the comparisons with null only match null, so nothing
+// is after a null key in a descending order
+func getKeyAfterFilter(item odata.OrderItem, value interface{}) (bson.M, bool) {
+	switch {
+	case value == nil && item.Order == odata.Descendent:
+		return nil, false
+	case value == nil:
+		return bson.M{item.Field: bson.M{"$ne": nil}}, true
+	case item.Order == odata.Descendent:
+		return bson.M{"$or": []bson.M{{item.Field: bson.M{"$lt": value}}, {item.Field: nil}}}, true
+	}
+
+	return bson.M{item.Field: bson.M{"$gt": value}}, true
+}
+
+// encodeSkipToken Encodes the keys of the document into a url safe token, the keys are stored
+// as bson so they keep their types
+func encodeSkipToken(document bson.Raw, sortFields []odata.OrderItem) (string, error) {
+	values := bson.A{}
+	for _, item := range sortFields {
+		value, err := document.LookupErr(strings.Split(item.Field, ".")...)
+		if err != nil {
+			values = append(values, nil)
+			continue
+		}
+		values = append(values, value)
+	}
+
+	data, err := bson.Marshal(bson.M{"v": values})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeSkipToken Decodes the keys of a skip token, they need to match the number of sort fields
+func decodeSkipToken(skipToken string, length int) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(skipToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid %v", ErrInvalidInput, odataSkipToken)
+	}
+
+	var token struct {
+		Values []interface{} `bson:"v"`
+	}
+	if err := bson.Unmarshal(data, &token); err != nil || len(token.Values) != length {
+		return nil, fmt.Errorf("%w: invalid %v", ErrInvalidInput, odataSkipToken)
+	}
+
+	return token.Values, nil
+}
+
+// getNextLink Creates the next page link replacing the skip token, the $skip is removed as it
+// was applied to the first page and the $top is reduced to the remaining documents
+func getNextLink(requestURL *url.URL, skipToken string, remaining int) string {
+	nextURL := *requestURL
+	query := requestURL.Query()
+
+	query.Del(odata.Skip)
+	query.Set(odataSkipToken, skipToken)
+	if remaining > 0 {
+		query.Set(odata.Top, strconv.Itoa(remaining))
+	}
+
+	nextURL.RawQuery = query.Encode()
+	return nextURL.String()
+}
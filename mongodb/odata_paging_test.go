@@ -0,0 +1,202 @@
+package mongodb
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/cjlapao/common-go/odata"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestGetPagingSortFields(t *testing.T) {
+	tests := []struct {
+		name         string
+		queryMap     map[string]interface{}
+		expect       []odata.OrderItem
+		expectSelect []string
+	}{
+		{
+			name:     "without orderby",
+			queryMap: map[string]interface{}{},
+			expect:   []odata.OrderItem{{Field: "_id", Order: odata.Ascendent}},
+		},
+		{
+			name: "orderby and select",
+			queryMap: map[string]interface{}{
+				odata.OrderBy: []odata.OrderItem{{Field: "name", Order: odata.Descendent}},
+				odata.Select:  []string{"name", "status"},
+			},
+			expect:       []odata.OrderItem{{Field: "name", Order: odata.Descendent}, {Field: "_id", Order: odata.Ascendent}},
+			expectSelect: []string{"name", "status", "_id"},
+		},
+		{
+			name: "orderby with the id",
+			queryMap: map[string]interface{}{
+				odata.OrderBy: []odata.OrderItem{{Field: "_id", Order: odata.Descendent}},
+			},
+			expect: []odata.OrderItem{{Field: "_id", Order: odata.Descendent}},
+		},
+		{
+			name: "search is paged by relevance",
+			queryMap: map[string]interface{}{
+				odataSearch:  []odataSearchTerm{{value: "shoes"}},
+				odata.Select: []string{"name"},
+			},
+			expect:       []odata.OrderItem{{Field: ODataSearchScoreField, Order: odata.Descendent}, {Field: "_id", Order: odata.Ascendent}},
+			expectSelect: []string{"name", ODataSearchScoreField, "_id"},
+		},
+		{
+			name: "search with orderby",
+			queryMap: map[string]interface{}{
+				odataSearch:   []odataSearchTerm{{value: "shoes"}},
+				odata.OrderBy: []odata.OrderItem{{Field: "name", Order: odata.Ascendent}},
+			},
+			expect: []odata.OrderItem{{Field: "name", Order: odata.Ascendent}, {Field: "_id", Order: odata.Ascendent}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := getPagingSortFields(tt.queryMap)
+			if !reflect.DeepEqual(result, tt.expect) {
+				t.Errorf("getPagingSortFields() = %v, expected %v", result, tt.expect)
+			}
+			if !reflect.DeepEqual(tt.queryMap[odata.OrderBy], tt.expect) {
+				t.Errorf("getPagingSortFields() orderby = %v, expected %v", tt.queryMap[odata.OrderBy], tt.expect)
+			}
+			if tt.expectSelect != nil && !reflect.DeepEqual(tt.queryMap[odata.Select], tt.expectSelect) {
+				t.Errorf("getPagingSortFields() select = %v, expected %v", tt.queryMap[odata.Select], tt.expectSelect)
+			}
+		})
+	}
+}
+
+func TestGetKeysetFilter(t *testing.T) {
+	ascending := []odata.OrderItem{{Field: "name", Order: odata.Ascendent}, {Field: "_id", Order: odata.Ascendent}}
+	descending := []odata.OrderItem{{Field: "name", Order: odata.Descendent}, {Field: "_id", Order: odata.Ascendent}}
+
+	tests := []struct {
+		name       string
+		sortFields []odata.OrderItem
+		values     []interface{}
+		expect     bson.M
+	}{
+		{
+			name:       "ascending",
+			sortFields: ascending,
+			values:     []interface{}{"john", "A1"},
+			expect: bson.M{"$or": []bson.M{
+				{"$and": []bson.M{{"name": bson.M{"$gt": "john"}}}},
+				{"$and": []bson.M{{"name": bson.M{"$eq": "john"}}, {"_id": bson.M{"$gt": "A1"}}}},
+			}},
+		},
+		{
+			name:       "ascending after null",
+			sortFields: ascending,
+			values:     []interface{}{nil, "A1"},
+			expect: bson.M{"$or": []bson.M{
+				{"$and": []bson.M{{"name": bson.M{"$ne": nil}}}},
+				{"$and": []bson.M{{"name": bson.M{"$eq": nil}}, {"_id": bson.M{"$gt": "A1"}}}},
+			}},
+		},
+		{
+			name:       "descending includes the nulls",
+			sortFields: descending,
+			values:     []interface{}{"john", "A1"},
+			expect: bson.M{"$or": []bson.M{
+				{"$and": []bson.M{{"$or": []bson.M{{"name": bson.M{"$lt": "john"}}, {"name": nil}}}}},
+				{"$and": []bson.M{{"name": bson.M{"$eq": "john"}}, {"_id": bson.M{"$gt": "A1"}}}},
+			}},
+		},
+		{
+			name:       "descending after null",
+			sortFields: descending,
+			values:     []interface{}{nil, "A1"},
+			expect: bson.M{"$or": []bson.M{
+				{"$and": []bson.M{{"name": bson.M{"$eq": nil}}, {"_id": bson.M{"$gt": "A1"}}}},
+			}},
+		},
+		{
+			name:       "nothing after the keys",
+			sortFields: []odata.OrderItem{{Field: "name", Order: odata.Descendent}},
+			values:     []interface{}{nil},
+			expect:     bson.M{"$expr": false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := getKeysetFilter(tt.sortFields, tt.values)
+			if !reflect.DeepEqual(result, tt.expect) {
+				t.Errorf("getKeysetFilter() = %v, expected %v", result, tt.expect)
+			}
+		})
+	}
+}
+
+func TestSkipToken(t *testing.T) {
+	sortFields := []odata.OrderItem{
+		{Field: ODataSearchScoreField, Order: odata.Descendent},
+		{Field: "address.city", Order: odata.Ascendent},
+		{Field: "name", Order: odata.Ascendent},
+		{Field: "_id", Order: odata.Ascendent},
+	}
+
+	document, err := bson.Marshal(bson.D{
+		{Key: "_id", Value: "A1"},
+		{Key: "name", Value: nil},
+		{Key: ODataSearchScoreField, Value: 1.5},
+	})
+	if err != nil {
+		t.Fatalf("bson.Marshal() error = %v", err)
+	}
+
+	skipToken, err := encodeSkipToken(document, sortFields)
+	if err != nil {
+		t.Fatalf("encodeSkipToken() error = %v", err)
+	}
+
+	values, err := decodeSkipToken(skipToken, len(sortFields))
+	if err != nil {
+		t.Fatalf("decodeSkipToken() error = %v", err)
+	}
+	expect := []interface{}{1.5, nil, nil, "A1"}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("decodeSkipToken() = %v, expected %v", values, expect)
+	}
+
+	if _, err := decodeSkipToken(skipToken, 2); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("decodeSkipToken() error = %v, wantErr %v", err, ErrInvalidInput)
+	}
+	if _, err := decodeSkipToken("not a token!", len(sortFields)); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("decodeSkipToken() error = %v, wantErr %v", err, ErrInvalidInput)
+	}
+}
+
+func TestODataParser_ParseQueryPageSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		pageSize int
+		query    url.Values
+		expect   interface{}
+	}{
+		{name: "without top", pageSize: 50, query: url.Values{}, expect: 50},
+		{name: "top over the page size", pageSize: 50, query: url.Values{"$top": {"200"}}, expect: 50},
+		{name: "top within the page size", pageSize: 50, query: url.Values{"$top": {"10"}}, expect: 10},
+		{name: "without page size", pageSize: 0, query: url.Values{"$top": {"200"}}, expect: 200},
+		{name: "aggregation", pageSize: 50, query: url.Values{"$apply": {"groupby((status))"}}, expect: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			odataParser := EmptyODataParser(&mongoCollection{name: "paged_users"}).WithPageSize(tt.pageSize)
+
+			queryMap, err := odataParser.parseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("parseQuery() error = %v", err)
+			}
+			if top := queryMap[odata.Top]; top != tt.expect {
+				t.Errorf("parseQuery() $top = %v, expected %v", top, tt.expect)
+			}
+		})
+	}
+}
@@ -4,8 +4,10 @@ package mongodb
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/url"
 	"reflect"
+	"strings"
 
 	"github.com/cjlapao/common-go/models"
 	"github.com/cjlapao/common-go/odata"
@@ -15,7 +17,12 @@ import (
 
 // ODataParser Structure element
 type ODataParser struct {
-	Collection *mongoCollection
+	context         context.Context
+	policy          *ODataPolicy
+	pageSize        int
+	concurrentCount bool
+	scope           interface{}
+	Collection      *mongoCollection
 }
 
 // ErrInvalidInput OData syntax error definition
@@ -27,41 +34,64 @@ var ErrInvalidDestination = errors.New("destination must be a pointer type")
 // EmptyODataParser Creates an empty odata parser for a specific collection
 func EmptyODataParser(collection *mongoCollection) *ODataParser {
 	result := ODataParser{
+		context:    context.Background(),
 		Collection: collection,
 	}
 
 	return &result
 }
 
-// TODO: Implement inner count
-// GetODataResponse Creates a odata response from an odata url query including count
+// WithContext Sets the context used to run the odata queries, use the session context to run
+// the queries inside a transaction
+func (odataParser *ODataParser) WithContext(ctx context.Context) *ODataParser {
+	if ctx != nil {
+		odataParser.context = ctx
+	}
+
+	return odataParser
+}
+
+// withScope Sets the scope filter of the repository, the queries and counts only return the
+// documents in the scope
+func (odataParser *ODataParser) withScope(scope interface{}) *ODataParser {
+	odataParser.scope = scope
+	return odataParser
+}
+
+// GetODataResponse Creates a odata response from an odata url query including count, the count
+// is the number of records matching the query before $skip and $top are applied
 func (odataParser *ODataParser) GetODataResponse(query url.Values) (*models.ODataResponse, error) {
-	// ctx := context.Background()
 	response := models.ODataResponse{}
 
-	queryMap, err := odata.ParseURLValues(query)
+	queryMap, err := odataParser.parseQuery(query)
 	if err != nil {
 		return nil, err
 	}
 
-	// Checks if the count flag is true and count the collection records
-	if count, ok := queryMap[odata.Count].(bool); ok {
-		if count {
-			builder := NewEmptyPipeline(odataParser.Collection)
-			countField := builder.CountCollection()
-			response.Count = countField
-		}
+	// Checks if the count flag is true and starts counting the matching records
+	var waitCount func() (int, error)
+	if count, ok := queryMap[odata.Count].(bool); ok && count {
+		waitCount = odataParser.startCount(queryMap)
 	}
 
 	// execute and decode the odata query
 	var element []map[string]interface{}
-	err = odataParser.Decode(query, &element)
+	cursor, err := odataParser.aggregate(queryMap)
 	if err != nil {
 		return nil, err
 	}
+	if err := cursor.cursor.All(odataParser.context, &element); err != nil {
+		return nil, err
+	}
 
 	response.Value = element
 
+	if waitCount != nil {
+		if response.Count, err = waitCount(); err != nil {
+			return nil, err
+		}
+	}
+
 	return &response, nil
 }
 
@@ -74,7 +104,7 @@ func (odataParser *ODataParser) Decode(query url.Values, destination interface{}
 		return ErrInvalidDestination
 	}
 
-	ctx := context.Background()
+	ctx := odataParser.context
 
 	cursor, err := odataParser.Query(query)
 	if err != nil {
@@ -92,69 +122,278 @@ func (odataParser *ODataParser) Decode(query url.Values, destination interface{}
 // Query creates a mongo query based on odata parameters
 // returns a cursor ready to be iterated or an error if something goes wrong
 func (odataParser *ODataParser) Query(query url.Values) (*mongoCursor, error) {
-	builder := NewEmptyPipeline(odataParser.Collection)
-
 	// Parse url values
-	queryMap, err := odata.ParseURLValues(query)
+	queryMap, err := odataParser.parseQuery(query)
 	if err != nil {
 		return nil, err
 	}
 
-	// Prepares the limit pipeline
-	if limit, ok := queryMap[odata.Top].(int); ok {
-		builder.Limit(limit)
+	return odataParser.aggregate(queryMap)
+}
+
+// aggregate Runs the aggregation of a parsed odata query
+func (odataParser *ODataParser) aggregate(queryMap map[string]interface{}) (*mongoCursor, error) {
+	builder := NewEmptyPipeline(odataParser.Collection).WithContext(odataParser.context).withScope(odataParser.scope)
+
+	if err := applyODataQuery(builder, odataParser.Collection.name, queryMap); err != nil {
+		return nil, err
 	}
 
-	// Prepares the skip pipeline
-	if skip, ok := queryMap[odata.Skip].(int); ok {
-		builder.Skip(skip)
+	cursor, err := builder.Aggregate()
+
+	return cursor, err
+}
+
+// parseQuery Parses the odata url values, applies the parser policy and page size and translates
+// the client field names into the stored names, the policy uses the client names
+func (odataParser *ODataParser) parseQuery(query url.Values) (map[string]interface{}, error) {
+	queryMap, err := parseODataQuery(query)
+	if err != nil {
+		return nil, err
 	}
 
-	// Prepares the filter object and build the match pipeline
-	filterObj := make(bson.M)
-	if queryMap[odata.Filter] != nil {
-		filterQuery, _ := queryMap[odata.Filter].(*parser.ParseNode)
-		var err error
-		filterObj, err = ApplyFilter(filterQuery)
+	if odataParser.policy != nil {
+		if err := odataParser.policy.apply(queryMap); err != nil {
+			return nil, err
+		}
+	}
+
+	odataParser.applyPageSize(queryMap)
+	mapODataQuery(odataParser.Collection.name, queryMap)
+
+	return queryMap, nil
+}
+
+// odataExtendedKeywords Keywords parsed by the ODataParser instead of the common odata parser
+var odataExtendedKeywords = map[string]bool{
+	odata.Count:    true,
+	odata.Filter:   true,
+	odataExpand:    true,
+	odataSearch:    true,
+	odataApply:     true,
+	odataSkipToken: true,
+}
+
+// parseODataQuery Parses the odata url values, the $filter is parsed with the FilterParser as
+// it supports more operators and functions than the common odata parser, the $expand is
+// parsed with its nested query options, the $search into its terms and the $apply into the
+// aggregation stages
+func parseODataQuery(query url.Values) (map[string]interface{}, error) {
+	commonQuery := url.Values{}
+	for key, values := range query {
+		if !odataExtendedKeywords[key] {
+			commonQuery[key] = values
+		}
+	}
+
+	queryMap, err := odata.ParseURLValues(commonQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	// the common parser takes any $count value as true
+	if values, ok := query[odata.Count]; ok {
+		if _, isInlineCount := query[odata.InlineCount]; isInlineCount {
+			return nil, fmt.Errorf("%w: $count and $inlinecount cannot be set in the same odata query", ErrInvalidInput)
+		}
+		if len(values) > 1 {
+			return nil, fmt.Errorf("%w: duplicate keyword '%v' found in odata query", ErrInvalidInput, odata.Count)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(values[0])) {
+		case "", "true":
+			queryMap[odata.Count] = true
+		case "false":
+			queryMap[odata.Count] = false
+		default:
+			return nil, fmt.Errorf("%w: $count needs to be true or false", ErrInvalidInput)
+		}
+	}
+	if inlineCount, ok := queryMap[odata.InlineCount].(string); ok && strings.TrimSpace(inlineCount) == "allpages" {
+		queryMap[odata.Count] = true
+	}
+
+	if filter, ok, err := getODataQueryValue(query, odata.Filter); ok {
 		if err != nil {
-			return nil, ErrInvalidInput
+			return nil, err
 		}
 
-		// Creates the match pipeline for the filter
-		builder.Match(filterObj)
+		tree, err := NewFilterParser(filter).parseFilterString(filter)
+		if err != nil {
+			return nil, err
+		}
+		queryMap[odata.Filter] = tree
 	}
 
-	// Prepare Select object and build the project pipeline
-	selectMap := make(bson.M)
-	if queryMap["$select"] != nil {
-		if selectSlice, ok := queryMap["$select"].([]string); ok {
-			for i := 0; i < len(selectSlice); i++ {
-				fieldName := selectSlice[i]
-				selectMap[fieldName] = 1
+	if expand, ok, err := getODataQueryValue(query, odataExpand); ok {
+		if err != nil {
+			return nil, err
+		}
+
+		expandItems, err := parseExpand(expand)
+		if err != nil {
+			return nil, err
+		}
+		queryMap[odataExpand] = expandItems
+	}
+
+	if search, ok, err := getODataQueryValue(query, odataSearch); ok {
+		if err != nil {
+			return nil, err
+		}
+
+		searchTerms, err := parseSearch(search)
+		if err != nil {
+			return nil, err
+		}
+		queryMap[odataSearch] = searchTerms
+	}
+
+	if apply, ok, err := getODataQueryValue(query, odataApply); ok {
+		if err != nil {
+			return nil, err
+		}
+
+		transformations, err := parseApply(apply)
+		if err != nil {
+			return nil, err
+		}
+		queryMap[odataApply] = transformations
+	}
+
+	if skipToken, ok, err := getODataQueryValue(query, odataSkipToken); ok {
+		if err != nil {
+			return nil, err
+		}
+		queryMap[odataSkipToken] = skipToken
+	}
+
+	return queryMap, nil
+}
+
+// getODataQueryValue Gets the value of a keyword, returns an error if it is duplicated or empty
+func getODataQueryValue(query url.Values, key string) (string, bool, error) {
+	values, ok := query[key]
+	if !ok {
+		return "", false, nil
+	}
+
+	if len(values) > 1 {
+		return "", true, fmt.Errorf("%w: duplicate keyword '%v' found in odata query", ErrInvalidInput, key)
+	}
+	if values[0] == "" {
+		return "", true, fmt.Errorf("%w: no value was set for keyword '%v'", ErrInvalidInput, key)
+	}
+
+	return values[0], true, nil
+}
+
+// applyODataQuery Adds the stages of a parsed odata query to the builder in the order they
+// need to run, search, the aggregation transformations, match, sort, skip, limit, the expanded
+// lookups and finally the projection
+func applyODataQuery(builder *PipelineBuilder, collection string, queryMap map[string]interface{}) error {
+	// Prepares the search, the text search match needs to be the first stage of the pipeline
+	textSearch := false
+	if searchTerms, ok := queryMap[odataSearch].([]odataSearchTerm); ok {
+		var err error
+		textSearch, err = applySearch(builder, collection, searchTerms)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Prepares the aggregation stages, the rest of the query is applied to the aggregated rows
+	if transformations, ok := queryMap[odataApply].([]odataApplyTransformation); ok {
+		for _, transformation := range transformations {
+			if transformation.filter != nil {
+				filterObj, err := ApplyFilter(transformation.filter)
+				if err != nil {
+					return err
+				}
+				builder.Add(bson.D{{Key: "$match", Value: filterObj}})
+				continue
 			}
 
-			// Creates the project pipeline for the select argument
-			builder.Project(selectMap)
+			for _, stage := range transformation.stages {
+				builder.Add(stage.(bson.D))
+			}
 		}
 	}
 
+	// Prepares the filter object and build the match pipeline
+	if filterQuery, ok := queryMap[odata.Filter].(*parser.ParseNode); ok {
+		filterObj, err := ApplyFilter(filterQuery)
+		if err != nil {
+			return err
+		}
+
+		// Creates the match pipeline for the filter
+		builder.Match(filterObj)
+	}
+
+	// Prepares the match of the documents after the skip token of the server driven paging
+	switch skipToken := queryMap[odataSkipToken].(type) {
+	case bson.M:
+		builder.Match(skipToken)
+	case string:
+		return fmt.Errorf("%w: %v can only be used in paged queries", ErrInvalidInput, odataSkipToken)
+	}
+
 	// Prepare the sort object and build the sort pipeline
-	sortMap := make(bson.M)
-	if queryMap[odata.OrderBy] != nil {
-		orderBySlice := queryMap[odata.OrderBy].([]odata.OrderItem)
+	if orderBySlice, ok := queryMap[odata.OrderBy].([]odata.OrderItem); ok {
+		sortFields := bson.D{}
 		for _, item := range orderBySlice {
-			if item.Order == "desc" {
-				sortMap[item.Field] = -1
+			if item.Order == odata.Descendent {
+				sortFields = append(sortFields, bson.E{Key: item.Field, Value: -1})
 			} else {
-				sortMap[item.Field] = 1
+				sortFields = append(sortFields, bson.E{Key: item.Field, Value: 1})
 			}
 		}
 
 		// Create the sort pipeline for the sorting object
-		builder.Sort(sortMap)
+		builder.Sort(sortFields)
+	} else if textSearch {
+		// Without an explicit order the text search results are sorted by relevance
+		builder.Sort(bson.D{{Key: ODataSearchScoreField, Value: -1}})
 	}
 
-	cursor, err := builder.Aggregate()
+	// Prepares the skip pipeline
+	if skip, ok := queryMap[odata.Skip].(int); ok {
+		builder.Skip(skip)
+	}
 
-	return cursor, err
+	// Prepares the limit pipeline
+	if limit, ok := queryMap[odata.Top].(int); ok {
+		builder.Limit(limit)
+	}
+
+	// Prepares the lookups of the expanded properties
+	expanded := make([]string, 0)
+	if expandItems, ok := queryMap[odataExpand].([]odataExpandItem); ok {
+		var err error
+		expanded, err = applyExpand(builder, collection, expandItems)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Prepare Select object and build the project pipeline, the expanded properties are
+	// always returned
+	if selectSlice, ok := queryMap[odata.Select].([]string); ok {
+		selectMap := make(bson.M)
+		for _, fieldName := range selectSlice {
+			selectMap[strings.TrimSpace(fieldName)] = 1
+		}
+		for _, fieldName := range expanded {
+			selectMap[fieldName] = 1
+		}
+		if textSearch {
+			selectMap[ODataSearchScoreField] = 1
+		}
+
+		// Creates the project pipeline for the select argument
+		builder.Project(selectMap)
+	}
+
+	return nil
 }
@@ -0,0 +1,223 @@
+package mongodb
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cjlapao/common-go/odata"
+	"github.com/cjlapao/common-go/parser"
+)
+
+// ErrODataPolicyViolation Error returned when an odata query does not comply with the parser policy
+var ErrODataPolicyViolation = errors.New("odata policy violation")
+
+// ODataPolicy Restricts what the clients can query with the ODataParser, empty field lists
+// allow all the fields and zero values disable the limits. The allowed fields also allow their
+// sub fields, so allowing address allows address.city, and the expanded properties are fields
+// too, so allowing customer allows the nested queries of the expanded customer
+type ODataPolicy struct {
+	// FilterableFields Fields that can be used in $filter
+	FilterableFields []string
+	// SortableFields Fields that can be used in $orderby
+	SortableFields []string
+	// SelectableFields Fields that can be used in $select
+	SelectableFields []string
+	// MaxTop Maximum value of $top
+	MaxTop int
+	// DefaultPageSize $top used when the query does not have one
+	DefaultPageSize int
+	// MaxFilterDepth Maximum nesting of the logical operators, comparisons and lambdas in $filter
+	MaxFilterDepth int
+	// DisallowedOperators Operators and functions that cannot be used in $filter, like regex,
+	// contains or any
+	DisallowedOperators []string
+	// AllowApply Allows $apply aggregations, the filters before the first groupby or aggregate
+	// are checked like the $filter, the groupby and aggregate properties are not checked
+	AllowApply bool
+	// AllowSearch Allows $search, the searched fields are the text index or the registered
+	// search fields of the collection
+	AllowSearch bool
+	// MaxSearchTerms Maximum number of terms of $search
+	MaxSearchTerms int
+}
+
+// WithPolicy Sets the policy the odata queries need to comply with, the queries violating it
+// return an ErrODataPolicyViolation error
+//
+// Example:
+//		parser := collection.OData().WithPolicy(&ODataPolicy{
+//			FilterableFields:    []string{"name", "status", "address"},
+//			SortableFields:      []string{"name", "createdOn"},
+//			MaxTop:              100,
+//			DefaultPageSize:     25,
+//			MaxFilterDepth:      5,
+//			DisallowedOperators: []string{"regex"},
+//		})
+func (odataParser *ODataParser) WithPolicy(policy *ODataPolicy) *ODataParser {
+	odataParser.policy = policy
+	return odataParser
+}
+
+// apply Validates a parsed query against the policy and sets the default page size
+func (policy *ODataPolicy) apply(queryMap map[string]interface{}) error {
+	if err := policy.validate(queryMap, ""); err != nil {
+		return err
+	}
+
+	if _, ok := queryMap[odata.Top].(int); !ok && policy.DefaultPageSize > 0 {
+		queryMap[odata.Top] = policy.DefaultPageSize
+	}
+
+	return nil
+}
+
+// validate Validates a parsed query, the fields of the expanded queries are checked with the
+// path of the expanded property as their prefix, so allowing customer.name allows filtering
+// the expanded customer by name
+func (policy *ODataPolicy) validate(queryMap map[string]interface{}, prefix string) error {
+	if top, ok := queryMap[odata.Top].(int); ok && policy.MaxTop > 0 && top > policy.MaxTop {
+		return fmt.Errorf("%w: $top cannot be greater than %v", ErrODataPolicyViolation, policy.MaxTop)
+	}
+
+	if searchTerms, ok := queryMap[odataSearch].([]odataSearchTerm); ok {
+		if !policy.AllowSearch {
+			return fmt.Errorf("%w: $search is not allowed", ErrODataPolicyViolation)
+		}
+		if policy.MaxSearchTerms > 0 && len(searchTerms) > policy.MaxSearchTerms {
+			return fmt.Errorf("%w: $search cannot have more than %v terms", ErrODataPolicyViolation, policy.MaxSearchTerms)
+		}
+	}
+
+	if filterQuery, ok := queryMap[odata.Filter].(*parser.ParseNode); ok {
+		if err := policy.validateFilterQuery(filterQuery, prefix, policy.FilterableFields); err != nil {
+			return err
+		}
+	}
+
+	if transformations, ok := queryMap[odataApply].([]odataApplyTransformation); ok {
+		if !policy.AllowApply {
+			return fmt.Errorf("%w: $apply is not allowed", ErrODataPolicyViolation)
+		}
+
+		// the filters of the aggregated rows use the groupby properties and the aggregate aliases
+		documentFilters, rowFilters := getApplyFilters(transformations)
+		for _, filter := range documentFilters {
+			if err := policy.validateFilterQuery(filter, prefix, policy.FilterableFields); err != nil {
+				return err
+			}
+		}
+		for _, filter := range rowFilters {
+			if err := policy.validateFilterQuery(filter, prefix, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	if orderBySlice, ok := queryMap[odata.OrderBy].([]odata.OrderItem); ok {
+		for _, item := range orderBySlice {
+			field := joinFieldPath(prefix, strings.ReplaceAll(strings.TrimSpace(item.Field), "/", "."))
+			if !isFieldAllowed(field, policy.SortableFields) {
+				return fmt.Errorf("%w: field %v cannot be used in $orderby", ErrODataPolicyViolation, field)
+			}
+		}
+	}
+
+	if selectSlice, ok := queryMap[odata.Select].([]string); ok {
+		for _, item := range selectSlice {
+			field := joinFieldPath(prefix, strings.ReplaceAll(strings.TrimSpace(item), "/", "."))
+			if !isFieldAllowed(field, policy.SelectableFields) {
+				return fmt.Errorf("%w: field %v cannot be used in $select", ErrODataPolicyViolation, field)
+			}
+		}
+	}
+
+	if expandItems, ok := queryMap[odataExpand].([]odataExpandItem); ok {
+		for _, item := range expandItems {
+			if err := policy.validate(item.query, joinFieldPath(prefix, item.name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateFilterQuery Checks the depth, the operators and the fields of a filter, the $apply
+// filters are checked like the $filter
+func (policy *ODataPolicy) validateFilterQuery(filterQuery *parser.ParseNode, prefix string, allowedFields []string) error {
+	if depth := getFilterDepth(filterQuery); policy.MaxFilterDepth > 0 && depth > policy.MaxFilterDepth {
+		return fmt.Errorf("%w: $filter depth %v is greater than %v", ErrODataPolicyViolation, depth, policy.MaxFilterDepth)
+	}
+
+	if err := policy.validateFilterOperators(filterQuery); err != nil {
+		return err
+	}
+
+	// only the literals in a field position are fields, the other ones are values
+	return visitFilterFields(filterQuery, prefix, func(node *parser.ParseNode, prefix string) error {
+		field := joinFieldPath(prefix, node.Token.Value.(string))
+		if node.Token.Value.(string) == "" {
+			field = prefix
+		}
+
+		if !isFieldAllowed(field, allowedFields) {
+			return fmt.Errorf("%w: field %v cannot be used in $filter", ErrODataPolicyViolation, field)
+		}
+		return nil
+	})
+}
+
+// validateFilterOperators Checks the filter does not use the disallowed operators and functions
+func (policy *ODataPolicy) validateFilterOperators(node *parser.ParseNode) error {
+	name, _ := node.Token.Value.(string)
+
+	switch node.Token.Type {
+	case parser.FilterTokenLogical, parser.FilterTokenFunc, filterTokenLambda:
+		for _, operator := range policy.DisallowedOperators {
+			if strings.EqualFold(operator, name) {
+				return fmt.Errorf("%w: operator %v is not allowed in $filter", ErrODataPolicyViolation, name)
+			}
+		}
+	}
+
+	for _, child := range node.Children {
+		if err := policy.validateFilterOperators(child); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getFilterDepth Gets the nesting depth of the logical operators, comparisons and lambdas
+func getFilterDepth(node *parser.ParseNode) int {
+	depth := 0
+	for _, child := range node.Children {
+		if childDepth := getFilterDepth(child); childDepth > depth {
+			depth = childDepth
+		}
+	}
+
+	if isBooleanFilterNode(node) {
+		depth++
+	}
+
+	return depth
+}
+
+// isFieldAllowed Checks if the field or one of its parents is in the allowed fields, an empty
+// list allows all the fields
+func isFieldAllowed(field string, allowedFields []string) bool {
+	if len(allowedFields) == 0 {
+		return true
+	}
+
+	for _, allowed := range allowedFields {
+		if field == allowed || strings.HasPrefix(field, allowed+".") {
+			return true
+		}
+	}
+
+	return false
+}
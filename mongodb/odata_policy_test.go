@@ -0,0 +1,157 @@
+package mongodb
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/cjlapao/common-go/odata"
+)
+
+func TestODataParser_Policy(t *testing.T) {
+	policy := &ODataPolicy{
+		FilterableFields:    []string{"name", "status", "tags", "customer.name"},
+		SortableFields:      []string{"name", "customer"},
+		SelectableFields:    []string{"name", "status"},
+		MaxTop:              50,
+		MaxFilterDepth:      3,
+		DisallowedOperators: []string{"regex"},
+		AllowSearch:         true,
+		MaxSearchTerms:      2,
+	}
+
+	tests := []struct {
+		name    string
+		policy  *ODataPolicy
+		query   url.Values
+		wantErr error
+	}{
+		{
+			name:   "allowed fields",
+			policy: policy,
+			query:  url.Values{"$filter": {"name eq 'john' and status ne 'closed'"}, "$orderby": {"name"}, "$select": {"name,status"}},
+		},
+		{
+			name:   "literal values are not fields",
+			policy: policy,
+			query:  url.Values{"$filter": {"status eq active and status in (open, closed) and contains(name, john)"}},
+		},
+		{
+			name:   "lambda fields are relative to the array",
+			policy: policy,
+			query:  url.Values{"$filter": {"tags/any(t: t eq 'urgent')"}},
+		},
+		{
+			name:    "filter field not allowed",
+			policy:  policy,
+			query:   url.Values{"$filter": {"name eq 'john' and total gt 10"}},
+			wantErr: ErrODataPolicyViolation,
+		},
+		{
+			name:    "expression fields are checked on both sides",
+			policy:  policy,
+			query:   url.Values{"$filter": {"name eq concat(status, internal)"}},
+			wantErr: ErrODataPolicyViolation,
+		},
+		{
+			name:    "function field not allowed",
+			policy:  policy,
+			query:   url.Values{"$filter": {"startswith(internal, 'a')"}},
+			wantErr: ErrODataPolicyViolation,
+		},
+		{
+			name:    "operator not allowed",
+			policy:  policy,
+			query:   url.Values{"$filter": {"name regex '^jo'"}},
+			wantErr: ErrODataPolicyViolation,
+		},
+		{
+			name:    "filter too deep",
+			policy:  policy,
+			query:   url.Values{"$filter": {"name eq 'a' or (name eq 'b' and not (status eq 'c'))"}},
+			wantErr: ErrODataPolicyViolation,
+		},
+		{
+			name:    "orderby field not allowed",
+			policy:  policy,
+			query:   url.Values{"$orderby": {"status"}},
+			wantErr: ErrODataPolicyViolation,
+		},
+		{
+			name:    "select field not allowed",
+			policy:  policy,
+			query:   url.Values{"$select": {"name,total"}},
+			wantErr: ErrODataPolicyViolation,
+		},
+		{
+			name:    "top too big",
+			policy:  policy,
+			query:   url.Values{"$top": {"100"}},
+			wantErr: ErrODataPolicyViolation,
+		},
+		{
+			name:   "expanded query fields",
+			policy: policy,
+			query:  url.Values{"$expand": {"customer($filter=name eq 'john';$orderby=name)"}},
+		},
+		{
+			name:    "expanded filter field not allowed",
+			policy:  policy,
+			query:   url.Values{"$expand": {"customer($filter=email eq 'john@example.com')"}},
+			wantErr: ErrODataPolicyViolation,
+		},
+		{
+			name:    "expanded select field not allowed",
+			policy:  policy,
+			query:   url.Values{"$expand": {"customer($select=email)"}},
+			wantErr: ErrODataPolicyViolation,
+		},
+		{
+			name:   "search",
+			policy: policy,
+			query:  url.Values{"$search": {`"red shoes" -kids`}},
+		},
+		{
+			name:    "too many search terms",
+			policy:  policy,
+			query:   url.Values{"$search": {"red shoes kids"}},
+			wantErr: ErrODataPolicyViolation,
+		},
+		{
+			name:    "search not allowed",
+			policy:  &ODataPolicy{},
+			query:   url.Values{"$search": {"shoes"}},
+			wantErr: ErrODataPolicyViolation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			odataParser := EmptyODataParser(&mongoCollection{name: "policy_orders"}).WithPolicy(tt.policy)
+
+			_, err := odataParser.parseQuery(tt.query)
+			if (err != nil) != (tt.wantErr != nil) || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Errorf("parseQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestODataPolicy_DefaultPageSize(t *testing.T) {
+	policy := &ODataPolicy{DefaultPageSize: 25}
+
+	queryMap := map[string]interface{}{}
+	if err := policy.apply(queryMap); err != nil {
+		t.Fatalf("apply() error = %v", err)
+	}
+	if top := queryMap[odata.Top]; top != 25 {
+		t.Errorf("apply() $top = %v, expected %v", top, 25)
+	}
+
+	queryMap = map[string]interface{}{odata.Top: 10}
+	if err := policy.apply(queryMap); err != nil {
+		t.Fatalf("apply() error = %v", err)
+	}
+	if top := queryMap[odata.Top]; top != 10 {
+		t.Errorf("apply() $top = %v, expected %v", top, 10)
+	}
+}
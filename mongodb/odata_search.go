@@ -0,0 +1,193 @@
+package mongodb
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// odataSearch OData keyword for the free text search, the common odata parser does not define it
+const odataSearch = "$search"
+
+// ODataSearchScoreField Field where the text search relevance score is returned
+const ODataSearchScoreField = "_score"
+
+type odataSearchTerm struct {
+	value   string
+	phrase  bool
+	negated bool
+}
+
+var odataSearchFields = make(map[string][]string)
+var odataSearchFieldsLock sync.RWMutex
+
+var odataSearchTermRegexp = regexp.MustCompile(`-?"[^"]*"|\S+`)
+
+// RegisterODataSearchFields Registers the fields of a collection used by $search when the
+// collection does not have a text index, the terms are then matched with a case insensitive
+// regex against any of the fields
+//
+// Example:
+//		RegisterODataSearchFields("products", "name", "description", "sku")
+//		// GET /products?$search="red shoes" NOT kids
+func RegisterODataSearchFields(collection string, fields ...string) {
+	odataSearchFieldsLock.Lock()
+	defer odataSearchFieldsLock.Unlock()
+
+	odataSearchFields[collection] = fields
+}
+
+// getODataSearchFields Gets the registered search fields of a collection
+func getODataSearchFields(collection string) []string {
+	odataSearchFieldsLock.RLock()
+	defer odataSearchFieldsLock.RUnlock()
+
+	return odataSearchFields[collection]
+}
+
+// parseSearch Parses the $search value into terms, double quoted terms are phrases and terms
+// prefixed with NOT or - are excluded, AND and OR are ignored as any of the terms can match
+func parseSearch(value string) ([]odataSearchTerm, error) {
+	result := make([]odataSearchTerm, 0)
+	negated := false
+	hasPositiveTerm := false
+
+	for _, token := range odataSearchTermRegexp.FindAllString(value, -1) {
+		switch token {
+		case "AND", "OR":
+			continue
+		case "NOT":
+			negated = true
+			continue
+		}
+
+		term := odataSearchTerm{
+			value:   token,
+			negated: negated,
+		}
+		negated = false
+
+		if strings.HasPrefix(term.value, "-") {
+			term.negated = true
+			term.value = term.value[1:]
+		}
+		if strings.HasPrefix(term.value, "\"") {
+			term.phrase = true
+			term.value = strings.Trim(term.value, "\"")
+		}
+		if term.value == "" {
+			continue
+		}
+
+		hasPositiveTerm = hasPositiveTerm || !term.negated
+		result = append(result, term)
+	}
+
+	if !hasPositiveTerm {
+		return nil, fmt.Errorf("%w: %v needs at least one term to search for", ErrInvalidInput, odataSearch)
+	}
+
+	return result, nil
+}
+
+// applySearch Adds the search match to the builder, collections with a text index use a $text
+// match and get the relevance score in the ODataSearchScoreField, the others use a regex over
+// the registered search fields. Returns true if the text index was used
+func applySearch(builder *PipelineBuilder, collection string, terms []odataSearchTerm) (bool, error) {
+	if builder.collection == nil {
+		return false, fmt.Errorf("%w: %v is not supported in expanded properties", ErrInvalidInput, odataSearch)
+	}
+
+	textIndex, err := hasTextIndex(builder)
+	if err != nil {
+		logger.Exception(err, "There was an error checking the text indexes of %v", collection)
+		return false, err
+	}
+
+	if textIndex {
+		builder.Match(bson.M{"$text": bson.M{"$search": getTextSearch(terms)}})
+		builder.AddField(ODataSearchScoreField, bson.M{"$meta": "textScore"})
+		return true, nil
+	}
+
+	fields := getODataSearchFields(collection)
+	if len(fields) == 0 {
+		return false, fmt.Errorf("%w: %v has no text index or search fields", ErrInvalidInput, collection)
+	}
+
+	builder.Match(getRegexSearch(fields, terms))
+	return false, nil
+}
+
+// hasTextIndex Checks if the builder collection has a text index
+func hasTextIndex(builder *PipelineBuilder) (bool, error) {
+	ctx := builder.context
+	cursor, err := builder.collection.Indexes().List(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	indexes := make([]MongoIndex, 0)
+	if err := cursor.All(ctx, &indexes); err != nil {
+		return false, err
+	}
+
+	for _, index := range indexes {
+		for _, key := range index.Keys {
+			if key.Value == string(TextIndex) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// getTextSearch Converts the terms into a $text search string
+func getTextSearch(terms []odataSearchTerm) string {
+	result := make([]string, 0)
+	for _, term := range terms {
+		value := term.value
+		if term.phrase {
+			value = "\"" + value + "\""
+		}
+		if term.negated {
+			value = "-" + value
+		}
+		result = append(result, value)
+	}
+
+	return strings.Join(result, " ")
+}
+
+// getRegexSearch Creates a filter matching any of the terms in any of the fields and none of the
+// excluded terms
+func getRegexSearch(fields []string, terms []odataSearchTerm) bson.M {
+	included := make([]bson.M, 0)
+	excluded := make([]bson.M, 0)
+
+	for _, term := range terms {
+		regex := primitive.Regex{
+			Pattern: regexp.QuoteMeta(term.value),
+			Options: "i",
+		}
+
+		for _, field := range fields {
+			if term.negated {
+				excluded = append(excluded, bson.M{field: regex})
+			} else {
+				included = append(included, bson.M{field: regex})
+			}
+		}
+	}
+
+	if len(excluded) == 0 {
+		return bson.M{"$or": included}
+	}
+
+	return bson.M{"$and": []bson.M{{"$or": included}, {"$nor": excluded}}}
+}
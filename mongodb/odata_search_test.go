@@ -0,0 +1,96 @@
+package mongodb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseSearch(t *testing.T) {
+	tests := []struct {
+		name     string
+		search   string
+		expect   []odataSearchTerm
+		wantText string
+		wantErr  error
+	}{
+		{
+			name:     "terms",
+			search:   "red shoes",
+			expect:   []odataSearchTerm{{value: "red"}, {value: "shoes"}},
+			wantText: "red shoes",
+		},
+		{
+			name:     "phrase and excluded terms",
+			search:   `"red shoes" NOT kids -used`,
+			expect:   []odataSearchTerm{{value: "red shoes", phrase: true}, {value: "kids", negated: true}, {value: "used", negated: true}},
+			wantText: `"red shoes" -kids -used`,
+		},
+		{
+			name:     "and or are ignored",
+			search:   "red AND shoes OR boots",
+			expect:   []odataSearchTerm{{value: "red"}, {value: "shoes"}, {value: "boots"}},
+			wantText: "red shoes boots",
+		},
+		{
+			name:     "excluded phrase",
+			search:   `shoes -"for kids"`,
+			expect:   []odataSearchTerm{{value: "shoes"}, {value: "for kids", phrase: true, negated: true}},
+			wantText: `shoes -"for kids"`,
+		},
+		{
+			name:    "only excluded terms",
+			search:  "NOT kids -used",
+			wantErr: ErrInvalidInput,
+		},
+		{
+			name:    "empty phrase",
+			search:  `""`,
+			wantErr: ErrInvalidInput,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terms, err := parseSearch(tt.search)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseSearch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(terms, tt.expect) {
+				t.Errorf("parseSearch() = %v, expected %v", terms, tt.expect)
+			}
+			if got := getTextSearch(terms); got != tt.wantText {
+				t.Errorf("getTextSearch() = %v, expected %v", got, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestGetRegexSearch(t *testing.T) {
+	terms := []odataSearchTerm{{value: "a.b"}, {value: "kids", negated: true}}
+
+	result := getRegexSearch([]string{"name", "sku"}, terms)
+
+	included := primitive.Regex{Pattern: `a\.b`, Options: "i"}
+	excluded := primitive.Regex{Pattern: "kids", Options: "i"}
+	expect := bson.M{"$and": []bson.M{
+		{"$or": []bson.M{{"name": included}, {"sku": included}}},
+		{"$nor": []bson.M{{"name": excluded}, {"sku": excluded}}},
+	}}
+	if !reflect.DeepEqual(result, expect) {
+		t.Errorf("getRegexSearch() = %v, expected %v", result, expect)
+	}
+}
+
+func TestApplySearchWithoutCollection(t *testing.T) {
+	_, err := applySearch(NewEmptyPipeline(nil), "products", []odataSearchTerm{{value: "shoes"}})
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("applySearch() error = %v, wantErr %v", err, ErrInvalidInput)
+	}
+}
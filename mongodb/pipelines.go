@@ -9,6 +9,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type Pipeline struct {
@@ -110,6 +111,7 @@ type PipelineOptions struct {
 	IncludeAddFields  bool
 	IncludeCount      bool
 	IncludeLimit      bool
+	IncludeLookup     bool
 	IncludeMatch      bool
 	IncludeProjection bool
 	IncludeSkip       bool
@@ -119,6 +121,8 @@ type PipelineOptions struct {
 }
 
 type PipelineBuilder struct {
+	context          context.Context
+	batchSize        int32
 	options          PipelineOptions
 	collection       *mongo.Collection
 	pipelines        []Pipeline
@@ -127,9 +131,11 @@ type PipelineBuilder struct {
 	sortingEndFields []sortField
 	projectedFields  []projectField
 	addFields        []addField
+	scope            interface{}
 }
 
-// NewEmptyPipeline Creates a new pipeline builder for a specific collection
+// NewEmptyPipeline Creates a new pipeline builder for a specific collection, the collection
+// can be nil to build sub pipelines, for example the pipeline of a lookup
 func NewEmptyPipeline(collection *mongoCollection) *PipelineBuilder {
 	builder := PipelineBuilder{}
 	builder.pipelines = make([]Pipeline, 0)
@@ -138,11 +144,15 @@ func NewEmptyPipeline(collection *mongoCollection) *PipelineBuilder {
 	builder.sortingFields = make([]sortField, 0)
 	builder.sortingEndFields = make([]sortField, 0)
 	builder.addFields = make([]addField, 0)
-	builder.collection = collection.coll
+	if collection != nil {
+		builder.collection = collection.coll
+	}
+	builder.context = context.Background()
 	builder.options = PipelineOptions{
 		IncludeAddFields:  true,
 		IncludeCount:      true,
 		IncludeLimit:      true,
+		IncludeLookup:     true,
 		IncludeMatch:      true,
 		IncludeProjection: true,
 		IncludeSkip:       true,
@@ -154,6 +164,30 @@ func NewEmptyPipeline(collection *mongoCollection) *PipelineBuilder {
 	return &builder
 }
 
+// WithContext Sets the context used to run the aggregations, use the session context to run
+// the pipeline inside a transaction
+func (pipelineBuilder *PipelineBuilder) WithContext(ctx context.Context) *PipelineBuilder {
+	if ctx != nil {
+		pipelineBuilder.context = ctx
+	}
+
+	return pipelineBuilder
+}
+
+// BatchSize Sets the number of documents the cursor fetches from the server in each batch,
+// if the size is lower or equal than 0 the server default is used
+func (pipelineBuilder *PipelineBuilder) BatchSize(batchSize int32) *PipelineBuilder {
+	pipelineBuilder.batchSize = batchSize
+	return pipelineBuilder
+}
+
+// withScope Sets the scope filter of the repository, the documents out of the scope are removed
+// at the start of the pipeline
+func (pipelineBuilder *PipelineBuilder) withScope(scope interface{}) *PipelineBuilder {
+	pipelineBuilder.scope = scope
+	return pipelineBuilder
+}
+
 // Add Adds a user custom pipeline to the builder, this can be any valid mongo pipeline
 func (pipelineBuilder *PipelineBuilder) Add(pipeline bson.D) *PipelineBuilder {
 	pipelineEntry := Pipeline{
@@ -201,7 +235,7 @@ func (pipelineBuilder *PipelineBuilder) Page(page int, pageSize int) *PipelineBu
 // consideration any filtering done by the user but not any system pipelines
 func (pipelineBuilder *PipelineBuilder) CountPipeline() int {
 	currentPipelines := pipelineBuilder.pipelines
-	ctx := context.Background()
+	ctx := pipelineBuilder.context
 	options := PipelineOptions{
 		IncludeCount:      true,
 		IncludeMatch:      true,
@@ -221,13 +255,13 @@ func (pipelineBuilder *PipelineBuilder) CountPipeline() int {
 	}
 
 	pipelineBuilder.pipelines = currentPipelines
-	return int(element[0]["count"].(int32))
+	return getCountValue(element[0]["count"])
 }
 
 // CountCollection This will count the pipeline collection excluding anything from the pipelines
 // we can use this for odata responses or to count how many objects the collection has
 func (pipelineBuilder *PipelineBuilder) CountCollection() int {
-	ctx := context.Background()
+	ctx := pipelineBuilder.context
 	countDocument := bson.D{
 		{
 			Key:   "$count",
@@ -237,6 +271,7 @@ func (pipelineBuilder *PipelineBuilder) CountCollection() int {
 
 	pipeline := bson.A{}
 	pipeline = append(pipeline, countDocument)
+	pipeline = scopePipeline(pipeline, pipelineBuilder.scope)
 	cursor, err := pipelineBuilder.collection.Aggregate(ctx, pipeline)
 	if err != nil {
 		return -1
@@ -247,7 +282,7 @@ func (pipelineBuilder *PipelineBuilder) CountCollection() int {
 		return 0
 	}
 
-	return int(element[0]["count"].(int32))
+	return getCountValue(element[0]["count"])
 }
 
 // Count Adds a count pipeline, this will overseed any other pipeline and will always return a count
@@ -387,11 +422,35 @@ func (pipelineBuilder *PipelineBuilder) Lookup(from string, localField string, f
 	return pipelineBuilder
 }
 
+// LookupPipeline Adds a lookup pipeline that joins the documents returned by a pipeline run on
+// the other collection, the let variables are available in the pipeline as $$variable
+func (pipelineBuilder *PipelineBuilder) LookupPipeline(from string, let bson.M, pipeline bson.A, fieldAs string) *PipelineBuilder {
+	lookupPipeline := Pipeline{
+		executionOrder: pipelineBuilder.getNextIndex(),
+		pipelineType:   Lookup,
+		primitive: bson.D{
+			{
+				Key: "$lookup",
+				Value: bson.M{
+					"from":     from,
+					"let":      let,
+					"pipeline": pipeline,
+					"as":       fieldAs,
+				},
+			},
+		},
+	}
+
+	pipelineBuilder.pipelines = append(pipelineBuilder.pipelines, lookupPipeline)
+
+	return pipelineBuilder
+}
+
 // Unwind Adds an Unwind pipeline to flatten an array
 func (pipelineBuilder *PipelineBuilder) Unwind(path string) *PipelineBuilder {
 	lookupPipeline := Pipeline{
 		executionOrder: pipelineBuilder.getNextIndex(),
-		pipelineType:   Lookup,
+		pipelineType:   Unwind,
 		primitive: bson.D{
 			{
 				Key: "$unwind",
@@ -411,7 +470,7 @@ func (pipelineBuilder *PipelineBuilder) Unwind(path string) *PipelineBuilder {
 func (pipelineBuilder *PipelineBuilder) UnwindWidthIndex(path string, includeArrayIndex string, preserveNullAndEmptyArrays bool) *PipelineBuilder {
 	lookupPipeline := Pipeline{
 		executionOrder: pipelineBuilder.getNextIndex(),
-		pipelineType:   Lookup,
+		pipelineType:   Unwind,
 		primitive: bson.D{
 			{
 				Key: "$unwind",
@@ -429,6 +488,28 @@ func (pipelineBuilder *PipelineBuilder) UnwindWidthIndex(path string, includeArr
 	return pipelineBuilder
 }
 
+// UnwindPreservingEmpty Adds an Unwind pipeline to flatten an array keeping the documents
+// where the array is missing or empty
+func (pipelineBuilder *PipelineBuilder) UnwindPreservingEmpty(path string) *PipelineBuilder {
+	unwindPipeline := Pipeline{
+		executionOrder: pipelineBuilder.getNextIndex(),
+		pipelineType:   Unwind,
+		primitive: bson.D{
+			{
+				Key: "$unwind",
+				Value: bson.M{
+					"path":                       path,
+					"preserveNullAndEmptyArrays": true,
+				},
+			},
+		},
+	}
+
+	pipelineBuilder.pipelines = append(pipelineBuilder.pipelines, unwindPipeline)
+
+	return pipelineBuilder
+}
+
 // ProjectField adds a field to be projected by the pipeline, this allows to easily build a projection
 // of complex fields without adding a pre built interface.
 func (pipelineBuilder *PipelineBuilder) ProjectField(field string) *PipelineBuilder {
@@ -663,6 +744,7 @@ func (pipelineBuilder *PipelineBuilder) FilterByUserPipelines() *PipelineBuilder
 		IncludeAddFields:  false,
 		IncludeCount:      false,
 		IncludeLimit:      false,
+		IncludeLookup:     false,
 		IncludeMatch:      false,
 		IncludeProjection: false,
 		IncludeSkip:       false,
@@ -676,15 +758,26 @@ func (pipelineBuilder *PipelineBuilder) FilterByUserPipelines() *PipelineBuilder
 }
 
 func (pipelineBuilder *PipelineBuilder) Aggregate() (*mongoCursor, error) {
-	ctx := context.Background()
+	ctx := pipelineBuilder.context
+
+	aggregateOptions := options.Aggregate()
+	if pipelineBuilder.batchSize > 0 {
+		aggregateOptions.SetBatchSize(pipelineBuilder.batchSize)
+	}
 
 	pipeline := pipelineBuilder.buildPipeline()
-	cursor, err := pipelineBuilder.collection.Aggregate(ctx, *pipeline)
+	cursor, err := pipelineBuilder.collection.Aggregate(ctx, *pipeline, aggregateOptions)
 
 	return &mongoCursor{cursor: cursor}, err
 }
 
 func (pipelineBuilder *PipelineBuilder) buildPipeline(options ...PipelineOptions) *bson.A {
+	pipelines := scopePipeline(pipelineBuilder.buildStages(options...), pipelineBuilder.scope)
+	return &pipelines
+}
+
+// buildStages Builds the stages added to the builder without the scope of the repository
+func (pipelineBuilder *PipelineBuilder) buildStages(options ...PipelineOptions) bson.A {
 	var builderOptions PipelineOptions
 	if len(options) == 0 {
 		builderOptions = pipelineBuilder.options
@@ -721,6 +814,10 @@ func (pipelineBuilder *PipelineBuilder) buildPipeline(options ...PipelineOptions
 			pipelines = append(pipelines, pipeline.primitive)
 		}
 
+		if (pipeline.pipelineType == Lookup) && builderOptions.IncludeLookup {
+			pipelines = append(pipelines, pipeline.primitive)
+		}
+
 		if (pipeline.pipelineType == Match ||
 			pipeline.pipelineType == MatchField) && builderOptions.IncludeMatch {
 			pipelines = append(pipelines, pipeline.primitive)
@@ -759,7 +856,25 @@ func (pipelineBuilder *PipelineBuilder) buildPipeline(options ...PipelineOptions
 		}
 	}
 
-	return &pipelines
+	return pipelines
+}
+
+// scopePipeline Adds the scope filter to the start of the pipeline, when the pipeline starts
+// with a match the scope is joined to it as a $text search needs to be in the first stage
+func scopePipeline(pipeline bson.A, scope interface{}) bson.A {
+	if scope == nil {
+		return pipeline
+	}
+
+	if len(pipeline) > 0 {
+		if stage, ok := pipeline[0].(primitive.D); ok && len(stage) == 1 && stage[0].Key == "$match" {
+			result := append(bson.A{}, pipeline...)
+			result[0] = bson.D{{Key: "$match", Value: joinFilters(stage[0].Value, scope)}}
+			return result
+		}
+	}
+
+	return append(bson.A{bson.D{{Key: "$match", Value: scope}}}, pipeline...)
 }
 
 func (pipelineBuilder *PipelineBuilder) has(key pipelineType) (bool, int) {
@@ -831,18 +946,16 @@ func (pipelineBuilder *PipelineBuilder) getFilteredPipeline() bool {
 		return false
 	}
 
-	fields := primitive.M{}
-
-	var stringFilter string
+	conditions := make([]string, 0)
 	for _, filter := range pipelineBuilder.filters {
-		stringFilter = getOperationString(filter.field, filter.operation, filter.value)
-
-		filterParser := NewFilterParser(stringFilter)
-		parsedFilter, err := filterParser.Parse()
+		conditions = append(conditions, getOperationString(filter.field, filter.operation, filter.value))
+	}
 
-		if err == nil {
-			fields[filter.field] = parsedFilter.(primitive.M)[filter.field]
-		}
+	// the pipeline does not return errors, an invalid filter matches no documents
+	fields, err := getOperationsFilter(conditions)
+	if err != nil {
+		logger.Error("There was an error applying the filter, %v", err.Error())
+		fields = bson.M{"$expr": false}
 	}
 
 	matchPipeline := Pipeline{
@@ -2,9 +2,12 @@ package mongodb
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -20,16 +23,46 @@ type MongoRepository interface {
 	InsertMany(elements ...interface{}) (*mongoInsertManyResult, error)
 	UpdateOne(model *MongoUpdateOneModel) (*mongoUpdateResult, error)
 	UpdateMany(models ...*MongoUpdateOneModel) (*mongoBulkWriteResult, error)
+	UpdateAll(model *MongoUpdateManyModel) (*mongoUpdateResult, error)
 	UpsertOne(model *MongoUpdateOneModel) (*mongoUpdateResult, error)
 	UpsertMany(models ...*MongoUpdateOneModel) (*mongoBulkWriteResult, error)
+	BulkWrite(model *MongoBulkWriteModel) (*mongoBulkWriteResult, error)
+	ReplaceOne(filter interface{}, replacement interface{}, upsert bool) (*mongoUpdateResult, error)
+	FindOneAndUpdate(model *MongoUpdateOneModel, returnDocument ReturnDocument) *mongoSingleResult
+	FindOneAndReplace(filter interface{}, replacement interface{}, returnDocument ReturnDocument, upsert bool) *mongoSingleResult
+	FindOneAndDelete(filter interface{}) *mongoSingleResult
 	DeleteOne(model *MongoDeleteOneModel) (*mongoDeleteResult, error)
 	DeleteMany(filter interface{}) (*mongoDeleteResult, error)
+	CreateIndex(model *MongoIndexModel) (string, error)
+	CreateIndexes(models ...*MongoIndexModel) ([]string, error)
+	ListIndexes() ([]MongoIndex, error)
+	DropIndex(name string) error
+	EnsureIndexes(document interface{}) ([]string, error)
+	Watch() *ChangeStreamBuilder
+	WithContext(ctx context.Context) MongoRepository
+	WithBatchSize(batchSize int32) MongoRepository
+	WithAudit(auditOptions *AuditOptions) MongoRepository
+	WithSoftDelete(softDeleteOptions *SoftDeleteOptions) MongoRepository
+	WithDeleted() MongoRepository
+	Restore(filter interface{}) (*mongoUpdateResult, error)
+	Purge(olderThan time.Duration) (*mongoDeleteResult, error)
+	WithTenantScope(field string, tenantId string) MongoRepository
 }
 
+// defaultOperationTimeout Timeout applied to each of the repository operations
+const defaultOperationTimeout = 10 * time.Second
+
 type MongoDefaultRepository struct {
-	factory    *MongoFactory
-	Database   *mongoDatabase
-	Collection *mongoCollection
+	factory     *MongoFactory
+	context     context.Context
+	batchSize   int32
+	audit       *AuditOptions
+	softDelete  *SoftDeleteOptions
+	withDeleted bool
+	tenantField string
+	tenantId    string
+	Database    *mongoDatabase
+	Collection  *mongoCollection
 }
 
 // NewRepository Creates a new repository for a specific collection, this will allow you to perform
@@ -38,6 +71,7 @@ type MongoDefaultRepository struct {
 func (mongoFactory *MongoFactory) NewRepository(collection string) MongoRepository {
 	defaultRepo := MongoDefaultRepository{
 		factory: mongoFactory,
+		context: context.Background(),
 	}
 
 	defaultRepo.Database = mongoFactory.GetDatabase(mongoFactory.Database.name)
@@ -50,7 +84,10 @@ func (mongoFactory *MongoFactory) NewRepository(collection string) MongoReposito
 // this will allow you to perform queries and aggregations in the collection
 // Returns an implemented interface MongoRepository
 func (mongoFactory *MongoFactory) NewDatabaseRepository(database string, collection string) MongoRepository {
-	defaultRepo := MongoDefaultRepository{}
+	defaultRepo := MongoDefaultRepository{
+		factory: mongoFactory,
+		context: context.Background(),
+	}
 
 	defaultRepo.Database = mongoFactory.GetDatabase(database)
 	defaultRepo.Collection = mongoFactory.GetCollection(collection)
@@ -58,14 +95,57 @@ func (mongoFactory *MongoFactory) NewDatabaseRepository(database string, collect
 	return &defaultRepo
 }
 
+// WithContext Creates a copy of the repository that runs all operations using the context as
+// parent, this allows the repository to take part in a transaction by passing the session
+// context created by the factory WithTransaction
+//
+// Example:
+//		repository.WithContext(sessCtx).InsertOne(element)
+func (repository *MongoDefaultRepository) WithContext(ctx context.Context) MongoRepository {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	result := *repository
+	result.context = ctx
+	return &result
+}
+
+// WithBatchSize Creates a copy of the repository that returns cursors fetching the documents
+// from the server in batches of the size, use it with Each or Stream to iterate large
+// results without loading them all in memory
+//
+// Example:
+//		cursor, err := repository.WithBatchSize(500).Find("status eq 'active'")
+func (repository *MongoDefaultRepository) WithBatchSize(batchSize int32) MongoRepository {
+	result := *repository
+	result.batchSize = batchSize
+	return &result
+}
+
+// WithAudit Creates a copy of the repository that stamps the inserted, updated and replaced
+// documents with the time and the user of the change, the user is taken from the execution
+// context authorization
+//
+// Example:
+//		repository.WithAudit(NewAuditOptions()).InsertOne(order)
+func (repository *MongoDefaultRepository) WithAudit(auditOptions *AuditOptions) MongoRepository {
+	result := *repository
+	result.audit = auditOptions
+	return &result
+}
+
 // Pipeline Creates an empty pipeline for querying mongodb
 func (repository *MongoDefaultRepository) Pipeline() *PipelineBuilder {
-	return NewEmptyPipeline(repository.Collection)
+	return NewEmptyPipeline(repository.Collection).
+		WithContext(repository.getParentContext()).
+		BatchSize(repository.batchSize).
+		withScope(repository.getScope())
 }
 
 // OData Creates an OData parser to return data
 func (repository *MongoDefaultRepository) OData() *ODataParser {
-	return EmptyODataParser(repository.Collection)
+	return EmptyODataParser(repository.Collection).WithContext(repository.getParentContext()).withScope(repository.getScope())
 }
 
 // Find finds records with a filter and returns a cursor to iterate trough them
@@ -80,8 +160,8 @@ func (repository *MongoDefaultRepository) OData() *ODataParser {
 //
 // or a function like startswith
 // 		repository.Find("startswith(userId, 'someId'")
-func (repository *MongoDefaultRepository) Find(filter interface{}) (*mongoCursor, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (r *MongoDefaultRepository) Find(filter interface{}) (*mongoCursor, error) {
+	ctx, cancel := r.getContext()
 	defer cancel()
 
 	var filterToApply interface{}
@@ -102,7 +182,7 @@ func (repository *MongoDefaultRepository) Find(filter interface{}) (*mongoCursor
 		filterToApply = filter
 	}
 
-	cur, err := repository.Collection.coll.Find(ctx, filterToApply)
+	cur, err := r.Collection.coll.Find(ctx, r.scopeFilter(filterToApply), r.getFindOptions())
 
 	return &mongoCursor{cursor: cur}, err
 }
@@ -123,7 +203,7 @@ func (r *MongoDefaultRepository) FindBy(filter string) (*mongoCursor, error) {
 // Example:
 //		repository.FindFieldBy("userId" , mongo.Equal, "someId")
 func (r *MongoDefaultRepository) FindFieldBy(fieldName string, operation filterOperation, value interface{}) (*mongoCursor, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := r.getContext()
 
 	stringFilter := getOperationString(fieldName, operation, value)
 
@@ -136,7 +216,7 @@ func (r *MongoDefaultRepository) FindFieldBy(fieldName string, operation filterO
 		return nil, err
 	}
 
-	cur, err := r.Collection.coll.Find(ctx, parsedFilter)
+	cur, err := r.Collection.coll.Find(ctx, r.scopeFilter(parsedFilter), r.getFindOptions())
 
 	return &mongoCursor{cursor: cur}, err
 }
@@ -150,7 +230,7 @@ func (r *MongoDefaultRepository) FindFieldBy(fieldName string, operation filterO
 //		repository.FindOne(bson.M{"userId": "someId"})
 // The result will be a `mongoSingleResult` that can be decoded to any interface
 func (r *MongoDefaultRepository) FindOne(filter interface{}) *mongoSingleResult {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := r.getContext()
 
 	var filterToApply interface{}
 
@@ -171,16 +251,22 @@ func (r *MongoDefaultRepository) FindOne(filter interface{}) *mongoSingleResult
 		filterToApply = filter
 	}
 
-	result := r.Collection.coll.FindOne(ctx, filterToApply)
+	result := r.Collection.coll.FindOne(ctx, r.scopeFilter(filterToApply))
 
 	return &mongoSingleResult{sr: result}
 }
 
 // InsertOne Inserts a record int the collection and returns the inserted id
 func (r *MongoDefaultRepository) InsertOne(element interface{}) (*mongoInsertOneResult, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := r.getContext()
 	defer cancel()
 
+	element, err := r.prepareDocument(element, newAuditStamp())
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+
 	insertResult, err := r.Collection.coll.InsertOne(ctx, element)
 
 	if err != nil {
@@ -195,10 +281,21 @@ func (r *MongoDefaultRepository) InsertOne(element interface{}) (*mongoInsertOne
 
 // InsertMany Inserts multiple records in the collection and returns the inserted id's
 func (r *MongoDefaultRepository) InsertMany(elements ...interface{}) (*mongoInsertManyResult, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := r.getContext()
 	defer cancel()
 
-	insertResult, err := r.Collection.coll.InsertMany(ctx, elements)
+	stamp := newAuditStamp()
+	preparedElements := make([]interface{}, 0)
+	for _, element := range elements {
+		preparedElement, err := r.prepareDocument(element, stamp)
+		if err != nil {
+			logger.LogError(err)
+			return nil, err
+		}
+		preparedElements = append(preparedElements, preparedElement)
+	}
+
+	insertResult, err := r.Collection.coll.InsertMany(ctx, preparedElements)
 
 	if err != nil {
 		logger.LogError(err)
@@ -212,12 +309,22 @@ func (r *MongoDefaultRepository) InsertMany(elements ...interface{}) (*mongoInse
 
 // UpdateOne updates a document in a collection using a UpdateOneModel, this can be constructed
 // using strong typed language when called the UpdateOneModelBuilder
-// It will return the number of affected documents
+// It will return the number of affected documents, the versioned models return
+// ErrConcurrencyConflict if the document version has changed
 func (r *MongoDefaultRepository) UpdateOne(model *MongoUpdateOneModel) (*mongoUpdateResult, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := r.getContext()
 	defer cancel()
 	options := options.Update().SetUpsert(*model.model.Upsert)
-	updateOneResult, err := r.Collection.coll.UpdateOne(ctx, model.Filter, model.Update, options)
+	if model.model.ArrayFilters != nil {
+		options.SetArrayFilters(*model.model.ArrayFilters)
+	}
+	update, err := r.prepareUpdate(model.model.Update, newAuditStamp())
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+
+	updateOneResult, err := r.Collection.coll.UpdateOne(ctx, r.scopeFilter(model.Filter), update, options)
 
 	if err != nil {
 		logger.LogError(err)
@@ -226,6 +333,12 @@ func (r *MongoDefaultRepository) UpdateOne(model *MongoUpdateOneModel) (*mongoUp
 
 	result := mongoUpdateResult{}
 	result.FromMongo(updateOneResult)
+
+	// a versioned update that does not match means the version has changed
+	if model.versionField != "" && result.MatchedCount == 0 && result.UpsertedCount == 0 {
+		return &result, ErrConcurrencyConflict
+	}
+
 	return &result, nil
 }
 
@@ -238,10 +351,155 @@ func (r *MongoDefaultRepository) UpsertOne(model *MongoUpdateOneModel) (*mongoUp
 	return r.UpdateOne(model)
 }
 
+// UpdateAll updates all the documents matching the filter of a UpdateManyModel, this can be
+// constructed with the BuildMany of the UpdateOneModelBuilder.
+// It will return the number of matched and modified documents, the versioned models return
+// ErrConcurrencyConflict if no document has the version
+func (r *MongoDefaultRepository) UpdateAll(model *MongoUpdateManyModel) (*mongoUpdateResult, error) {
+	ctx, cancel := r.getContext()
+	defer cancel()
+	options := options.Update()
+	if model.model.Upsert != nil {
+		options.SetUpsert(*model.model.Upsert)
+	}
+	if model.model.ArrayFilters != nil {
+		options.SetArrayFilters(*model.model.ArrayFilters)
+	}
+	update, err := r.prepareUpdate(model.model.Update, newAuditStamp())
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+
+	updateManyResult, err := r.Collection.coll.UpdateMany(ctx, r.scopeFilter(model.Filter), update, options)
+
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+
+	result := mongoUpdateResult{}
+	result.FromMongo(updateManyResult)
+
+	// a versioned update that does not match means the version has changed
+	if model.versionField != "" && result.MatchedCount == 0 && result.UpsertedCount == 0 {
+		return &result, ErrConcurrencyConflict
+	}
+
+	return &result, nil
+}
+
+// ReplaceOne Replaces the first document matching the filter, the filter can be a bson document
+// or a odata type of query. With upsert the replacement is inserted if no document matches
+//
+// Example:
+//		repository.ReplaceOne("userId eq 'someId'", user, true)
+func (r *MongoDefaultRepository) ReplaceOne(filter interface{}, replacement interface{}, upsert bool) (*mongoUpdateResult, error) {
+	ctx, cancel := r.getContext()
+	defer cancel()
+
+	filterToApply, err := parseFilter(filter)
+	if err != nil {
+		logger.Error("There was an error applying the filter, %v", err.Error())
+		return nil, err
+	}
+
+	replacement, err = r.prepareReplacement(replacement, newAuditStamp())
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+
+	replaceResult, err := r.Collection.coll.ReplaceOne(ctx, r.scopeFilter(filterToApply), replacement, options.Replace().SetUpsert(upsert))
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+
+	result := mongoUpdateResult{}
+	result.FromMongo(replaceResult)
+	return &result, nil
+}
+
+// FindOneAndUpdate Updates the first document matching the model filter and returns it as it
+// was before or after the update in a single atomic operation, this can be used for counters
+// or to claim jobs
+//
+// Example:
+//		model, _ := NewUpdateOneModelBuilder().Filter("status eq 'pending'").Set("status", "running").Build()
+//		repository.FindOneAndUpdate(model, ReturnAfter).Decode(&job)
+func (r *MongoDefaultRepository) FindOneAndUpdate(model *MongoUpdateOneModel, returnDocument ReturnDocument) *mongoSingleResult {
+	ctx, cancel := r.getContext()
+	defer cancel()
+
+	update, err := r.prepareUpdate(model.model.Update, newAuditStamp())
+	if err != nil {
+		logger.LogError(err)
+		return &mongoSingleResult{err: err}
+	}
+
+	result := r.Collection.coll.FindOneAndUpdate(ctx, r.scopeFilter(model.Filter), update, getFindOneAndUpdateOptions(model, returnDocument))
+	if model.versionField != "" && errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return &mongoSingleResult{sr: result, err: ErrConcurrencyConflict}
+	}
+
+	return &mongoSingleResult{sr: result}
+}
+
+// FindOneAndReplace Replaces the first document matching the filter and returns it as it was
+// before or after the replace, the filter can be a bson document or a odata type of query
+func (r *MongoDefaultRepository) FindOneAndReplace(filter interface{}, replacement interface{}, returnDocument ReturnDocument, upsert bool) *mongoSingleResult {
+	ctx, cancel := r.getContext()
+	defer cancel()
+
+	filterToApply, err := parseFilter(filter)
+	if err != nil {
+		logger.Error("There was an error applying the filter, %v", err.Error())
+		return &mongoSingleResult{err: err}
+	}
+
+	replacement, err = r.prepareReplacement(replacement, newAuditStamp())
+	if err != nil {
+		logger.LogError(err)
+		return &mongoSingleResult{err: err}
+	}
+
+	replaceOptions := options.FindOneAndReplace().
+		SetReturnDocument(getReturnDocument(returnDocument)).
+		SetUpsert(upsert)
+	result := r.Collection.coll.FindOneAndReplace(ctx, r.scopeFilter(filterToApply), replacement, replaceOptions)
+
+	return &mongoSingleResult{sr: result}
+}
+
+// FindOneAndDelete Deletes the first document matching the filter and returns it, the filter
+// can be a bson document or a odata type of query
+func (r *MongoDefaultRepository) FindOneAndDelete(filter interface{}) *mongoSingleResult {
+	ctx, cancel := r.getContext()
+	defer cancel()
+
+	filterToApply, err := parseFilter(filter)
+	if err != nil {
+		logger.Error("There was an error applying the filter, %v", err.Error())
+		return &mongoSingleResult{err: err}
+	}
+
+	// the soft deletes stamp the document and return it as it was before
+	if r.softDelete != nil {
+		updateOptions := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+		result := r.Collection.coll.FindOneAndUpdate(ctx, r.scopeFilter(filterToApply), r.getSoftDeleteUpdate(newAuditStamp()), updateOptions)
+		return &mongoSingleResult{sr: result}
+	}
+
+	result := r.Collection.coll.FindOneAndDelete(ctx, r.scopeFilter(filterToApply))
+
+	return &mongoSingleResult{sr: result}
+}
+
 // UpdateMany updates documents in the collection using a UpdateOneModel, this can be constructed
 // using strong typed language when called the UpdateOneModelBuilder.
 func (r *MongoDefaultRepository) UpdateMany(models ...*MongoUpdateOneModel) (*mongoBulkWriteResult, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := r.getContext()
 	defer cancel()
 
 	if len(models) == 0 {
@@ -250,8 +508,14 @@ func (r *MongoDefaultRepository) UpdateMany(models ...*MongoUpdateOneModel) (*mo
 
 	writeModels := make([]mongo.WriteModel, 0)
 
+	stamp := newAuditStamp()
 	for _, model := range models {
-		writeModels = append(writeModels, model.model)
+		writeModel, err := r.prepareWriteModel(model.model, stamp)
+		if err != nil {
+			logger.LogError(err)
+			return nil, err
+		}
+		writeModels = append(writeModels, r.scopeWriteModel(writeModel, stamp))
 	}
 
 	bulkWriteResult, err := r.Collection.coll.BulkWrite(ctx, writeModels)
@@ -280,16 +544,82 @@ func (r *MongoDefaultRepository) UpsertMany(models ...*MongoUpdateOneModel) (*mo
 	return r.UpdateMany(models...)
 }
 
+// BulkWrite Runs a batch of operations built with the BulkWriteBuilder, the batch is sent in
+// chunks and each chunk runs with its own timeout. If any operation fails a *BulkWriteError is
+// returned with the result of the operations that succeeded, the errors have the index of the
+// operation in the builder. Ordered batches stop at the first failed chunk
+func (r *MongoDefaultRepository) BulkWrite(model *MongoBulkWriteModel) (*mongoBulkWriteResult, error) {
+	if model == nil || len(model.models) == 0 {
+		return nil, ErrNoElements
+	}
+
+	stamp := newAuditStamp()
+	scopedModel := MongoBulkWriteModel{
+		models:    make([]mongo.WriteModel, 0),
+		Ordered:   model.Ordered,
+		ChunkSize: model.ChunkSize,
+	}
+	for _, writeModel := range model.models {
+		preparedModel, err := r.prepareWriteModel(writeModel, stamp)
+		if err != nil {
+			logger.LogError(err)
+			return nil, err
+		}
+		scopedModel.models = append(scopedModel.models, r.scopeWriteModel(preparedModel, stamp))
+	}
+	model = &scopedModel
+
+	result := mongoBulkWriteResult{UpsertedIDs: make(map[int64]interface{})}
+	bulkWriteError := BulkWriteError{}
+	offset := 0
+
+	for _, chunk := range model.getChunks() {
+		bulkWriteResult, err := r.bulkWriteChunk(chunk, model.getBulkWriteOptions())
+		result.addBulkWriteResult(bulkWriteResult, offset)
+
+		if err != nil {
+			logger.LogError(err)
+			if !bulkWriteError.addBulkWriteException(err, offset) {
+				return &result, err
+			}
+			if model.Ordered {
+				break
+			}
+		}
+
+		offset += len(chunk)
+	}
+
+	if len(bulkWriteError.Errors) > 0 || bulkWriteError.WriteConcernError != nil {
+		return &result, &bulkWriteError
+	}
+
+	return &result, nil
+}
+
+// bulkWriteChunk Sends a chunk of a batch to the server
+func (r *MongoDefaultRepository) bulkWriteChunk(chunk []mongo.WriteModel, bulkWriteOptions *options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	ctx, cancel := r.getContext()
+	defer cancel()
+
+	return r.Collection.coll.BulkWrite(ctx, chunk, bulkWriteOptions)
+}
+
 // DeleteOne Deletes a document in a collection using a DeleteOneModel, this can be constructed
-// using strong typed language using the DeleteOneModelBuilder
+// using strong typed language using the DeleteOneModelBuilder. The soft delete repositories
+// stamp the deleted fields instead of removing the document
 func (r *MongoDefaultRepository) DeleteOne(model *MongoDeleteOneModel) (*mongoDeleteResult, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := r.getContext()
 	defer cancel()
 
+	if r.softDelete != nil {
+		return r.softDeleteDocuments(ctx, r.scopeFilter(model.Filter), model.Hint, false)
+	}
+
 	deleteOptions := options.Delete()
 	deleteOptions.Hint = model.Hint
 
-	deleteOneResult, err := r.Collection.coll.DeleteOne(ctx, model.Filter, deleteOptions)
+	deleteOneResult, err := r.Collection.coll.DeleteOne(ctx, r.scopeFilter(model.Filter), deleteOptions)
 
 	if err != nil {
 		logger.Exception(err, "There was an error while deleting collection documents")
@@ -302,14 +632,15 @@ func (r *MongoDefaultRepository) DeleteOne(model *MongoDeleteOneModel) (*mongoDe
 }
 
 // DeleteMany Deletes documents in a collection based on a filter, the filter can be a valid bson document
-// or you can use a odata type query.
+// or you can use a odata type query. The soft delete repositories stamp the deleted fields
+// instead of removing the documents.
 //
 // Example:
 //		repository.DeleteMany(bson.M{"userId": "someId"})
 // or
 //		repository.DeleteMany("userId eq 'someId'")
 func (r *MongoDefaultRepository) DeleteMany(filter interface{}) (*mongoDeleteResult, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := r.getContext()
 	defer cancel()
 
 	var filterToApply interface{}
@@ -330,9 +661,13 @@ func (r *MongoDefaultRepository) DeleteMany(filter interface{}) (*mongoDeleteRes
 		filterToApply = filter
 	}
 
+	if r.softDelete != nil {
+		return r.softDeleteDocuments(ctx, r.scopeFilter(filterToApply), nil, true)
+	}
+
 	deleteOptions := options.Delete()
 
-	deleteOneResult, err := r.Collection.coll.DeleteMany(ctx, filterToApply, deleteOptions)
+	deleteOneResult, err := r.Collection.coll.DeleteMany(ctx, r.scopeFilter(filterToApply), deleteOptions)
 
 	if err != nil {
 		logger.Exception(err, "There was an error while deleting collection documents")
@@ -343,3 +678,349 @@ func (r *MongoDefaultRepository) DeleteMany(filter interface{}) (*mongoDeleteRes
 	result.FromMongo(deleteOneResult)
 	return &result, nil
 }
+
+// CreateIndex Creates an index in the collection using a IndexModel, this can be constructed
+// using strong typed language using the IndexModelBuilder
+// It will return the name of the created index
+func (r *MongoDefaultRepository) CreateIndex(model *MongoIndexModel) (string, error) {
+	ctx, cancel := r.getContext()
+	defer cancel()
+
+	name, err := r.Collection.coll.Indexes().CreateOne(ctx, *model.model)
+	if err != nil {
+		logger.Exception(err, "There was an error creating index %v", model.Name)
+		return "", err
+	}
+
+	return name, nil
+}
+
+// CreateIndexes Creates several indexes in the collection using IndexModels, this can be
+// constructed using strong typed language using the IndexModelBuilder
+// It will return the names of the created indexes
+func (r *MongoDefaultRepository) CreateIndexes(models ...*MongoIndexModel) ([]string, error) {
+	ctx, cancel := r.getContext()
+	defer cancel()
+
+	if len(models) == 0 {
+		return nil, ErrNoElements
+	}
+
+	indexModels := make([]mongo.IndexModel, 0)
+	for _, model := range models {
+		indexModels = append(indexModels, *model.model)
+	}
+
+	names, err := r.Collection.coll.Indexes().CreateMany(ctx, indexModels)
+	if err != nil {
+		logger.Exception(err, "There was an error creating the collection indexes")
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// ListIndexes Lists all the indexes in the collection
+func (r *MongoDefaultRepository) ListIndexes() ([]MongoIndex, error) {
+	ctx, cancel := r.getContext()
+	defer cancel()
+
+	cursor, err := r.Collection.coll.Indexes().List(ctx)
+	if err != nil {
+		logger.Exception(err, "There was an error listing the collection indexes")
+		return nil, err
+	}
+
+	result := make([]MongoIndex, 0)
+	if err := cursor.All(ctx, &result); err != nil {
+		logger.Exception(err, "There was an error decoding the collection indexes")
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DropIndex Drops an index from the collection by its name
+func (r *MongoDefaultRepository) DropIndex(name string) error {
+	ctx, cancel := r.getContext()
+	defer cancel()
+
+	if _, err := r.Collection.coll.Indexes().DropOne(ctx, name); err != nil {
+		logger.Exception(err, "There was an error dropping index %v", name)
+		return err
+	}
+
+	return nil
+}
+
+// EnsureIndexes Creates the indexes declared in the document struct tags that do not exist
+// in the collection yet, this is safe to call on every start as existing indexes are matched
+// by name and skipped. Existing indexes whose keys or options changed are dropped and created
+// again, see GetIndexModels for the tag options.
+// It will return the names of the created indexes
+//
+// Example:
+//		repository.EnsureIndexes(User{})
+func (r *MongoDefaultRepository) EnsureIndexes(document interface{}) ([]string, error) {
+	models, err := GetIndexModels(document)
+	if err != nil {
+		return nil, err
+	}
+
+	existingIndexes, err := r.ListIndexes()
+	if err != nil {
+		return nil, err
+	}
+
+	missingModels := make([]*MongoIndexModel, 0)
+	for _, model := range models {
+		exists := false
+		for _, existingIndex := range existingIndexes {
+			if existingIndex.Name != model.Name {
+				continue
+			}
+
+			exists = model.matches(existingIndex)
+			if !exists {
+				logger.Warn("Index %v in collection %v changed, it will be created again", model.Name, r.Collection.name)
+				if err := r.DropIndex(model.Name); err != nil {
+					return nil, err
+				}
+			}
+			break
+		}
+
+		if !exists {
+			missingModels = append(missingModels, model)
+		}
+	}
+
+	if len(missingModels) == 0 {
+		return make([]string, 0), nil
+	}
+
+	names, err := r.CreateIndexes(missingModels...)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Created indexes %v in collection %v", strings.Join(names, ", "), r.Collection.name)
+	return names, nil
+}
+
+// getFindOptions Gets the options for the find operations
+func (r *MongoDefaultRepository) getFindOptions() *options.FindOptions {
+	findOptions := options.Find()
+	if r.batchSize > 0 {
+		findOptions.SetBatchSize(r.batchSize)
+	}
+
+	return findOptions
+}
+
+// getReturnDocument Converts the returned document version into the driver option
+func getReturnDocument(returnDocument ReturnDocument) options.ReturnDocument {
+	if returnDocument == ReturnAfter {
+		return options.After
+	}
+
+	return options.Before
+}
+
+// getFindOneAndUpdateOptions Gets the find one and update options of an update model, the model
+// upsert and array filters are kept
+func getFindOneAndUpdateOptions(model *MongoUpdateOneModel, returnDocument ReturnDocument) *options.FindOneAndUpdateOptions {
+	updateOptions := options.FindOneAndUpdate().SetReturnDocument(getReturnDocument(returnDocument))
+	if model.model.Upsert != nil {
+		updateOptions.SetUpsert(*model.model.Upsert)
+	}
+	if model.model.ArrayFilters != nil {
+		updateOptions.SetArrayFilters(*model.model.ArrayFilters)
+	}
+
+	return updateOptions
+}
+
+// getParentContext Gets the context the repository operations will be derived from
+func (r *MongoDefaultRepository) getParentContext() context.Context {
+	if r.context == nil {
+		return context.Background()
+	}
+
+	return r.context
+}
+
+// prepareDocument Stamps a document that is inserted with the audit fields when the repository
+// is audited and with the tenant when the repository is tenant scoped
+func (r *MongoDefaultRepository) prepareDocument(document interface{}, stamp auditStamp) (interface{}, error) {
+	var err error
+	if r.audit != nil {
+		if document, err = r.audit.stampDocument(document, stamp); err != nil {
+			return nil, err
+		}
+	}
+
+	return r.stampTenant(document)
+}
+
+// prepareReplacement Stamps a replacement document with the audit fields when the repository
+// is audited and with the tenant when the repository is tenant scoped
+func (r *MongoDefaultRepository) prepareReplacement(document interface{}, stamp auditStamp) (interface{}, error) {
+	var err error
+	if r.audit != nil {
+		if document, err = r.audit.stampReplacement(document, stamp); err != nil {
+			return nil, err
+		}
+	}
+
+	return r.stampTenant(document)
+}
+
+// prepareUpdate Stamps an update with the audit fields when the repository is audited and keeps
+// the tenant of the documents when the repository is tenant scoped
+func (r *MongoDefaultRepository) prepareUpdate(update interface{}, stamp auditStamp) (interface{}, error) {
+	return r.stampTenantUpdate(r.auditUpdate(update, stamp))
+}
+
+// auditUpdate Stamps an update with the audit fields when the repository is audited
+func (r *MongoDefaultRepository) auditUpdate(update interface{}, stamp auditStamp) interface{} {
+	if r.audit == nil {
+		return update
+	}
+
+	return r.audit.stampUpdate(update, stamp)
+}
+
+// prepareWriteModel Copies a write model with its document, update or replacement prepared, the
+// model is not changed so it can be reused or retried
+func (r *MongoDefaultRepository) prepareWriteModel(model mongo.WriteModel, stamp auditStamp) (mongo.WriteModel, error) {
+	var err error
+	switch writeModel := model.(type) {
+	case *mongo.InsertOneModel:
+		preparedModel := *writeModel
+		preparedModel.Document, err = r.prepareDocument(writeModel.Document, stamp)
+		return &preparedModel, err
+	case *mongo.ReplaceOneModel:
+		preparedModel := *writeModel
+		preparedModel.Replacement, err = r.prepareReplacement(writeModel.Replacement, stamp)
+		return &preparedModel, err
+	case *mongo.UpdateOneModel:
+		preparedModel := *writeModel
+		preparedModel.Update, err = r.prepareUpdate(writeModel.Update, stamp)
+		return &preparedModel, err
+	case *mongo.UpdateManyModel:
+		preparedModel := *writeModel
+		preparedModel.Update, err = r.prepareUpdate(writeModel.Update, stamp)
+		return &preparedModel, err
+	}
+
+	return model, nil
+}
+
+// getScopeFilters Gets the filters applied to all the queries, updates and deletes of the
+// repository, the soft delete repositories exclude the deleted documents and the tenant scoped
+// repositories the documents of other tenants
+func (r *MongoDefaultRepository) getScopeFilters() []interface{} {
+	filters := make([]interface{}, 0)
+	if r.tenantField != "" {
+		filters = append(filters, r.getTenantFilter())
+	}
+	if r.softDelete != nil && !r.withDeleted {
+		filters = append(filters, r.softDelete.getNotDeletedFilter())
+	}
+
+	return filters
+}
+
+// getScope Gets the scope filters of the repository as a single filter, returns nil if the
+// repository has no scope
+func (r *MongoDefaultRepository) getScope() interface{} {
+	filters := r.getScopeFilters()
+	if len(filters) == 0 {
+		return nil
+	}
+
+	return joinFilters(bson.D{}, filters...)
+}
+
+// scopeFilter Joins the filter of an operation with the scope filters of the repository
+func (r *MongoDefaultRepository) scopeFilter(filter interface{}) interface{} {
+	return joinFilters(filter, r.getScopeFilters()...)
+}
+
+// scopeWriteModel Copies a write model of a bulk write with the scope filters of the repository,
+// the deletes of the soft delete repositories are replaced by updates stamping the deleted fields
+func (r *MongoDefaultRepository) scopeWriteModel(model mongo.WriteModel, stamp auditStamp) mongo.WriteModel {
+	switch writeModel := model.(type) {
+	case *mongo.UpdateOneModel:
+		scopedModel := *writeModel
+		scopedModel.Filter = r.scopeFilter(writeModel.Filter)
+		return &scopedModel
+	case *mongo.UpdateManyModel:
+		scopedModel := *writeModel
+		scopedModel.Filter = r.scopeFilter(writeModel.Filter)
+		return &scopedModel
+	case *mongo.ReplaceOneModel:
+		scopedModel := *writeModel
+		scopedModel.Filter = r.scopeFilter(writeModel.Filter)
+		return &scopedModel
+	case *mongo.DeleteOneModel:
+		if r.softDelete != nil {
+			updateModel := mongo.NewUpdateOneModel().SetFilter(r.scopeFilter(writeModel.Filter)).SetUpdate(r.getSoftDeleteUpdate(stamp))
+			updateModel.Hint = writeModel.Hint
+			return updateModel
+		}
+		scopedModel := *writeModel
+		scopedModel.Filter = r.scopeFilter(writeModel.Filter)
+		return &scopedModel
+	case *mongo.DeleteManyModel:
+		if r.softDelete != nil {
+			updateModel := mongo.NewUpdateManyModel().SetFilter(r.scopeFilter(writeModel.Filter)).SetUpdate(r.getSoftDeleteUpdate(stamp))
+			updateModel.Hint = writeModel.Hint
+			return updateModel
+		}
+		scopedModel := *writeModel
+		scopedModel.Filter = r.scopeFilter(writeModel.Filter)
+		return &scopedModel
+	}
+
+	return model
+}
+
+// joinFilters Joins a filter with other filters using $and, the empty filters are skipped
+func joinFilters(filter interface{}, filters ...interface{}) interface{} {
+	if len(filters) == 0 {
+		return filter
+	}
+
+	conditions := bson.A{}
+	if !isEmptyFilter(filter) {
+		conditions = append(conditions, filter)
+	}
+	conditions = append(conditions, filters...)
+
+	if len(conditions) == 1 {
+		return conditions[0]
+	}
+
+	return bson.M{"$and": conditions}
+}
+
+// isEmptyFilter Checks if a filter matches all the documents
+func isEmptyFilter(filter interface{}) bool {
+	switch value := filter.(type) {
+	case nil:
+		return true
+	case primitive.D:
+		return len(value) == 0
+	case primitive.M:
+		return len(value) == 0
+	}
+
+	return false
+}
+
+// getContext Gets a context for a single operation with the default operation timeout
+func (r *MongoDefaultRepository) getContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(r.getParentContext(), defaultOperationTimeout)
+}
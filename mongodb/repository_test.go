@@ -0,0 +1,102 @@
+package mongodb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestGetFindOneAndUpdateOptions(t *testing.T) {
+	upsertModel, err := NewUpdateOneModelBuilder().
+		Filter("status eq 'pending'").
+		Set("grades.$[grade].passed", true).
+		ArrayFilter("grade/score ge 50").
+		Build(UpsertBuildOption)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	model, err := NewUpdateOneModelBuilder().Filter("status eq 'pending'").Set("status", "running").Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	expectedFilter := bson.M{"status": bson.M{"$eq": "pending"}}
+	if !reflect.DeepEqual(upsertModel.Filter, expectedFilter) {
+		t.Errorf("Build() filter = %v, expected %v", upsertModel.Filter, expectedFilter)
+	}
+
+	updateOptions := getFindOneAndUpdateOptions(upsertModel, ReturnAfter)
+	if *updateOptions.ReturnDocument != options.After {
+		t.Errorf("getFindOneAndUpdateOptions() return document = %v, expected %v", *updateOptions.ReturnDocument, options.After)
+	}
+	if updateOptions.Upsert == nil || !*updateOptions.Upsert {
+		t.Errorf("getFindOneAndUpdateOptions() upsert = %v, expected true", updateOptions.Upsert)
+	}
+	expectedArrayFilters := []interface{}{bson.M{"grade.score": bson.M{"$gte": 50}}}
+	if updateOptions.ArrayFilters == nil || !reflect.DeepEqual(updateOptions.ArrayFilters.Filters, expectedArrayFilters) {
+		t.Errorf("getFindOneAndUpdateOptions() array filters = %v, expected %v", updateOptions.ArrayFilters, expectedArrayFilters)
+	}
+
+	updateOptions = getFindOneAndUpdateOptions(model, ReturnBefore)
+	if *updateOptions.ReturnDocument != options.Before {
+		t.Errorf("getFindOneAndUpdateOptions() return document = %v, expected %v", *updateOptions.ReturnDocument, options.Before)
+	}
+	if (updateOptions.Upsert != nil && *updateOptions.Upsert) || updateOptions.ArrayFilters != nil {
+		t.Errorf("getFindOneAndUpdateOptions() = %v, %v, expected no upsert and array filters", updateOptions.Upsert, updateOptions.ArrayFilters)
+	}
+}
+
+func TestMongoDefaultRepository_PrepareReplacement(t *testing.T) {
+	stamp := auditStamp{time: time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC), user: "user1"}
+	repository := &MongoDefaultRepository{audit: NewAuditOptions(), tenantField: DefaultTenantField, tenantId: "tenant1"}
+
+	replacement := bson.D{{Key: "name", Value: "john"}, {Key: "createdBy", Value: "user0"}, {Key: "tenantId", Value: "tenant2"}}
+	result, err := repository.prepareReplacement(replacement, stamp)
+	if err != nil {
+		t.Fatalf("prepareReplacement() error = %v", err)
+	}
+
+	expected := bson.D{
+		{Key: "name", Value: "john"},
+		{Key: "createdBy", Value: "user0"},
+		{Key: "tenantId", Value: "tenant1"},
+		{Key: "updatedAt", Value: primitive.NewDateTimeFromTime(stamp.time)},
+		{Key: "updatedBy", Value: "user1"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("prepareReplacement() = %v, expected %v", result, expected)
+	}
+}
+
+func TestMongoDefaultRepository_FindOneAndModifyErrors(t *testing.T) {
+	repository := &MongoDefaultRepository{tenantField: DefaultTenantField, Collection: &mongoCollection{name: "orders"}}
+
+	model, err := NewUpdateOneModelBuilder().Filter("status eq 'pending'").Set("status", "running").Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	result := repository.FindOneAndUpdate(model, ReturnAfter)
+	if err := result.Err(); !errors.Is(err, ErrMissingTenant) {
+		t.Errorf("FindOneAndUpdate() error = %v, wantErr %v", err, ErrMissingTenant)
+	}
+	var document bson.M
+	if err := result.Decode(&document); !errors.Is(err, ErrMissingTenant) {
+		t.Errorf("FindOneAndUpdate() decode error = %v, wantErr %v", err, ErrMissingTenant)
+	}
+
+	if _, err := repository.ReplaceOne(bson.M{"_id": 1}, bson.M{"name": "john"}, false); !errors.Is(err, ErrMissingTenant) {
+		t.Errorf("ReplaceOne() error = %v, wantErr %v", err, ErrMissingTenant)
+	}
+	if err := repository.FindOneAndReplace(bson.M{"_id": 1}, bson.M{"name": "john"}, ReturnBefore, true).Err(); !errors.Is(err, ErrMissingTenant) {
+		t.Errorf("FindOneAndReplace() error = %v, wantErr %v", err, ErrMissingTenant)
+	}
+	if err := repository.FindOneAndDelete("status eq"); err.Err() == nil {
+		t.Errorf("FindOneAndDelete() error = nil, expected a filter error")
+	}
+}
@@ -1,31 +1,55 @@
 package mongodb
 
 import (
+	"context"
 	"strings"
+	"time"
 
+	"github.com/cjlapao/common-go-database/helpers"
 	"github.com/cjlapao/common-go/execution_context"
 	"github.com/cjlapao/common-go/guard"
 )
 
+// defaultMaxTenantFactories Number of tenant database factories kept by the service
+const defaultMaxTenantFactories = 100
+
+// defaultTenantIdleTimeout Time after which a tenant database factory that is not used is removed
+const defaultTenantIdleTimeout = 30 * time.Minute
+
+// tenantFactoryCloseDelay Time a removed tenant database factory stays connected, the requests
+// that got the factory before it was removed can still be using it
+const tenantFactoryCloseDelay = time.Minute
+
 // Global mongoDB service to keep single service for consumers
 var globalMongoDBService *MongoDBService
 
 // Global global database factory to keep a single mongodb client
 var globalFactory *MongoFactory
 
-// Global tenant database factory to keep a single mongodb client
-var tenantFactory *MongoFactory
+// tenantFactory Tenant database factory, the factories created without the global client own
+// their client and disconnect it when they are removed
+type tenantFactory struct {
+	factory    *MongoFactory
+	ownsClient bool
+}
+
+// Global tenant database factories, they share the client of the global factory
+var tenantFactories = newTenantFactories(defaultMaxTenantFactories, defaultTenantIdleTimeout)
 
-// MongoDBServiceOptions structure
+// MongoDBServiceOptions structure, the tenant factories limits use the defaults when they are 0
 type MongoDBServiceOptions struct {
 	ConnectionString   string
 	GlobalDatabaseName string
+	MaxTenantFactories int
+	TenantIdleTimeout  time.Duration
 }
 
 // MongoDBService structure
 type MongoDBService struct {
 	ConnectionString   string
 	GlobalDatabaseName string
+	// Deprecated: the tenant factories are kept per tenant, use GetTenant to get the tenant of
+	// the execution context
 	TenantDatabaseName string
 }
 
@@ -59,6 +83,17 @@ func NewWithOptions(options MongoDBServiceOptions) *MongoDBService {
 		globalFactory = NewFactory(service.ConnectionString).WithDatabase(service.GlobalDatabaseName)
 	}
 
+	maxTenantFactories := options.MaxTenantFactories
+	if maxTenantFactories == 0 {
+		maxTenantFactories = defaultMaxTenantFactories
+	}
+	tenantIdleTimeout := options.TenantIdleTimeout
+	if tenantIdleTimeout == 0 {
+		tenantIdleTimeout = defaultTenantIdleTimeout
+	}
+	tenantFactories.Clear()
+	tenantFactories = newTenantFactories(maxTenantFactories, tenantIdleTimeout)
+
 	globalMongoDBService = &service
 	return globalMongoDBService
 }
@@ -112,28 +147,76 @@ func (service *MongoDBService) GlobalDatabase() *MongoFactory {
 	return globalFactory
 }
 
-// TenantDatabase Gets the tenant database factory and initiate it ready for consumption
-// if there is no tenant set this will bring the global database and we will treat it as
-// a single tenant system.
-// This will try to only keep a client per session to avoid starvation of the clients
+// TenantDatabase Gets the tenant database factory of the execution context tenant and initiate it
+// ready for consumption, if there is no tenant set this will bring the global database and we
+// will treat it as a single tenant system.
 // returns a MongoFactory pointer
 func (service *MongoDBService) TenantDatabase() *MongoFactory {
-	ctx := execution_context.Get()
-	tenantId := ctx.Authorization.TenantId
+	return service.TenantDatabaseFor(getContextTenantId())
+}
+
+// TenantDatabaseFor Gets the database factory of a tenant, the factories are kept per tenant
+// and share the client of the global factory so concurrent requests of different tenants do
+// not reconnect. The least recently used and idle factories are removed.
+// If the tenant is empty or global this will bring the global database
+// returns a MongoFactory pointer or nil if the tenant factory could not connect
+func (service *MongoDBService) TenantDatabaseFor(tenantId string) *MongoFactory {
 	if tenantId == "" || strings.ToLower(tenantId) == "global" {
 		return service.GlobalDatabase()
 	}
-	if !strings.EqualFold(tenantId, service.TenantDatabaseName) {
-		service.TenantDatabaseName = tenantId
-		logger.Info("Initiating MongoDB Service for tenant database %v", service.TenantDatabaseName)
-		tenantFactory = NewFactory(service.ConnectionString).WithDatabase(service.TenantDatabaseName)
-		logger.Info("MongoDB Service for tenant database %v initiated successfully", service.TenantDatabaseName)
+
+	tenant, err := tenantFactories.GetOrCreate(tenantId, func() (*tenantFactory, error) {
+		logger.Info("Initiating MongoDB Service for tenant database %v", tenantId)
+		result := tenantFactory{}
+		if global := service.GlobalDatabase(); global != nil && global.Client != nil {
+			result.factory = global.ForDatabase(tenantId)
+		} else {
+			factory := NewFactory(service.ConnectionString)
+			if factory.Client == nil {
+				return nil, ErrNoClient
+			}
+			result.factory = factory.WithDatabase(tenantId)
+			result.ownsClient = true
+		}
+		logger.Info("MongoDB Service for tenant database %v initiated successfully", tenantId)
+
+		return &result, nil
+	})
+	if err != nil {
+		logger.Error("There was an error creating the MongoDB Service for tenant database %v, %v", tenantId, err.Error())
+		return nil
 	}
 
-	return tenantFactory
+	return tenant.factory
 }
 
+// GetTenant Gets the tenant database name of the execution context, the global database name is
+// returned when there is no tenant
 func (service *MongoDBService) GetTenant() string {
-	service.TenantDatabase()
-	return service.TenantDatabaseName
+	tenantId := getContextTenantId()
+	if tenantId == "" || strings.ToLower(tenantId) == "global" {
+		return service.GlobalDatabaseName
+	}
+
+	return tenantId
+}
+
+// newTenantFactories Creates the registry of the tenant factories, the evicted factories only
+// disconnect their client when they do not share the client of the global factory. The client
+// is disconnected after the close delay so the operations started with it can finish
+func newTenantFactories(maxSize int, idleTimeout time.Duration) *helpers.TenantRegistry[*tenantFactory] {
+	return helpers.NewTenantRegistry(maxSize, idleTimeout, func(tenantId string, tenant *tenantFactory) {
+		logger.Info("Removing MongoDB Service for tenant database %v", tenantId)
+		if !tenant.ownsClient || tenant.factory == nil || tenant.factory.Client == nil {
+			return
+		}
+
+		time.AfterFunc(tenantFactoryCloseDelay, func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := tenant.factory.Client.cl.Disconnect(ctx); err != nil {
+				logger.LogError(err)
+			}
+		})
+	})
 }
@@ -0,0 +1,46 @@
+package mongodb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMongoDBService_TenantDatabaseFor(t *testing.T) {
+	previousFactory, previousTenants := globalFactory, tenantFactories
+	t.Cleanup(func() {
+		globalFactory, tenantFactories = previousFactory, previousTenants
+	})
+
+	global := newTransactionTestFactory(t)
+	global.DatabaseContext = &MongoDatabaseContext{ConnectionString: "mongodb://localhost:27017"}
+	global.WithDatabase("global")
+	globalFactory = global
+	tenantFactories = newTenantFactories(10, time.Minute)
+
+	service := &MongoDBService{GlobalDatabaseName: "global"}
+	for _, tenantId := range []string{"", "global", "GLOBAL"} {
+		if factory := service.TenantDatabaseFor(tenantId); factory != global {
+			t.Errorf("TenantDatabaseFor(%q) = %p, expected the global factory %p", tenantId, factory, global)
+		}
+	}
+
+	tenant := service.TenantDatabaseFor("Tenant1")
+	if tenant == nil || tenant == global {
+		t.Fatalf("TenantDatabaseFor() = %p, expected a tenant factory", tenant)
+	}
+	if tenant.Client != global.Client {
+		t.Errorf("TenantDatabaseFor() does not share the global client")
+	}
+	if tenant.Database.name != "Tenant1" {
+		t.Errorf("TenantDatabaseFor() database = %v, expected Tenant1", tenant.Database.name)
+	}
+	if factory := service.TenantDatabaseFor("TENANT1"); factory != tenant {
+		t.Errorf("TenantDatabaseFor() = %p, expected the tenant factory %p", factory, tenant)
+	}
+	if factory := service.TenantDatabaseFor("tenant2"); factory == tenant {
+		t.Errorf("TenantDatabaseFor() reused the factory of another tenant")
+	}
+	if tenantFactories.Len() != 2 {
+		t.Errorf("tenant factories = %v, expected 2", tenantFactories.Len())
+	}
+}
@@ -0,0 +1,190 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrSoftDeleteDisabled Error returned when restoring or purging documents in a repository that
+// does not use soft delete
+var ErrSoftDeleteDisabled = errors.New("the repository does not use soft delete")
+
+// SoftDeleteOptions Fields stamped with the time and the user of the deletes of a soft delete
+// repository, the documents with a deleted time are excluded from the queries
+type SoftDeleteOptions struct {
+	DeletedAtField string
+	DeletedByField string
+}
+
+// NewSoftDeleteOptions Creates soft delete options using the deletedAt and deletedBy fields
+//
+// Example:
+//		repository := factory.NewRepository("orders").WithSoftDelete(NewSoftDeleteOptions())
+//		repository.DeleteMany("status eq 'cancelled'")
+//		repository.WithDeleted().Find("status eq 'cancelled'")
+func NewSoftDeleteOptions() *SoftDeleteOptions {
+	return &SoftDeleteOptions{
+		DeletedAtField: "deletedAt",
+		DeletedByField: "deletedBy",
+	}
+}
+
+// WithFields Sets the fields stamped with the time and the user of the deletes, the user field
+// can be empty to only stamp the time
+func (o *SoftDeleteOptions) WithFields(deletedAtField string, deletedByField string) *SoftDeleteOptions {
+	o.DeletedAtField = deletedAtField
+	o.DeletedByField = deletedByField
+	return o
+}
+
+// getNotDeletedFilter Filter matching the documents that are not deleted, the missing and null
+// deleted times both mean the document is not deleted
+func (o *SoftDeleteOptions) getNotDeletedFilter() bson.M {
+	return bson.M{o.DeletedAtField: nil}
+}
+
+// getDeletedFilter Filter matching the documents deleted before the time, a zero time matches
+// all the deleted documents
+func (o *SoftDeleteOptions) getDeletedFilter(before time.Time) bson.M {
+	if before.IsZero() {
+		return bson.M{o.DeletedAtField: bson.M{"$ne": nil}}
+	}
+
+	return bson.M{o.DeletedAtField: bson.M{"$ne": nil, "$lte": before}}
+}
+
+// getDeleteUpdate Update stamping the deleted fields
+func (o *SoftDeleteOptions) getDeleteUpdate(stamp auditStamp) primitive.M {
+	return bson.M{"$set": getAuditFields(o.DeletedAtField, o.DeletedByField, stamp)}
+}
+
+// getRestoreUpdate Update removing the deleted fields
+func (o *SoftDeleteOptions) getRestoreUpdate() primitive.M {
+	fields := []primitive.E{{Key: o.DeletedAtField, Value: ""}}
+	if o.DeletedByField != "" {
+		fields = append(fields, primitive.E{Key: o.DeletedByField, Value: ""})
+	}
+
+	return bson.M{"$unset": fields}
+}
+
+// WithSoftDelete Creates a copy of the repository where the deletes stamp the deleted fields
+// instead of removing the documents, the finds, odata queries, pipelines and updates of the
+// repository exclude the deleted documents
+func (repository *MongoDefaultRepository) WithSoftDelete(softDeleteOptions *SoftDeleteOptions) MongoRepository {
+	result := *repository
+	result.softDelete = softDeleteOptions
+	return &result
+}
+
+// WithDeleted Creates a copy of a soft delete repository that includes the deleted documents in
+// its queries and updates
+//
+// Example:
+//		repository.WithDeleted().FindOne("_id eq '5f1b9c8e8e4b2a3d4c5e6f70'")
+func (repository *MongoDefaultRepository) WithDeleted() MongoRepository {
+	result := *repository
+	result.withDeleted = true
+	return &result
+}
+
+// Restore Restores the deleted documents matching the filter by removing the deleted fields,
+// the filter can be a bson document or a odata type of query. Returns ErrSoftDeleteDisabled
+// if the repository does not use soft delete
+//
+// Example:
+//		repository.Restore("customerId eq 'someId'")
+func (r *MongoDefaultRepository) Restore(filter interface{}) (*mongoUpdateResult, error) {
+	if r.softDelete == nil {
+		return nil, ErrSoftDeleteDisabled
+	}
+
+	ctx, cancel := r.getContext()
+	defer cancel()
+
+	filterToApply, err := parseFilter(filter)
+	if err != nil {
+		logger.Error("There was an error applying the filter, %v", err.Error())
+		return nil, err
+	}
+
+	deletedRepository := r.WithDeleted().(*MongoDefaultRepository)
+	filterToApply = deletedRepository.scopeFilter(joinFilters(filterToApply, r.softDelete.getDeletedFilter(time.Time{})))
+
+	update := r.auditUpdate(r.softDelete.getRestoreUpdate(), newAuditStamp())
+
+	updateResult, err := r.Collection.coll.UpdateMany(ctx, filterToApply, update)
+	if err != nil {
+		logger.LogError(err)
+		return nil, err
+	}
+
+	result := mongoUpdateResult{}
+	result.FromMongo(updateResult)
+	return &result, nil
+}
+
+// Purge Removes the documents that were deleted before the duration, a zero duration removes
+// all the deleted documents. Returns ErrSoftDeleteDisabled if the repository does not use soft
+// delete
+//
+// Example:
+//		repository.Purge(30 * 24 * time.Hour)
+func (r *MongoDefaultRepository) Purge(olderThan time.Duration) (*mongoDeleteResult, error) {
+	if r.softDelete == nil {
+		return nil, ErrSoftDeleteDisabled
+	}
+
+	ctx, cancel := r.getContext()
+	defer cancel()
+
+	deletedRepository := r.WithDeleted().(*MongoDefaultRepository)
+	filterToApply := deletedRepository.scopeFilter(r.softDelete.getDeletedFilter(time.Now().UTC().Add(-olderThan)))
+
+	deleteResult, err := r.Collection.coll.DeleteMany(ctx, filterToApply)
+	if err != nil {
+		logger.Exception(err, "There was an error while purging collection documents")
+		return nil, err
+	}
+
+	result := mongoDeleteResult{}
+	result.FromMongo(deleteResult)
+	return &result, nil
+}
+
+// softDeleteDocuments Stamps the deleted fields of the first or all the documents matching the
+// filter, the deleted count is the number of stamped documents
+func (r *MongoDefaultRepository) softDeleteDocuments(ctx context.Context, filter interface{}, hint interface{}, many bool) (*mongoDeleteResult, error) {
+	update := r.getSoftDeleteUpdate(newAuditStamp())
+	updateOptions := options.Update()
+	if hint != nil {
+		updateOptions.SetHint(hint)
+	}
+
+	var updateResult *mongo.UpdateResult
+	var err error
+	if many {
+		updateResult, err = r.Collection.coll.UpdateMany(ctx, filter, update, updateOptions)
+	} else {
+		updateResult, err = r.Collection.coll.UpdateOne(ctx, filter, update, updateOptions)
+	}
+	if err != nil {
+		logger.Exception(err, "There was an error while deleting collection documents")
+		return nil, err
+	}
+
+	return &mongoDeleteResult{DeletedCount: updateResult.ModifiedCount}, nil
+}
+
+// getSoftDeleteUpdate Update of the soft deletes, the audit fields are also stamped when the
+// repository is audited. The soft deletes do not upsert nor change the tenant so the tenant
+// is only kept by the scope filter
+func (r *MongoDefaultRepository) getSoftDeleteUpdate(stamp auditStamp) interface{} {
+	return r.auditUpdate(r.softDelete.getDeleteUpdate(stamp), stamp)
+}
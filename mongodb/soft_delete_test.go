@@ -0,0 +1,95 @@
+package mongodb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestMongoDefaultRepository_ScopeFilter(t *testing.T) {
+	softDelete := NewSoftDeleteOptions()
+
+	tests := []struct {
+		name       string
+		repository *MongoDefaultRepository
+		filter     interface{}
+		expect     interface{}
+	}{
+		{
+			name:       "without soft delete",
+			repository: &MongoDefaultRepository{},
+			filter:     bson.M{"name": "john"},
+			expect:     bson.M{"name": "john"},
+		},
+		{
+			name:       "empty filter",
+			repository: &MongoDefaultRepository{softDelete: softDelete},
+			filter:     bson.D{},
+			expect:     bson.M{"deletedAt": nil},
+		},
+		{
+			name:       "filter",
+			repository: &MongoDefaultRepository{softDelete: softDelete},
+			filter:     bson.M{"name": "john"},
+			expect:     bson.M{"$and": bson.A{bson.M{"name": "john"}, bson.M{"deletedAt": nil}}},
+		},
+		{
+			name:       "with deleted",
+			repository: &MongoDefaultRepository{softDelete: softDelete, withDeleted: true},
+			filter:     bson.M{"name": "john"},
+			expect:     bson.M{"name": "john"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.repository.scopeFilter(tt.filter)
+			if !reflect.DeepEqual(result, tt.expect) {
+				t.Errorf("scopeFilter() = %v, expected %v", result, tt.expect)
+			}
+		})
+	}
+}
+
+func TestMongoDefaultRepository_ScopeWriteModel(t *testing.T) {
+	repository := &MongoDefaultRepository{softDelete: NewSoftDeleteOptions().WithFields("removedOn", "")}
+	stamp := auditStamp{time: time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC), user: "user1"}
+
+	model := repository.scopeWriteModel(mongo.NewDeleteManyModel().SetFilter(bson.M{"status": "cancelled"}), stamp)
+
+	updateModel, ok := model.(*mongo.UpdateManyModel)
+	if !ok {
+		t.Fatalf("scopeWriteModel() = %T, expected *mongo.UpdateManyModel", model)
+	}
+	expectedFilter := bson.M{"$and": bson.A{bson.M{"status": "cancelled"}, bson.M{"removedOn": nil}}}
+	if !reflect.DeepEqual(updateModel.Filter, expectedFilter) {
+		t.Errorf("scopeWriteModel() filter = %v, expected %v", updateModel.Filter, expectedFilter)
+	}
+	expectedUpdate := bson.M{"$set": getAuditFields("removedOn", "", stamp)}
+	if !reflect.DeepEqual(updateModel.Update, expectedUpdate) {
+		t.Errorf("scopeWriteModel() update = %v, expected %v", updateModel.Update, expectedUpdate)
+	}
+}
+
+func TestScopePipeline(t *testing.T) {
+	scope := bson.M{"deletedAt": nil}
+	textMatch := bson.D{{Key: "$match", Value: bson.M{"$text": bson.M{"$search": "shoes"}}}}
+	sort := bson.D{{Key: "$sort", Value: bson.M{"name": 1}}}
+
+	result := scopePipeline(bson.A{textMatch, sort}, scope)
+	expected := bson.A{
+		bson.D{{Key: "$match", Value: bson.M{"$and": bson.A{bson.M{"$text": bson.M{"$search": "shoes"}}, scope}}}},
+		sort,
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("scopePipeline() = %v, expected %v", result, expected)
+	}
+
+	result = scopePipeline(bson.A{sort}, scope)
+	expected = bson.A{bson.D{{Key: "$match", Value: scope}}, sort}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("scopePipeline() = %v, expected %v", result, expected)
+	}
+}
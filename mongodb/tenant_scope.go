@@ -0,0 +1,145 @@
+package mongodb
+
+import (
+	"errors"
+
+	"github.com/cjlapao/common-go/execution_context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DefaultTenantField Field holding the tenant of the documents in the shared collections
+const DefaultTenantField = "tenantId"
+
+// ErrMissingTenant Error returned when a tenant scoped repository without tenant writes a document
+var ErrMissingTenant = errors.New("the repository has no tenant")
+
+// NewTenantRepository Creates a new repository for a collection shared by several tenants, the
+// documents are scoped by the tenantId field using the tenant. When the tenant is empty the
+// tenant of the execution context authorization is used. Create the repository for each request,
+// the repository keeps the tenant it was created with
+//
+// Example:
+//		repository := mongodb.Get().GlobalDatabase().NewTenantRepository("orders", "")
+//		repository.Find("status eq 'paid'")
+func (mongoFactory *MongoFactory) NewTenantRepository(collection string, tenantId string) MongoRepository {
+	return mongoFactory.NewRepository(collection).WithTenantScope(DefaultTenantField, tenantId)
+}
+
+// WithTenantScope Creates a copy of the repository where the finds, odata queries, pipelines,
+// updates and deletes only match the documents of the tenant, the inserted and replaced
+// documents are stamped with the tenant and updates cannot change it.
+// When the tenant is empty the tenant of the execution context authorization is used, without
+// any tenant no document is matched and the inserts, replaces, updates and upserts return
+// ErrMissingTenant. The collections joined by $lookup are not scoped, the odata $expand uses the
+// scope unless the relationship is unscoped
+func (repository *MongoDefaultRepository) WithTenantScope(field string, tenantId string) MongoRepository {
+	if field == "" {
+		field = DefaultTenantField
+	}
+
+	if tenantId == "" {
+		tenantId = getContextTenantId()
+	}
+
+	result := *repository
+	result.tenantField = field
+	result.tenantId = tenantId
+	return &result
+}
+
+// getContextTenantId Gets the tenant of the execution context authorization, returns empty if
+// there is no authorization
+func getContextTenantId() string {
+	ctx := execution_context.Get()
+	if ctx == nil || ctx.Authorization == nil {
+		return ""
+	}
+
+	return ctx.Authorization.TenantId
+}
+
+// getTenantFilter Filter matching the documents of the tenant, without tenant the filter does
+// not match any document
+func (r *MongoDefaultRepository) getTenantFilter() bson.M {
+	if r.tenantId == "" {
+		logger.Error("The repository has no tenant, no %v documents will be matched", r.Collection.name)
+		return bson.M{r.tenantField: bson.M{"$in": bson.A{}}}
+	}
+
+	return bson.M{r.tenantField: r.tenantId}
+}
+
+// stampTenant Stamps a document with the tenant when the repository is tenant scoped, any other
+// tenant in the document is replaced
+func (r *MongoDefaultRepository) stampTenant(document interface{}) (interface{}, error) {
+	if r.tenantField == "" {
+		return document, nil
+	}
+
+	if r.tenantId == "" {
+		return nil, ErrMissingTenant
+	}
+
+	return setDocumentFields(document, []primitive.E{{Key: r.tenantField, Value: r.tenantId}})
+}
+
+// stampTenantUpdate Prevents an update from changing the tenant of the documents when the
+// repository is tenant scoped, the tenant set by the update is replaced and the other operators
+// on the tenant field, including the renames to it, are removed. Returns ErrMissingTenant if the repository has no tenant so
+// upserts cannot insert documents without tenant
+func (r *MongoDefaultRepository) stampTenantUpdate(update interface{}) (interface{}, error) {
+	if r.tenantField == "" {
+		return update, nil
+	}
+
+	if r.tenantId == "" {
+		return nil, ErrMissingTenant
+	}
+
+	tenant := primitive.E{Key: r.tenantField, Value: r.tenantId}
+
+	switch value := update.(type) {
+	case mongo.Pipeline:
+		stages := append(mongo.Pipeline{}, value...)
+		return append(stages, bson.D{{Key: "$set", Value: bson.D{{Key: tenant.Key, Value: bson.M{"$literal": tenant.Value}}}}}), nil
+
+	case primitive.M:
+		value = copyUpdate(value)
+		if renames, ok := value["$rename"]; ok {
+			remaining := make([]primitive.E, 0)
+			for _, element := range getUpdateFields(renames) {
+				if target, ok := element.Value.(string); !ok || target != tenant.Key {
+					remaining = append(remaining, element)
+				}
+			}
+			if len(remaining) == 0 {
+				delete(value, "$rename")
+			} else {
+				value["$rename"] = remaining
+			}
+		}
+
+		for operator, fields := range value {
+			if !hasOperatorField(primitive.M{operator: fields}, tenant.Key) {
+				continue
+			}
+
+			if operator == "$set" || operator == "$setOnInsert" {
+				value[operator] = setUpdateField(getUpdateFields(fields), tenant)
+				continue
+			}
+
+			remaining := removeUpdateField(getUpdateFields(fields), tenant.Key)
+			if len(remaining) == 0 {
+				delete(value, operator)
+			} else {
+				value[operator] = remaining
+			}
+		}
+		return value, nil
+	}
+
+	return update, nil
+}
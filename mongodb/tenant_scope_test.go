@@ -0,0 +1,125 @@
+package mongodb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/cjlapao/common-go/execution_context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestMongoDefaultRepository_TenantScope(t *testing.T) {
+	repository := &MongoDefaultRepository{tenantField: DefaultTenantField, tenantId: "tenant1", softDelete: NewSoftDeleteOptions()}
+
+	filter := repository.scopeFilter(bson.M{"name": "john"})
+	expectedFilter := bson.M{"$and": bson.A{bson.M{"name": "john"}, bson.M{"tenantId": "tenant1"}, bson.M{"deletedAt": nil}}}
+	if !reflect.DeepEqual(filter, expectedFilter) {
+		t.Errorf("scopeFilter() = %v, expected %v", filter, expectedFilter)
+	}
+
+	update, err := repository.stampTenantUpdate(bson.M{
+		"$set":    []primitive.E{{Key: "name", Value: "john"}, {Key: "tenantId", Value: "tenant2"}},
+		"$rename": []primitive.E{{Key: "tenantId", Value: "oldTenantId"}},
+	})
+	if err != nil {
+		t.Fatalf("stampTenantUpdate() error = %v", err)
+	}
+	expectedUpdate := bson.M{
+		"$set": []primitive.E{{Key: "name", Value: "john"}, {Key: "tenantId", Value: "tenant1"}},
+	}
+	if !reflect.DeepEqual(update, expectedUpdate) {
+		t.Errorf("stampTenantUpdate() = %v, expected %v", update, expectedUpdate)
+	}
+
+	document, err := repository.stampTenant(bson.D{{Key: "name", Value: "john"}, {Key: "tenantId", Value: "tenant2"}})
+	if err != nil {
+		t.Fatalf("stampTenant() error = %v", err)
+	}
+	expectedDocument := bson.D{{Key: "name", Value: "john"}, {Key: "tenantId", Value: "tenant1"}}
+	if !reflect.DeepEqual(document, expectedDocument) {
+		t.Errorf("stampTenant() = %v, expected %v", document, expectedDocument)
+	}
+}
+
+func TestMongoDefaultRepository_TenantScopeWithoutTenant(t *testing.T) {
+	repository := &MongoDefaultRepository{tenantField: "customer", Collection: &mongoCollection{name: "orders"}}
+
+	filter := repository.scopeFilter(bson.D{})
+	expectedFilter := bson.M{"customer": bson.M{"$in": bson.A{}}}
+	if !reflect.DeepEqual(filter, expectedFilter) {
+		t.Errorf("scopeFilter() = %v, expected %v", filter, expectedFilter)
+	}
+
+	if _, err := repository.stampTenant(bson.M{"name": "john"}); !errors.Is(err, ErrMissingTenant) {
+		t.Errorf("stampTenant() error = %v, wantErr %v", err, ErrMissingTenant)
+	}
+
+	updates := []interface{}{
+		bson.M{"$set": bson.M{"name": "john"}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"name": "john"}}}},
+	}
+	for _, update := range updates {
+		if _, err := repository.prepareUpdate(update, newAuditStamp()); !errors.Is(err, ErrMissingTenant) {
+			t.Errorf("prepareUpdate() error = %v, wantErr %v", err, ErrMissingTenant)
+		}
+	}
+
+	upsert := mongo.NewUpdateOneModel().SetFilter(bson.M{}).SetUpdate(bson.M{"$set": bson.M{"name": "john"}}).SetUpsert(true)
+	if _, err := repository.prepareWriteModel(upsert, newAuditStamp()); !errors.Is(err, ErrMissingTenant) {
+		t.Errorf("prepareWriteModel() error = %v, wantErr %v", err, ErrMissingTenant)
+	}
+}
+
+func TestMongoDefaultRepository_WithTenantScope(t *testing.T) {
+	ctx := execution_context.Get().WithDefaultAuthorization()
+	defer func() { ctx.Authorization = nil }()
+	ctx.Authorization.TenantId = "tenant2"
+
+	repository := (&MongoDefaultRepository{}).WithTenantScope("", "tenant1").(*MongoDefaultRepository)
+
+	filter := repository.scopeFilter(bson.M{})
+	expectedFilter := bson.M{DefaultTenantField: "tenant1"}
+	if !reflect.DeepEqual(filter, expectedFilter) {
+		t.Errorf("scopeFilter() = %v, expected %v", filter, expectedFilter)
+	}
+
+	repository = (&MongoDefaultRepository{}).WithTenantScope("", "").(*MongoDefaultRepository)
+	if repository.tenantId != "tenant2" {
+		t.Errorf("WithTenantScope() tenant = %v, expected the context tenant tenant2", repository.tenantId)
+	}
+}
+
+func TestMongoDefaultRepository_TenantScopeRename(t *testing.T) {
+	repository := &MongoDefaultRepository{tenantField: DefaultTenantField, tenantId: "tenant1"}
+
+	tests := []struct {
+		name   string
+		update bson.M
+		expect bson.M
+	}{
+		{
+			name:   "rename to the tenant",
+			update: bson.M{"$rename": bson.M{"owner": "tenantId"}, "$set": bson.M{"name": "john"}},
+			expect: bson.M{"$set": bson.M{"name": "john"}},
+		},
+		{
+			name:   "other renames are kept",
+			update: bson.M{"$rename": bson.D{{Key: "owner", Value: "tenantId"}, {Key: "nick", Value: "alias"}}},
+			expect: bson.M{"$rename": []primitive.E{{Key: "nick", Value: "alias"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update, err := repository.stampTenantUpdate(tt.update)
+			if err != nil {
+				t.Fatalf("stampTenantUpdate() error = %v", err)
+			}
+			if !reflect.DeepEqual(update, tt.expect) {
+				t.Errorf("stampTenantUpdate() = %v, expected %v", update, tt.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package mongodb
+
+import (
+	"encoding/json"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoUpdateManyModel Update applied to all the documents matching the filter
+type MongoUpdateManyModel struct {
+	model        *mongo.UpdateManyModel
+	Filter       interface{}
+	Hint         interface{}
+	Update       interface{}
+	ArrayFilters []interface{}
+	versionField string
+}
+
+// Transforms the model into a json string representation
+func (model MongoUpdateManyModel) String() string {
+	result, err := json.MarshalIndent(model.model, "", "  ")
+	if err != nil {
+		return ""
+	}
+
+	return string(result)
+}
+
+// BuildMany Builds a model that updates all the documents matching the filter instead of the
+// first one, the update operators, pipeline stages, array filters, version and options are the
+// same as in Build, for example:
+//		model, err := NewUpdateOneModelBuilder().Filter("status eq 'pending'").Set("status", "expired").BuildMany()
+//		result, err := repository.UpdateAll(model)
+func (c *UpdateOneModelBuilder) BuildMany(buildOptions ...BuilderOptions) (*MongoUpdateManyModel, error) {
+	updateOneModel, err := c.Build(buildOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	model := mongo.UpdateManyModel{
+		Collation:    updateOneModel.model.Collation,
+		Filter:       updateOneModel.model.Filter,
+		Update:       updateOneModel.model.Update,
+		ArrayFilters: updateOneModel.model.ArrayFilters,
+		Hint:         updateOneModel.model.Hint,
+		Upsert:       updateOneModel.model.Upsert,
+	}
+
+	return &MongoUpdateManyModel{
+		model:        &model,
+		Filter:       model.Filter,
+		Hint:         model.Hint,
+		Update:       model.Update,
+		ArrayFilters: updateOneModel.ArrayFilters,
+
+		versionField: updateOneModel.versionField,
+	}, nil
+}
@@ -3,19 +3,26 @@ package mongodb
 //TODO: Refactor implementation
 import (
 	"encoding/json"
+	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/cjlapao/common-go/guard"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var arrayFilterIdentifierRegexp = regexp.MustCompile(`\$\[([a-z][a-zA-Z0-9]*)\]`)
+
 type MongoUpdateOneModel struct {
-	model  *mongo.UpdateOneModel
-	Filter interface{}
-	Hint   interface{}
-	Update interface{}
+	model        *mongo.UpdateOneModel
+	Filter       interface{}
+	Hint         interface{}
+	Update       interface{}
+	ArrayFilters []interface{}
+	versionField string
 }
 
 // Transforms the model into a json string representation
@@ -31,6 +38,12 @@ func (model MongoUpdateOneModel) String() string {
 type UpdateOneModelBuilder struct {
 	LiteralFilter string
 	Elements      []builderElement
+	ArrayFilters  []interface{}
+	Stages        mongo.Pipeline
+	versionField  string
+	version       interface{}
+	audit         *AuditOptions
+	err           error
 }
 
 // NewUpdateOneModelBuilder Creates a new builder for an updateone model
@@ -42,65 +55,195 @@ func NewUpdateOneModelBuilder() *UpdateOneModelBuilder {
 
 // Set Sets a field to be updated, this will add the property if it does not exist in the model
 func (c *UpdateOneModelBuilder) Set(field string, value interface{}) *UpdateOneModelBuilder {
-	guard.FatalEmptyOrNil(field)
+	return c.setElement(SetOperation, field, value)
+}
 
-	element := builderElement{
-		SetOperation,
-		field,
-		Equal,
-		value,
+// Unset Unsets a field in the document, this will effectively remove the property from the document
+func (c *UpdateOneModelBuilder) Unset(field string) *UpdateOneModelBuilder {
+	return c.setElement(UnsetOperation, field, "1")
+}
+
+// SetOnInsert, only updates a value on insert, if the operation is a update it will be ignored
+func (c *UpdateOneModelBuilder) SetOnInsert(field string, value interface{}) *UpdateOneModelBuilder {
+	return c.setElement(SetOnInsertOperation, field, value)
+}
+
+// Inc Increments a field by the value, negative values decrement it
+func (c *UpdateOneModelBuilder) Inc(field string, value interface{}) *UpdateOneModelBuilder {
+	return c.addElement(IncOperation, field, value)
+}
+
+// Mul Multiplies a field by the value
+func (c *UpdateOneModelBuilder) Mul(field string, value interface{}) *UpdateOneModelBuilder {
+	return c.addElement(MulOperation, field, value)
+}
+
+// Min Updates a field only if the value is lower than the current one
+func (c *UpdateOneModelBuilder) Min(field string, value interface{}) *UpdateOneModelBuilder {
+	return c.addElement(MinOperation, field, value)
+}
+
+// Max Updates a field only if the value is greater than the current one
+func (c *UpdateOneModelBuilder) Max(field string, value interface{}) *UpdateOneModelBuilder {
+	return c.addElement(MaxOperation, field, value)
+}
+
+// Rename Renames a field, both the field and the new name are validated for conflicts
+func (c *UpdateOneModelBuilder) Rename(field string, newName string) *UpdateOneModelBuilder {
+	guard.FatalEmptyOrNil(newName)
+	return c.addElement(RenameOperation, field, newName)
+}
+
+// CurrentDate Sets a field to the current date of the server
+func (c *UpdateOneModelBuilder) CurrentDate(field string) *UpdateOneModelBuilder {
+	return c.addElement(CurrentDateOperation, field, true)
+}
+
+// CurrentTimestamp Sets a field to the current timestamp of the server
+func (c *UpdateOneModelBuilder) CurrentTimestamp(field string) *UpdateOneModelBuilder {
+	return c.addElement(CurrentDateOperation, field, bson.M{"$type": "timestamp"})
+}
+
+// Push Appends a value to an array field
+func (c *UpdateOneModelBuilder) Push(field string, value interface{}) *UpdateOneModelBuilder {
+	return c.addElement(PushOperation, field, value)
+}
+
+// PushEach Appends the values to an array field, the options can slice, sort or set the
+// position of the values
+// for example:
+//		builder.PushEach("scores", []interface{}{89, 92}, NewPushOptions().SetSort(-1).SetSlice(3))
+func (c *UpdateOneModelBuilder) PushEach(field string, values []interface{}, pushOptions *PushOptions) *UpdateOneModelBuilder {
+	each := bson.D{{Key: "$each", Value: values}}
+	if pushOptions != nil {
+		if pushOptions.Position != nil {
+			each = append(each, bson.E{Key: "$position", Value: *pushOptions.Position})
+		}
+		if pushOptions.Slice != nil {
+			each = append(each, bson.E{Key: "$slice", Value: *pushOptions.Slice})
+		}
+		if pushOptions.Sort != nil {
+			each = append(each, bson.E{Key: "$sort", Value: pushOptions.Sort})
+		}
 	}
 
-	has, idx := c.hasElement(field)
-	if !has {
-		c.Elements = append(c.Elements, element)
-	} else {
-		c.Elements[idx].operation = SetOperation
-		c.Elements[idx].value = value
+	return c.addElement(PushOperation, field, each)
+}
+
+// AddToSet Adds the values to an array field if they are not already in it
+func (c *UpdateOneModelBuilder) AddToSet(field string, values ...interface{}) *UpdateOneModelBuilder {
+	if len(values) == 1 {
+		return c.addElement(AddToSetOperation, field, values[0])
 	}
+
+	return c.addElement(AddToSetOperation, field, bson.D{{Key: "$each", Value: values}})
+}
+
+// Pull Removes the elements of an array field matching the condition, the condition can be a
+// value or a query document
+// for example:
+//		builder.Pull("tags", "obsolete")
+//		builder.Pull("results", bson.M{"score": bson.M{"$lt": 5}})
+func (c *UpdateOneModelBuilder) Pull(field string, condition interface{}) *UpdateOneModelBuilder {
+	return c.addElement(PullOperation, field, condition)
+}
+
+// PullAll Removes all the instances of the values from an array field
+func (c *UpdateOneModelBuilder) PullAll(field string, values ...interface{}) *UpdateOneModelBuilder {
+	return c.addElement(PullAllOperation, field, values)
+}
+
+// Pop Removes the first or the last element of an array field
+func (c *UpdateOneModelBuilder) Pop(field string, position PopPosition) *UpdateOneModelBuilder {
+	return c.addElement(PopOperation, field, int(position))
+}
+
+// ArrayFilter Adds a filter for the elements updated with the $[identifier] positional operator,
+// the filter can be a bson document or an odata type of query using the identifier as the
+// element, for example:
+//		builder.Set("grades.$[grade].passed", true).ArrayFilter("grade/score ge 50")
+func (c *UpdateOneModelBuilder) ArrayFilter(filter interface{}) *UpdateOneModelBuilder {
+	c.ArrayFilters = append(c.ArrayFilters, filter)
 	return c
 }
 
-// Unset Unsets a field in the document, this will effectively remove the property from the document
-func (c *UpdateOneModelBuilder) Unset(field string) *UpdateOneModelBuilder {
+// SetStage Adds a $set stage to the update pipeline, the values are aggregation expressions so
+// they can use the current values of other fields. The pipeline updates cannot be mixed with
+// the update operators
+// for example:
+//		builder.SetStage(bson.D{{Key: "total", Value: bson.M{"$multiply": bson.A{"$price", "$qty"}}}})
+func (c *UpdateOneModelBuilder) SetStage(fields interface{}) *UpdateOneModelBuilder {
+	c.Stages = append(c.Stages, bson.D{{Key: "$set", Value: fields}})
+	return c
+}
+
+// SetExpression Adds a $set stage to the update pipeline setting the field with an odata type
+// of expression, it supports the arithmetic operators and the functions of the filters
+// for example:
+//		builder.SetExpression("total", "price mul qty").SetExpression("name", "toupper(name)")
+func (c *UpdateOneModelBuilder) SetExpression(field string, expression string) *UpdateOneModelBuilder {
 	guard.FatalEmptyOrNil(field)
 
-	element := builderElement{
-		UnsetOperation,
-		field,
-		Equal,
-		"1",
+	tree, err := parseFilterExpression(expression)
+	if err != nil {
+		c.err = err
+		return c
 	}
-
-	has, idx := c.hasElement(field)
-	if !has {
-		c.Elements = append(c.Elements, element)
-	} else {
-		c.Elements[idx].operation = UnsetOperation
-		c.Elements[idx].value = "1"
+	value, err := getFilterExpression(tree)
+	if err != nil {
+		c.err = err
+		return c
 	}
+
+	return c.SetStage(bson.D{{Key: field, Value: value}})
+}
+
+// UnsetStage Adds an $unset stage to the update pipeline removing the fields
+func (c *UpdateOneModelBuilder) UnsetStage(fields ...string) *UpdateOneModelBuilder {
+	c.Stages = append(c.Stages, bson.D{{Key: "$unset", Value: fields}})
 	return c
 }
 
-// SetOnInsert, only updates a value on insert, if the operation is a update it will be ignored
-func (c *UpdateOneModelBuilder) SetOnInsert(field string, value interface{}) *UpdateOneModelBuilder {
+// ReplaceWithStage Adds a $replaceWith stage to the update pipeline replacing the document with
+// the expression, like a merge of the document with an embedded document
+// for example:
+//		builder.ReplaceWithStage(bson.M{"$mergeObjects": bson.A{"$$ROOT", "$draft"}})
+func (c *UpdateOneModelBuilder) ReplaceWithStage(expression interface{}) *UpdateOneModelBuilder {
+	c.Stages = append(c.Stages, bson.D{{Key: "$replaceWith", Value: expression}})
+	return c
+}
+
+// WithVersion Makes the update optimistic using the _version field, the update only matches the
+// document if it still has the version and increments it. The repository UpdateOne returns
+// ErrConcurrencyConflict when the document was changed in the meantime, for example:
+//		builder.FilterBy("_id", Equal, user.ID).Set("name", "john").WithVersion(user.Version)
+func (c *UpdateOneModelBuilder) WithVersion(version interface{}) *UpdateOneModelBuilder {
+	return c.WithVersionField(DefaultVersionField, version)
+}
+
+// WithVersionField Same as WithVersion using a different field to hold the version, the field
+// cannot be updated by the builder as the version is always incremented by one
+func (c *UpdateOneModelBuilder) WithVersionField(field string, version interface{}) *UpdateOneModelBuilder {
 	guard.FatalEmptyOrNil(field)
 
-	element := builderElement{
-		SetOnInsertOperation,
-		field,
-		Equal,
-		value,
+	elements := make([]builderElement, 0)
+	for _, element := range c.Elements {
+		if element.operation == FilterOperation || element.key != field {
+			elements = append(elements, element)
+		}
 	}
+	c.Elements = elements
 
-	has, idx := c.hasElement(field)
-	if !has {
-		c.Elements = append(c.Elements, element)
-	} else {
-		c.Elements[idx].operation = SetOnInsertOperation
-		c.Elements[idx].value = value
-	}
+	c.versionField = field
+	c.version = version
+	return c
+}
 
+// WithAudit Stamps the update with the time and the user of the change, the updated fields are
+// set and the created fields are only set on insert when the model is used to upsert, for example:
+//		builder.FilterBy("_id", Equal, id).Set("name", "john").WithAudit(NewAuditOptions())
+func (c *UpdateOneModelBuilder) WithAudit(auditOptions *AuditOptions) *UpdateOneModelBuilder {
+	c.audit = auditOptions
 	return c
 }
 
@@ -167,7 +310,7 @@ func (c *UpdateOneModelBuilder) Encode(element interface{}, ignoredFields ...str
 		}
 
 		has, _ := c.hasElement(field)
-		if !ignored && !has {
+		if !ignored && !has && !strings.EqualFold(c.versionField, field) {
 			c.Set(field, val)
 		}
 	}
@@ -178,88 +321,46 @@ func (c *UpdateOneModelBuilder) Encode(element interface{}, ignoredFields ...str
 // Build Builds the model to use during the query
 // you can pass options for the build process, for example
 //		builder.Build(UpsertBuildOption)
-func (c *UpdateOneModelBuilder) Build(options ...BuilderOptions) (*MongoUpdateOneModel, error) {
+func (c *UpdateOneModelBuilder) Build(buildOptions ...BuilderOptions) (*MongoUpdateOneModel, error) {
 	model := mongo.UpdateOneModel{}
 	model.SetUpsert(false)
 
-	// if there is no instructions to build
-	if len(c.Elements) == 0 {
-		return nil, ErrNoElements
+	// the errors of the pipeline expressions are returned when building
+	if c.err != nil {
+		return nil, c.err
 	}
 
-	// getting all elements filtered by there respective functions
-	setOperations := c.getElements(SetOperation)
-	setOnInsertOperations := c.getElements(SetOnInsertOperation)
-	filterOperations := c.getElements(FilterOperation)
-	unsetOperations := c.getElements(UnsetOperation)
-
-	// creating initial arrays for each of the different functions
-	setElementsPrimitives := make([]primitive.E, 0)
-	setOnInsertPrimitives := make([]primitive.E, 0)
-	unsetPrimitives := make([]primitive.E, 0)
-
-	// creating the primitives for the set operations
-	for _, updateElement := range setOperations {
-		bsonElement := primitive.E{
-			Key:   updateElement.key,
-			Value: updateElement.value,
-		}
-
-		setElementsPrimitives = append(setElementsPrimitives, bsonElement)
+	// if there is no instructions to build
+	if len(c.Elements) == 0 && len(c.Stages) == 0 && c.versionField == "" {
+		return nil, ErrNoElements
 	}
 
-	// creating the primitives for the setOnInsert operations
-	for _, updateElement := range setOnInsertOperations {
-		bsonElement := primitive.E{
-			Key:   updateElement.key,
-			Value: updateElement.value,
+	// pipeline updates and update operators cannot be used together
+	if len(c.Stages) > 0 {
+		if err := c.buildPipelineUpdate(&model); err != nil {
+			return nil, err
 		}
-
-		setOnInsertPrimitives = append(setOnInsertPrimitives, bsonElement)
+	} else if err := c.buildOperatorsUpdate(&model); err != nil {
+		return nil, err
 	}
 
-	// creating the primitives for the unset operations
-	for _, unsetElement := range unsetOperations {
-		bsonElement := primitive.E{
-			Key:   unsetElement.key,
-			Value: unsetElement.value,
-		}
-
-		unsetPrimitives = append(unsetPrimitives, bsonElement)
+	if c.audit != nil {
+		model.Update = c.audit.stampUpdate(model.Update, newAuditStamp())
 	}
 
-	// Creating the root set object if there is any primitive
-	update := bson.M{}
-	if len(setElementsPrimitives) > 0 {
-		update["$set"] = setElementsPrimitives
-	}
-
-	// Creating the root setOnInsert object if there is any primitive
-	if len(setOnInsertPrimitives) > 0 {
-		update["$setOnInsert"] = setOnInsertPrimitives
-	}
-
-	// Creating the root unset object if there is any primitive
-	if len(unsetOperations) > 0 {
-		update["$unset"] = unsetPrimitives
-	}
-
-	model.Update = update
+	filterOperations := c.getElements(FilterOperation)
 
 	// Processing the filter elements, this can be the literal or just the operations
 	if len(filterOperations) > 0 {
-		filterPrimitives := bson.M{}
+		conditions := make([]string, 0)
 		for _, filterElement := range filterOperations {
-			stringFilter := getOperationString(filterElement.key, filterElement.filterOperation, filterElement.value)
-
-			filterParser := NewFilterParser(stringFilter)
-			parsedFilter, err := filterParser.Parse()
-			if err != nil {
-				return nil, err
-			}
-			filterPrimitives[filterElement.key] = parsedFilter.(primitive.M)[filterElement.key]
+			conditions = append(conditions, getOperationString(filterElement.key, filterElement.filterOperation, filterElement.value))
 		}
 
+		filterPrimitives, err := getOperationsFilter(conditions)
+		if err != nil {
+			return nil, err
+		}
 		model.Filter = filterPrimitives
 	} else if len(c.LiteralFilter) > 0 {
 		filterParser := NewFilterParser(c.LiteralFilter)
@@ -272,20 +373,256 @@ func (c *UpdateOneModelBuilder) Build(options ...BuilderOptions) (*MongoUpdateOn
 		model.Filter = bson.D{}
 	}
 
-	if len(options) > 0 {
-		for _, option := range options {
+	// versioned updates only match the document with the expected version
+	if c.versionField != "" {
+		model.Filter = addVersionFilter(model.Filter, c.versionField, c.version)
+	}
+
+	if len(buildOptions) > 0 {
+		for _, option := range buildOptions {
 			if option == UpsertBuildOption {
 				model.SetUpsert(true)
 			}
 		}
 	}
 
-	return &MongoUpdateOneModel{
+	result := MongoUpdateOneModel{
 		model:  &model,
 		Filter: model.Filter,
 		Hint:   model.Hint,
 		Update: model.Update,
-	}, nil
+
+		versionField: c.versionField,
+	}
+	if model.ArrayFilters != nil {
+		result.ArrayFilters = model.ArrayFilters.Filters
+	}
+
+	return &result, nil
+}
+
+// buildOperatorsUpdate Builds the update document with the update operators
+func (c *UpdateOneModelBuilder) buildOperatorsUpdate(model *mongo.UpdateOneModel) error {
+	// validating the paths before building, mongodb rejects updates with conflicting paths
+	if err := c.validatePaths(); err != nil {
+		return err
+	}
+
+	// creating the root object of each operator with its primitives
+	update := bson.M{}
+	for _, updateOperator := range updateOperators {
+		operationPrimitives := make([]primitive.E, 0)
+		for _, updateElement := range c.getElements(updateOperator.operation) {
+			bsonElement := primitive.E{
+				Key:   updateElement.key,
+				Value: updateElement.value,
+			}
+
+			operationPrimitives = append(operationPrimitives, bsonElement)
+		}
+
+		// the version of the versioned updates is always incremented by one
+		if updateOperator.operation == IncOperation && c.versionField != "" {
+			operationPrimitives = append(operationPrimitives, primitive.E{Key: c.versionField, Value: 1})
+		}
+
+		if len(operationPrimitives) > 0 {
+			update[updateOperator.operator] = operationPrimitives
+		}
+	}
+
+	model.Update = update
+
+	// Processing the array filters of the positional operators
+	arrayFilters, err := c.getArrayFilters()
+	if err != nil {
+		return err
+	}
+	if len(arrayFilters) > 0 {
+		model.SetArrayFilters(options.ArrayFilters{Filters: arrayFilters})
+	}
+
+	return nil
+}
+
+// buildPipelineUpdate Builds the update pipeline, the update operators and the array filters
+// cannot be used in pipeline updates
+func (c *UpdateOneModelBuilder) buildPipelineUpdate(model *mongo.UpdateOneModel) error {
+	for _, element := range c.Elements {
+		if element.operation != FilterOperation {
+			return fmt.Errorf("%w: %v is updated with an operator", ErrMixedUpdate, element.key)
+		}
+	}
+	if len(c.ArrayFilters) > 0 {
+		return fmt.Errorf("%w: array filters cannot be used in pipeline updates", ErrInvalidArrayFilter)
+	}
+
+	// the version is incremented by its own stage at the end of the pipeline
+	if c.versionField != "" {
+		stages := append(mongo.Pipeline{}, c.Stages...)
+		model.Update = append(stages, getVersionStage(c.versionField))
+		return nil
+	}
+
+	model.Update = c.Stages
+	return nil
+}
+
+// addElement Adds an update operation element, unlike the set operations they are not replaced
+// when the field is used again so the conflicts are reported when building
+func (c *UpdateOneModelBuilder) addElement(operation elementBuilderOperation, field string, value interface{}) *UpdateOneModelBuilder {
+	guard.FatalEmptyOrNil(field)
+
+	element := builderElement{
+		operation,
+		field,
+		Equal,
+		value,
+	}
+
+	c.Elements = append(c.Elements, element)
+	return c
+}
+
+// setElement Adds a set, unset or set on insert element, they replace the previous set, unset or
+// set on insert of the field. The other operators on the field are kept so the conflict is
+// reported when building
+func (c *UpdateOneModelBuilder) setElement(operation elementBuilderOperation, field string, value interface{}) *UpdateOneModelBuilder {
+	guard.FatalEmptyOrNil(field)
+
+	for idx, element := range c.Elements {
+		if isSetOperation(element.operation) && strings.EqualFold(field, element.key) {
+			c.Elements[idx].operation = operation
+			c.Elements[idx].value = value
+			return c
+		}
+	}
+
+	return c.addElement(operation, field, value)
+}
+
+// isSetOperation Checks if the operation sets or unsets the value of a field
+func isSetOperation(operation elementBuilderOperation) bool {
+	return operation == SetOperation || operation == UnsetOperation || operation == SetOnInsertOperation
+}
+
+// validatePaths Checks that no path is updated twice and that a path and its sub paths are not
+// both updated, the renamed fields use both the field and its new name
+func (c *UpdateOneModelBuilder) validatePaths() error {
+	paths := make([]string, 0)
+	for _, element := range c.Elements {
+		if element.operation == FilterOperation {
+			continue
+		}
+
+		paths = append(paths, element.key)
+		if element.operation == RenameOperation {
+			paths = append(paths, element.value.(string))
+		}
+	}
+	if c.versionField != "" {
+		paths = append(paths, c.versionField)
+	}
+
+	for index, path := range paths {
+		for _, otherPath := range paths[index+1:] {
+			if path == otherPath || strings.HasPrefix(path, otherPath+".") || strings.HasPrefix(otherPath, path+".") {
+				return fmt.Errorf("%w: %v and %v", ErrUpdateConflict, path, otherPath)
+			}
+		}
+	}
+
+	return nil
+}
+
+// getArrayFilters Parses the array filters and checks each of them is used by the update paths
+// and that every identifier used in the paths has a filter
+func (c *UpdateOneModelBuilder) getArrayFilters() ([]interface{}, error) {
+	used := make(map[string]bool)
+	for _, element := range c.Elements {
+		if element.operation == FilterOperation {
+			continue
+		}
+		for _, match := range arrayFilterIdentifierRegexp.FindAllStringSubmatch(element.key, -1) {
+			used[match[1]] = true
+		}
+	}
+
+	result := make([]interface{}, 0)
+	filtered := make(map[string]bool)
+	for _, arrayFilter := range c.ArrayFilters {
+		parsedFilter, err := parseFilter(arrayFilter)
+		if err != nil {
+			return nil, err
+		}
+
+		identifier, err := getArrayFilterIdentifier(parsedFilter)
+		if err != nil {
+			return nil, err
+		}
+		if !used[identifier] {
+			return nil, fmt.Errorf("%w: identifier %v is not used in the update", ErrInvalidArrayFilter, identifier)
+		}
+
+		filtered[identifier] = true
+		result = append(result, parsedFilter)
+	}
+
+	for identifier := range used {
+		if !filtered[identifier] {
+			return nil, fmt.Errorf("%w: identifier %v has no array filter", ErrInvalidArrayFilter, identifier)
+		}
+	}
+
+	return result, nil
+}
+
+// getArrayFilterIdentifier Gets the identifier of an array filter, all its fields need to start
+// with the same identifier
+func getArrayFilterIdentifier(filter interface{}) (string, error) {
+	identifiers := make(map[string]bool)
+	collectArrayFilterIdentifiers(filter, identifiers)
+	if len(identifiers) != 1 {
+		return "", fmt.Errorf("%w: an array filter needs to use a single identifier", ErrInvalidArrayFilter)
+	}
+
+	for identifier := range identifiers {
+		return identifier, nil
+	}
+
+	return "", nil
+}
+
+// collectArrayFilterIdentifiers Adds the first segment of the fields of a filter, the operators
+// are walked into
+func collectArrayFilterIdentifiers(filter interface{}, identifiers map[string]bool) {
+	switch filter := filter.(type) {
+	case bson.M:
+		for key, value := range filter {
+			collectArrayFilterIdentifier(key, value, identifiers)
+		}
+	case bson.D:
+		for _, element := range filter {
+			collectArrayFilterIdentifier(element.Key, element.Value, identifiers)
+		}
+	case []bson.M:
+		for _, element := range filter {
+			collectArrayFilterIdentifiers(element, identifiers)
+		}
+	case bson.A:
+		for _, element := range filter {
+			collectArrayFilterIdentifiers(element, identifiers)
+		}
+	}
+}
+
+func collectArrayFilterIdentifier(key string, value interface{}, identifiers map[string]bool) {
+	if strings.HasPrefix(key, "$") {
+		collectArrayFilterIdentifiers(value, identifiers)
+		return
+	}
+
+	identifiers[strings.Split(key, ".")[0]] = true
 }
 
 // getElements Gets the elements filtered by operation
@@ -300,10 +637,11 @@ func (c *UpdateOneModelBuilder) getElements(operation elementBuilderOperation) [
 	return result
 }
 
-// hasElement Checks if an element exists in the slice
+// hasElement Checks if an update element exists in the slice, the filters are not taken into
+// account
 func (c *UpdateOneModelBuilder) hasElement(key string) (bool, int) {
 	for idx, element := range c.Elements {
-		if strings.EqualFold(key, element.key) {
+		if element.operation != FilterOperation && strings.EqualFold(key, element.key) {
 			return true, idx
 		}
 	}
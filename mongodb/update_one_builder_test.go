@@ -0,0 +1,266 @@
+package mongodb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestUpdateOneModelBuilder_Build(t *testing.T) {
+	tests := []struct {
+		name         string
+		builder      *UpdateOneModelBuilder
+		expect       interface{}
+		arrayFilters []interface{}
+		wantErr      error
+	}{
+		{
+			name: "field operators",
+			builder: NewUpdateOneModelBuilder().
+				Set("name", "john").
+				Inc("visits", 1).
+				Mul("price", 1.1).
+				Min("lowest", 10).
+				Max("highest", 90).
+				Rename("nick", "alias").
+				CurrentDate("updatedOn"),
+			expect: bson.M{
+				"$set":         []primitive.E{{Key: "name", Value: "john"}},
+				"$inc":         []primitive.E{{Key: "visits", Value: 1}},
+				"$mul":         []primitive.E{{Key: "price", Value: 1.1}},
+				"$min":         []primitive.E{{Key: "lowest", Value: 10}},
+				"$max":         []primitive.E{{Key: "highest", Value: 90}},
+				"$rename":      []primitive.E{{Key: "nick", Value: "alias"}},
+				"$currentDate": []primitive.E{{Key: "updatedOn", Value: true}},
+			},
+		},
+		{
+			name: "array operators",
+			builder: NewUpdateOneModelBuilder().
+				PushEach("scores", []interface{}{89, 92}, NewPushOptions().SetPosition(0).SetSlice(-5).SetSort(-1)).
+				AddToSet("tags", "a", "b").
+				Pull("results", bson.M{"score": bson.M{"$lt": 5}}).
+				PullAll("codes", 1, 2).
+				Pop("queue", PopFirst),
+			expect: bson.M{
+				"$push": []primitive.E{{Key: "scores", Value: bson.D{
+					{Key: "$each", Value: []interface{}{89, 92}},
+					{Key: "$position", Value: 0},
+					{Key: "$slice", Value: -5},
+					{Key: "$sort", Value: -1},
+				}}},
+				"$addToSet": []primitive.E{{Key: "tags", Value: bson.D{{Key: "$each", Value: []interface{}{"a", "b"}}}}},
+				"$pull":     []primitive.E{{Key: "results", Value: bson.M{"score": bson.M{"$lt": 5}}}},
+				"$pullAll":  []primitive.E{{Key: "codes", Value: []interface{}{1, 2}}},
+				"$pop":      []primitive.E{{Key: "queue", Value: -1}},
+			},
+		},
+		{
+			name: "array filters",
+			builder: NewUpdateOneModelBuilder().
+				Set("grades.$[grade].passed", true).
+				ArrayFilter("grade/score ge 50"),
+			expect: bson.M{
+				"$set": []primitive.E{{Key: "grades.$[grade].passed", Value: true}},
+			},
+			arrayFilters: []interface{}{bson.M{"grade.score": bson.M{"$gte": 50}}},
+		},
+		{
+			name: "pipeline update",
+			builder: NewUpdateOneModelBuilder().
+				SetExpression("total", "price mul qty").
+				UnsetStage("draft").
+				ReplaceWithStage(bson.M{"$mergeObjects": bson.A{"$$ROOT", "$changes"}}),
+			expect: mongo.Pipeline{
+				{{Key: "$set", Value: bson.D{{Key: "total", Value: bson.M{"$multiply": bson.A{"$price", "$qty"}}}}}},
+				{{Key: "$unset", Value: []string{"draft"}}},
+				{{Key: "$replaceWith", Value: bson.M{"$mergeObjects": bson.A{"$$ROOT", "$changes"}}}},
+			},
+		},
+		{
+			name: "versioned update",
+			builder: NewUpdateOneModelBuilder().
+				Set("_version", 10).
+				Set("name", "john").
+				WithVersion(3),
+			expect: bson.M{
+				"$set": []primitive.E{{Key: "name", Value: "john"}},
+				"$inc": []primitive.E{{Key: "_version", Value: 1}},
+			},
+		},
+		{
+			name: "versioned pipeline update",
+			builder: NewUpdateOneModelBuilder().
+				SetExpression("total", "price mul qty").
+				WithVersionField("revision", 3),
+			expect: mongo.Pipeline{
+				{{Key: "$set", Value: bson.D{{Key: "total", Value: bson.M{"$multiply": bson.A{"$price", "$qty"}}}}}},
+				{{Key: "$set", Value: bson.D{{Key: "revision", Value: bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$revision", 0}}, 1}}}}}},
+			},
+		},
+		{
+			name:    "versioned update changing the version",
+			builder: NewUpdateOneModelBuilder().WithVersion(3).Set("_version", 10),
+			wantErr: ErrUpdateConflict,
+		},
+		{
+			name:    "pipeline update with operators",
+			builder: NewUpdateOneModelBuilder().SetExpression("total", "price mul qty").Inc("version", 1),
+			wantErr: ErrMixedUpdate,
+		},
+		{
+			name:    "invalid pipeline expression",
+			builder: NewUpdateOneModelBuilder().SetExpression("total", "price mul"),
+			wantErr: ErrInvalidInput,
+		},
+		{
+			name:    "conflicting operators",
+			builder: NewUpdateOneModelBuilder().Set("address", bson.M{}).Inc("address.number", 1),
+			wantErr: ErrUpdateConflict,
+		},
+		{
+			name:    "set after another operator",
+			builder: NewUpdateOneModelBuilder().Inc("visits", 1).Set("visits", 0),
+			wantErr: ErrUpdateConflict,
+		},
+		{
+			name:    "another operator after set",
+			builder: NewUpdateOneModelBuilder().Set("visits", 0).Inc("visits", 1),
+			wantErr: ErrUpdateConflict,
+		},
+		{
+			name:    "unset after push",
+			builder: NewUpdateOneModelBuilder().Push("tags", "a").Unset("tags"),
+			wantErr: ErrUpdateConflict,
+		},
+		{
+			name:    "set on insert after min",
+			builder: NewUpdateOneModelBuilder().Min("lowest", 10).SetOnInsert("lowest", 0),
+			wantErr: ErrUpdateConflict,
+		},
+		{
+			name:    "set replaces the unset of the field",
+			builder: NewUpdateOneModelBuilder().Unset("name").Set("name", "john"),
+			expect:  bson.M{"$set": []primitive.E{{Key: "name", Value: "john"}}},
+		},
+		{
+			name:    "conflicting rename",
+			builder: NewUpdateOneModelBuilder().Rename("nick", "alias").Set("alias", "x"),
+			wantErr: ErrUpdateConflict,
+		},
+		{
+			name:    "array filter without identifier in the update",
+			builder: NewUpdateOneModelBuilder().Set("grades.$[].passed", true).ArrayFilter("grade/score ge 50"),
+			wantErr: ErrInvalidArrayFilter,
+		},
+		{
+			name:    "identifier without array filter",
+			builder: NewUpdateOneModelBuilder().Set("grades.$[grade].passed", true),
+			wantErr: ErrInvalidArrayFilter,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model, err := tt.builder.Build()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Build() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if !reflect.DeepEqual(model.Update, tt.expect) {
+				t.Errorf("Build() update = %v, expected %v", model.Update, tt.expect)
+			}
+			if !reflect.DeepEqual(model.ArrayFilters, tt.arrayFilters) {
+				t.Errorf("Build() arrayFilters = %v, expected %v", model.ArrayFilters, tt.arrayFilters)
+			}
+		})
+	}
+}
+
+func TestUpdateOneModelBuilder_BuildMany(t *testing.T) {
+	model, err := NewUpdateOneModelBuilder().
+		Filter("status eq 'pending'").
+		Set("status", "expired").
+		BuildMany(UpsertBuildOption)
+	if err != nil {
+		t.Fatalf("BuildMany() error = %v", err)
+	}
+
+	expectedFilter := bson.M{"status": bson.M{"$eq": "pending"}}
+	if !reflect.DeepEqual(model.Filter, expectedFilter) {
+		t.Errorf("BuildMany() filter = %v, expected %v", model.Filter, expectedFilter)
+	}
+	expectedUpdate := bson.M{"$set": []primitive.E{{Key: "status", Value: "expired"}}}
+	if !reflect.DeepEqual(model.Update, expectedUpdate) {
+		t.Errorf("BuildMany() update = %v, expected %v", model.Update, expectedUpdate)
+	}
+	if model.model.Upsert == nil || !*model.model.Upsert {
+		t.Errorf("BuildMany() upsert = %v, expected true", model.model.Upsert)
+	}
+}
+
+func TestUpdateOneModelBuilder_BuildManyVersion(t *testing.T) {
+	model, err := NewUpdateOneModelBuilder().
+		Filter("status eq 'pending'").
+		Set("status", "expired").
+		WithVersionField("revision", 3).
+		BuildMany()
+	if err != nil {
+		t.Fatalf("BuildMany() error = %v", err)
+	}
+
+	expectedFilter := bson.M{"status": bson.M{"$eq": "pending"}, "revision": 3}
+	if !reflect.DeepEqual(model.Filter, expectedFilter) {
+		t.Errorf("BuildMany() filter = %v, expected %v", model.Filter, expectedFilter)
+	}
+	expectedUpdate := bson.M{
+		"$set": []primitive.E{{Key: "status", Value: "expired"}},
+		"$inc": []primitive.E{{Key: "revision", Value: 1}},
+	}
+	if !reflect.DeepEqual(model.Update, expectedUpdate) {
+		t.Errorf("BuildMany() update = %v, expected %v", model.Update, expectedUpdate)
+	}
+	if model.versionField != "revision" {
+		t.Errorf("BuildMany() version field = %v, expected revision", model.versionField)
+	}
+}
+
+func TestUpdateOneModelBuilder_BuildVersionFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *UpdateOneModelBuilder
+		expect  interface{}
+	}{
+		{
+			name:    "without filter",
+			builder: NewUpdateOneModelBuilder().Set("name", "john").WithVersion(3),
+			expect:  bson.M{"_version": 3},
+		},
+		{
+			name:    "filter by",
+			builder: NewUpdateOneModelBuilder().FilterBy("name", Equal, "jane").Set("name", "john").WithVersion(3),
+			expect:  bson.M{"name": bson.M{"$eq": "jane"}, "_version": 3},
+		},
+		{
+			name:    "filter on the version",
+			builder: NewUpdateOneModelBuilder().Filter("_version gt 1").Set("name", "john").WithVersion(3),
+			expect:  bson.M{"$and": bson.A{bson.M{"_version": bson.M{"$gt": 1}}, bson.M{"_version": 3}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model, err := tt.builder.Build()
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			if !reflect.DeepEqual(model.Filter, tt.expect) {
+				t.Errorf("Build() filter = %v, expected %v", model.Filter, tt.expect)
+			}
+		})
+	}
+}
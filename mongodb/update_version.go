@@ -0,0 +1,41 @@
+package mongodb
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultVersionField Field holding the version of the documents updated with WithVersion
+const DefaultVersionField = "_version"
+
+// ErrConcurrencyConflict Error returned when a versioned update does not match any document,
+// the document was changed by someone else or it does not exist anymore
+var ErrConcurrencyConflict = errors.New("the document was modified by another operation")
+
+// addVersionFilter Adds the expected version to the filter of a versioned update, the document
+// filters are extended and any other filter is joined with the version using $and
+func addVersionFilter(filter interface{}, field string, version interface{}) interface{} {
+	switch value := filter.(type) {
+	case primitive.M:
+		if _, exists := value[field]; !exists {
+			value[field] = version
+			return value
+		}
+	case primitive.D:
+		if len(value) == 0 {
+			return bson.M{field: version}
+		}
+	}
+
+	return bson.M{"$and": bson.A{filter, bson.M{field: version}}}
+}
+
+// getVersionStage Pipeline stage incrementing the version of a versioned pipeline update, the
+// documents without version start from zero
+func getVersionStage(field string) bson.D {
+	return bson.D{{Key: "$set", Value: bson.D{{Key: field, Value: bson.M{
+		"$add": bson.A{bson.M{"$ifNull": bson.A{"$" + field, 0}}, 1},
+	}}}}}
+}
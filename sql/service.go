@@ -1,32 +1,52 @@
 package sql
 
 import (
+	"errors"
 	"strings"
+	"time"
 
+	"github.com/cjlapao/common-go-database/helpers"
 	"github.com/cjlapao/common-go/execution_context"
 	"github.com/cjlapao/common-go/guard"
 	"github.com/cjlapao/common-go/log"
 )
 
+// defaultMaxTenantFactories Number of tenant database factories kept by the service
+const defaultMaxTenantFactories = 100
+
+// defaultTenantIdleTimeout Time after which a tenant database factory that is not used is removed
+const defaultTenantIdleTimeout = 30 * time.Minute
+
+// tenantFactoryCloseDelay Time a removed tenant database factory stays open, the requests that
+// got the factory before it was removed can still be using it
+const tenantFactoryCloseDelay = time.Minute
+
+// ErrInvalidConnectionString Error returned when the connection string of a factory cannot be parsed
+var ErrInvalidConnectionString = errors.New("invalid sql connection string")
+
 // Global Sql service to keep single service for consumers
 var globalSqlService *SqlService
 
 // Global global database factory to keep a single Sql client
 var globalFactory *SqlFactory
 
-// Global tenant database factory to keep a single Sql client
-var tenantFactory *SqlFactory
+// Global tenant database factories, the evicted factories close their database connection
+var tenantFactories = newTenantFactories(defaultMaxTenantFactories, defaultTenantIdleTimeout)
 
-// SqlServiceOptions structure
+// SqlServiceOptions structure, the tenant factories limits use the defaults when they are 0
 type SqlServiceOptions struct {
 	ConnectionString   string
 	GlobalDatabaseName string
+	MaxTenantFactories int
+	TenantIdleTimeout  time.Duration
 }
 
 // SqlService structure
 type SqlService struct {
 	ConnectionString   string
 	GlobalDatabaseName string
+	// Deprecated: the tenant factories are kept per tenant, use TenantDatabaseFor to get the
+	// factory of a tenant
 	TenantDatabaseName string
 	logger             *log.Logger
 }
@@ -62,6 +82,17 @@ func NewWithOptions(options SqlServiceOptions) *SqlService {
 		globalFactory = NewFactory(service.ConnectionString).WithDatabase(service.GlobalDatabaseName)
 	}
 
+	maxTenantFactories := options.MaxTenantFactories
+	if maxTenantFactories == 0 {
+		maxTenantFactories = defaultMaxTenantFactories
+	}
+	tenantIdleTimeout := options.TenantIdleTimeout
+	if tenantIdleTimeout == 0 {
+		tenantIdleTimeout = defaultTenantIdleTimeout
+	}
+	tenantFactories.Clear()
+	tenantFactories = newTenantFactories(maxTenantFactories, tenantIdleTimeout)
+
 	globalSqlService = &service
 	return globalSqlService
 }
@@ -117,10 +148,9 @@ func (service *SqlService) GlobalDatabase() *SqlFactory {
 	return globalFactory
 }
 
-// TenantDatabase Gets the tenant database factory and initiate it ready for consumption
-// if there is no tenant set this will bring the global database and we will treat it as
-// a single tenant system.
-// This will try to only keep a client per session to avoid starvation of the clients
+// TenantDatabase Gets the tenant database factory of the execution context tenant and initiate it
+// ready for consumption, if there is no tenant set this will bring the global database and we
+// will treat it as a single tenant system.
 // returns a SqlFactory pointer
 func (service *SqlService) TenantDatabase() *SqlFactory {
 	ctx := execution_context.Get()
@@ -129,15 +159,50 @@ func (service *SqlService) TenantDatabase() *SqlFactory {
 		tenantId = ctx.Authorization.TenantId
 	}
 
+	return service.TenantDatabaseFor(tenantId)
+}
+
+// TenantDatabaseFor Gets the database factory of a tenant, the factories are kept per tenant so
+// concurrent requests of different tenants do not replace each other factories. The least
+// recently used and idle factories are removed.
+// If the tenant is empty or global this will bring the global database
+// returns a SqlFactory pointer or nil if the connection string is not valid
+func (service *SqlService) TenantDatabaseFor(tenantId string) *SqlFactory {
 	if tenantId == "" || strings.ToLower(tenantId) == "global" {
 		return service.GlobalDatabase()
 	}
-	if !strings.EqualFold(tenantId, service.TenantDatabaseName) {
-		service.TenantDatabaseName = tenantId
-		service.logger.Info("Initiating Sql Service for tenant database %v", service.TenantDatabaseName)
-		tenantFactory = NewFactory(service.ConnectionString).WithDatabase(service.TenantDatabaseName)
-		service.logger.Info("Sql Service for tenant database %v initiated successfully", service.TenantDatabaseName)
+
+	factory, err := tenantFactories.GetOrCreate(tenantId, func() (*SqlFactory, error) {
+		service.logger.Info("Initiating Sql Service for tenant database %v", tenantId)
+		factory := NewFactory(service.ConnectionString)
+		if factory == nil {
+			return nil, ErrInvalidConnectionString
+		}
+
+		factory.WithDatabase(tenantId)
+		service.logger.Info("Sql Service for tenant database %v initiated successfully", tenantId)
+		return factory, nil
+	})
+	if err != nil {
+		service.logger.Error("There was an error creating the Sql Service for tenant database %v, %v", tenantId, err.Error())
+		return nil
 	}
 
-	return tenantFactory
+	return factory
+}
+
+// newTenantFactories Creates the registry of the tenant factories, the evicted factories close
+// their database connection after the close delay so the queries started with them can finish
+func newTenantFactories(maxSize int, idleTimeout time.Duration) *helpers.TenantRegistry[*SqlFactory] {
+	return helpers.NewTenantRegistry(maxSize, idleTimeout, func(tenantId string, factory *SqlFactory) {
+		if factory == nil || factory.Database == nil {
+			return
+		}
+
+		time.AfterFunc(tenantFactoryCloseDelay, func() {
+			if err := factory.Database.Close(); err != nil {
+				factory.Logger.Exception(err, "There was an error closing the tenant database %v", tenantId)
+			}
+		})
+	})
 }
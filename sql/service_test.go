@@ -0,0 +1,52 @@
+package sql
+
+import (
+	"testing"
+)
+
+func TestSqlService_TenantDatabaseFor(t *testing.T) {
+	service := NewWithOptions(SqlServiceOptions{
+		ConnectionString:   "admin:test@tcp(127.0.0.1)/global",
+		GlobalDatabaseName: "global",
+	})
+
+	global := service.GlobalDatabase()
+	if global == nil {
+		t.Fatalf("GlobalDatabase() = nil")
+	}
+	for _, tenantId := range []string{"", "global", "GLOBAL"} {
+		if factory := service.TenantDatabaseFor(tenantId); factory != global {
+			t.Errorf("TenantDatabaseFor(%q) = %p, expected the global factory %p", tenantId, factory, global)
+		}
+	}
+
+	tenant := service.TenantDatabaseFor("Tenant1")
+	if tenant == nil || tenant == global {
+		t.Fatalf("TenantDatabaseFor() = %p, expected a tenant factory", tenant)
+	}
+	if database := tenant.DatabaseContext.ConnectionString.Database; database != "Tenant1" {
+		t.Errorf("TenantDatabaseFor() database = %v, expected Tenant1", database)
+	}
+	if factory := service.TenantDatabaseFor("TENANT1"); factory != tenant {
+		t.Errorf("TenantDatabaseFor() = %p, expected the tenant factory %p", factory, tenant)
+	}
+	if factory := service.TenantDatabaseFor("tenant2"); factory == tenant {
+		t.Errorf("TenantDatabaseFor() reused the factory of another tenant")
+	}
+}
+
+func TestSqlService_TenantDatabaseForInvalidConnectionString(t *testing.T) {
+	service := NewWithOptions(SqlServiceOptions{ConnectionString: "invalid"})
+
+	if factory := service.TenantDatabaseFor("tenant1"); factory != nil {
+		t.Errorf("TenantDatabaseFor() = %p, expected nil", factory)
+	}
+	if tenantFactories.Len() != 0 {
+		t.Errorf("tenant factories = %v, expected the failed factory not to be kept", tenantFactories.Len())
+	}
+
+	service.ConnectionString = "admin:test@tcp(127.0.0.1)"
+	if factory := service.TenantDatabaseFor("tenant1"); factory == nil {
+		t.Errorf("TenantDatabaseFor() = nil, expected the factory to be created again")
+	}
+}